@@ -0,0 +1,261 @@
+package wayes
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CacheControl represents a structure for the directives of the Cache-Control response header.
+// Zero durations are omitted, use NoCache to require revalidation of the response.
+type CacheControl struct {
+	// Public allows shared caches to store the response.
+	Public bool
+
+	// Private allows only the browser cache to store the response.
+	Private bool
+
+	// NoCache requires caches to revalidate the response before using it.
+	NoCache bool
+
+	// NoStore forbids caches to store the response.
+	NoStore bool
+
+	// NoTransform forbids intermediaries to transform the response.
+	NoTransform bool
+
+	// MustRevalidate forbids caches to use the stale response without revalidation.
+	MustRevalidate bool
+
+	// ProxyRevalidate is the same as MustRevalidate for shared caches only.
+	ProxyRevalidate bool
+
+	// Immutable indicates that the response will not change while it is fresh.
+	Immutable bool
+
+	// MaxAge is the time the response remains fresh.
+	MaxAge time.Duration
+
+	// SMaxAge is the time the response remains fresh in shared caches.
+	SMaxAge time.Duration
+
+	// StaleWhileRevalidate is the time a stale response can be used while it is revalidated in the background.
+	StaleWhileRevalidate time.Duration
+
+	// StaleIfError is the time a stale response can be used if the revalidation fails.
+	StaleIfError time.Duration
+}
+
+// directive represents a structure for a directive of the Cache-Control header.
+type directive struct {
+	name  string
+	value string
+}
+
+// directives returns the directives of the Cache-Control header in the recommended order.
+func (cc CacheControl) directives() []directive {
+	var directives []directive
+
+	flags := []struct {
+		name    string
+		enabled bool
+	}{
+		{"public", cc.Public},
+		{"private", cc.Private},
+		{"no-cache", cc.NoCache},
+		{"no-store", cc.NoStore},
+		{"no-transform", cc.NoTransform},
+		{"must-revalidate", cc.MustRevalidate},
+		{"proxy-revalidate", cc.ProxyRevalidate},
+	}
+	for _, flag := range flags {
+		if flag.enabled {
+			directives = append(directives, directive{name: flag.name})
+		}
+	}
+
+	durations := []struct {
+		name     string
+		duration time.Duration
+	}{
+		{"max-age", cc.MaxAge},
+		{"s-maxage", cc.SMaxAge},
+		{"stale-while-revalidate", cc.StaleWhileRevalidate},
+		{"stale-if-error", cc.StaleIfError},
+	}
+	for _, duration := range durations {
+		if duration.duration > 0 {
+			seconds := strconv.FormatInt(int64(duration.duration/time.Second), 10)
+			directives = append(directives, directive{name: duration.name, value: seconds})
+		}
+	}
+
+	if cc.Immutable {
+		directives = append(directives, directive{name: "immutable"})
+	}
+
+	return directives
+}
+
+// String returns the value of the Cache-Control header, for example "public, max-age=3600, immutable".
+func (cc CacheControl) String() string {
+	return formatDirectives(cc.directives())
+}
+
+// CacheDirectives represents a map of the Cache-Control directive names in lower case to their values.
+// Directives without a value are stored with an empty string.
+type CacheDirectives map[string]string
+
+// ParseCacheControl parses the values of the Cache-Control header.
+func ParseCacheControl(values ...string) CacheDirectives {
+	directives := make(CacheDirectives)
+	for _, d := range parseDirectives(values) {
+		if _, ok := directives[d.name]; !ok {
+			directives[d.name] = d.value
+		}
+	}
+
+	return directives
+}
+
+// Has reports whether the directive is present.
+func (d CacheDirectives) Has(name string) bool {
+	_, ok := d[strings.ToLower(name)]
+
+	return ok
+}
+
+// Duration returns the value of the directive in seconds, such as max-age, as a duration.
+// It reports false if the directive is missing or its value is not a non-negative number of seconds.
+func (d CacheDirectives) Duration(name string) (time.Duration, bool) {
+	value, ok := d[strings.ToLower(name)]
+	if !ok {
+		return 0, false
+	}
+
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || seconds < 0 {
+		return 0, false
+	}
+
+	return time.Duration(seconds) * time.Second, true
+}
+
+// parseDirectives parses the values of the Cache-Control header preserving the order of the directives.
+func parseDirectives(values []string) []directive {
+	var directives []directive
+	for _, value := range values {
+		for _, part := range strings.Split(value, ",") {
+			name, arg, _ := strings.Cut(strings.TrimSpace(part), "=")
+			if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+				directives = append(directives, directive{name: name, value: strings.Trim(strings.TrimSpace(arg), `"`)})
+			}
+		}
+	}
+
+	return directives
+}
+
+// formatDirectives returns the value of the Cache-Control header for the directives.
+func formatDirectives(directives []directive) string {
+	parts := make([]string, 0, len(directives))
+	for _, d := range directives {
+		if d.value == "" {
+			parts = append(parts, d.name)
+			continue
+		}
+
+		parts = append(parts, d.name+"="+d.value)
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// CacheControl sets the Cache-Control header for the response.
+// The directives are merged with the existing value: directives of the same name are replaced,
+// and public and private replace each other.
+func (c *ctx) CacheControl(cc CacheControl) {
+	header := c.response.Header()
+	directives := parseDirectives(header.Values("Cache-Control"))
+
+	for _, d := range cc.directives() {
+		directives = removeDirective(directives, d.name)
+		switch d.name {
+		case "public":
+			directives = removeDirective(directives, "private")
+		case "private":
+			directives = removeDirective(directives, "public")
+		}
+
+		directives = append(directives, d)
+	}
+
+	if len(directives) == 0 {
+		return
+	}
+
+	header.Set("Cache-Control", formatDirectives(directives))
+}
+
+// removeDirective returns the directives without the directive of the name.
+func removeDirective(directives []directive, name string) []directive {
+	result := directives[:0]
+	for _, d := range directives {
+		if d.name != name {
+			result = append(result, d)
+		}
+	}
+
+	return result
+}
+
+// RequestCacheControl returns the parsed directives of the Cache-Control request header.
+func (c *ctx) RequestCacheControl() CacheDirectives {
+	return ParseCacheControl(c.request.Header.Values("Cache-Control")...)
+}
+
+// LastModified sets the Last-Modified header for the response.
+// The time is used by [Ctx.Fresh] and [Ctx.Precondition] to evaluate conditional requests.
+func (c *ctx) LastModified(t time.Time) {
+	if t.IsZero() {
+		return
+	}
+
+	c.Set("Last-Modified", t.UTC().Format(http.TimeFormat))
+}
+
+// Expires sets the Expires header for the response.
+func (c *ctx) Expires(t time.Time) {
+	c.Set("Expires", t.UTC().Format(http.TimeFormat))
+}
+
+// Vary adds the request headers to the Vary header for the response.
+// Headers already listed are skipped, and a Vary header containing "*" is left unchanged.
+func (c *ctx) Vary(headers ...string) {
+	header := c.response.Header()
+
+	var existing []string
+	for _, value := range header.Values("Vary") {
+		for _, field := range strings.Split(value, ",") {
+			if field = strings.TrimSpace(field); field != "" {
+				existing = append(existing, field)
+			}
+		}
+	}
+
+	for _, name := range headers {
+		found := false
+		for _, field := range existing {
+			if field == "*" || strings.EqualFold(field, name) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			header.Add("Vary", name)
+			existing = append(existing, name)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package wayes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCacheControl_String tests the formatting of the Cache-Control directives.
+func TestCacheControl_String(t *testing.T) {
+	cases := []struct {
+		name     string
+		cc       CacheControl
+		excepted string
+	}{
+		{
+			name:     "Empty",
+			excepted: "",
+		},
+		{
+			name:     "Immutable",
+			cc:       CacheControl{Public: true, MaxAge: time.Hour, Immutable: true},
+			excepted: "public, max-age=3600, immutable",
+		},
+		{
+			name:     "Revalidation",
+			cc:       CacheControl{Private: true, NoCache: true, MustRevalidate: true},
+			excepted: "private, no-cache, must-revalidate",
+		},
+		{
+			name:     "Shared cache",
+			cc:       CacheControl{SMaxAge: time.Minute, StaleWhileRevalidate: 30 * time.Second, StaleIfError: time.Hour},
+			excepted: "s-maxage=60, stale-while-revalidate=30, stale-if-error=3600",
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.excepted, test.cc.String())
+		})
+	}
+}
+
+// TestCtxCacheControl tests the merging of the Cache-Control header with the existing value.
+func TestCtxCacheControl(t *testing.T) {
+	cases := []struct {
+		name     string
+		existing string
+		cc       CacheControl
+		excepted string
+	}{
+		{
+			name:     "Without existing value",
+			cc:       CacheControl{Public: true, MaxAge: time.Hour},
+			excepted: "public, max-age=3600",
+		},
+		{
+			name:     "Replaced max-age",
+			existing: "no-transform, max-age=60",
+			cc:       CacheControl{MaxAge: time.Hour},
+			excepted: "no-transform, max-age=3600",
+		},
+		{
+			name:     "Private replaces public",
+			existing: "public, max-age=60",
+			cc:       CacheControl{Private: true},
+			excepted: "max-age=60, private",
+		},
+		{
+			name:     "Empty directives",
+			existing: "no-store",
+			excepted: "no-store",
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			if test.existing != "" {
+				rr.Header().Set("Cache-Control", test.existing)
+			}
+
+			context := NewCtx(nil, rr, httptest.NewRequest(http.MethodGet, "/", nil))
+			context.CacheControl(test.cc)
+
+			assert.Equal(t, test.excepted, rr.Header().Get("Cache-Control"))
+		})
+	}
+}
+
+// TestParseCacheControl tests the parsing of the Cache-Control header.
+func TestParseCacheControl(t *testing.T) {
+	directives := ParseCacheControl(`No-Cache, max-age=0`, `max-stale="120", min-fresh=abc`)
+
+	assert.True(t, directives.Has("no-cache"))
+	assert.False(t, directives.Has("no-store"))
+
+	maxAge, ok := directives.Duration("max-age")
+	assert.True(t, ok)
+	assert.Zero(t, maxAge)
+
+	maxStale, ok := directives.Duration("max-stale")
+	assert.True(t, ok)
+	assert.Equal(t, 2*time.Minute, maxStale)
+
+	_, ok = directives.Duration("min-fresh")
+	assert.False(t, ok)
+
+	_, ok = directives.Duration("s-maxage")
+	assert.False(t, ok)
+}
+
+// TestCtxRequestCacheControl tests the parsing of the Cache-Control request header by the context.
+func TestCtxRequestCacheControl(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Cache-Control", "no-store")
+
+	context := NewCtx(nil, httptest.NewRecorder(), req)
+
+	assert.True(t, context.RequestCacheControl().Has("no-store"))
+}
+
+// TestCtxLastModified tests the setting of the Last-Modified and Expires headers.
+func TestCtxLastModified(t *testing.T) {
+	modified := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.FixedZone("MSK", 3*60*60))
+
+	rr := httptest.NewRecorder()
+	context := NewCtx(nil, rr, httptest.NewRequest(http.MethodGet, "/", nil))
+	context.LastModified(modified)
+	context.Expires(modified.Add(time.Hour))
+
+	assert.Equal(t, "Fri, 01 Mar 2024 09:00:00 GMT", rr.Header().Get("Last-Modified"))
+	assert.Equal(t, "Fri, 01 Mar 2024 10:00:00 GMT", rr.Header().Get("Expires"))
+
+	context.LastModified(time.Time{})
+	assert.Equal(t, "Fri, 01 Mar 2024 09:00:00 GMT", rr.Header().Get("Last-Modified"))
+}
+
+// TestCtxVary tests the merging of the Vary header with the existing values.
+func TestCtxVary(t *testing.T) {
+	cases := []struct {
+		name     string
+		existing []string
+		headers  []string
+		excepted []string
+	}{
+		{
+			name:     "Without existing value",
+			headers:  []string{"Accept-Encoding", "Origin"},
+			excepted: []string{"Accept-Encoding", "Origin"},
+		},
+		{
+			name:     "Duplicates",
+			existing: []string{"Accept-Encoding, origin"},
+			headers:  []string{"Origin", "Accept-Language", "accept-language"},
+			excepted: []string{"Accept-Encoding, origin", "Accept-Language"},
+		},
+		{
+			name:     "Wildcard",
+			existing: []string{"*"},
+			headers:  []string{"Origin"},
+			excepted: []string{"*"},
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			for _, value := range test.existing {
+				rr.Header().Add("Vary", value)
+			}
+
+			context := NewCtx(nil, rr, httptest.NewRequest(http.MethodGet, "/", nil))
+			context.Vary(test.headers...)
+
+			assert.Equal(t, test.excepted, rr.Header().Values("Vary"))
+		})
+	}
+}
@@ -0,0 +1,214 @@
+package wayes
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrCookieKeys represents an error indicating that no cookie keys are configured on the router.
+	ErrCookieKeys = errors.New("cookie keys are not configured")
+
+	// ErrCookieSignature represents an error indicating that the signature of the cookie is invalid,
+	// the cookie was tampered with or signed with an unknown key.
+	ErrCookieSignature = errors.New("invalid cookie signature")
+
+	// ErrCookieDecryption represents an error indicating that the cookie cannot be decrypted,
+	// the cookie was tampered with or encrypted with an unknown key.
+	ErrCookieDecryption = errors.New("cookie decryption failed")
+
+	// ErrCookieTooLarge represents an error indicating that the encoded cookie exceeds the size supported by browsers.
+	ErrCookieTooLarge = errors.New("cookie too large")
+)
+
+// maxCookieSize is the maximum size of a cookie supported by browsers.
+const maxCookieSize = 4096
+
+// cookieKey represents a structure for the keys derived from a cookie key configured on the router.
+type cookieKey struct {
+	sign    []byte
+	encrypt cipher.AEAD
+}
+
+// newCookieKey derives the signing and encryption keys from the secret.
+func newCookieKey(secret []byte) cookieKey {
+	block, _ := aes.NewCipher(deriveKey(secret, "encrypt"))
+	aead, _ := cipher.NewGCM(block)
+
+	return cookieKey{
+		sign:    deriveKey(secret, "sign"),
+		encrypt: aead,
+	}
+}
+
+// deriveKey derives a 256-bit key for the purpose from the secret.
+func deriveKey(secret []byte, purpose string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("wayes cookie " + purpose))
+
+	return mac.Sum(nil)
+}
+
+// signature returns the signature of the cookie value bound to the cookie name.
+func (k cookieKey) signature(name, value string) []byte {
+	mac := hmac.New(sha256.New, k.sign)
+	mac.Write([]byte(name + "=" + value))
+
+	return mac.Sum(nil)
+}
+
+// Cookie returns the value of the request cookie, an empty string if the cookie is missing.
+func (c *ctx) Cookie(name string) string {
+	cookie, err := c.request.Cookie(name)
+	if err != nil {
+		return ""
+	}
+
+	return cookie.Value
+}
+
+// SetCookie adds the Set-Cookie header to the response.
+// The path defaults to "/" and the SameSite attribute to Lax.
+func (c *ctx) SetCookie(cookie *http.Cookie) {
+	if cookie.Path == "" {
+		cookie.Path = "/"
+	}
+
+	if cookie.SameSite == 0 {
+		cookie.SameSite = http.SameSiteLaxMode
+	}
+
+	http.SetCookie(c.response, cookie)
+}
+
+// ClearCookie instructs the client to remove the cookie with the "/" path.
+func (c *ctx) ClearCookie(name string) {
+	c.SetCookie(&http.Cookie{
+		Name:    name,
+		Expires: time.Unix(0, 0),
+		MaxAge:  -1,
+	})
+}
+
+// SetSignedCookie adds the Set-Cookie header with the value signed by the first cookie key of the router.
+// The value is readable by the client, use [Ctx.SetEncryptedCookie] to hide it.
+func (c *ctx) SetSignedCookie(cookie *http.Cookie) error {
+	if len(c.settings.cookieKeys) == 0 {
+		return ErrCookieKeys
+	}
+
+	value := base64.RawURLEncoding.EncodeToString([]byte(cookie.Value))
+	signature := c.settings.cookieKeys[0].signature(cookie.Name, value)
+
+	return c.setEncodedCookie(cookie, value+"."+base64.RawURLEncoding.EncodeToString(signature))
+}
+
+// SignedCookie returns the value of the signed request cookie.
+// Cookies signed with any of the cookie keys of the router are accepted, so keys can be rotated.
+// It returns [http.ErrNoCookie] if the cookie is missing and [ErrCookieSignature] if it was tampered with.
+func (c *ctx) SignedCookie(name string) (string, error) {
+	if len(c.settings.cookieKeys) == 0 {
+		return "", ErrCookieKeys
+	}
+
+	cookie, err := c.request.Cookie(name)
+	if err != nil {
+		return "", err
+	}
+
+	value, encoded, ok := strings.Cut(cookie.Value, ".")
+	if !ok {
+		return "", ErrCookieSignature
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", ErrCookieSignature
+	}
+
+	for _, key := range c.settings.cookieKeys {
+		if hmac.Equal(signature, key.signature(name, value)) {
+			decoded, err := base64.RawURLEncoding.DecodeString(value)
+			if err != nil {
+				return "", ErrCookieSignature
+			}
+
+			return string(decoded), nil
+		}
+	}
+
+	return "", ErrCookieSignature
+}
+
+// SetEncryptedCookie adds the Set-Cookie header with the value encrypted by the first cookie key of the router
+// using AES-GCM, so the value is neither readable nor modifiable by the client.
+func (c *ctx) SetEncryptedCookie(cookie *http.Cookie) error {
+	if len(c.settings.cookieKeys) == 0 {
+		return ErrCookieKeys
+	}
+
+	aead := c.settings.cookieKeys[0].encrypt
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(cookie.Value)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	sealed := aead.Seal(nonce, nonce, []byte(cookie.Value), []byte(cookie.Name))
+
+	return c.setEncodedCookie(cookie, base64.RawURLEncoding.EncodeToString(sealed))
+}
+
+// EncryptedCookie returns the decrypted value of the encrypted request cookie.
+// Cookies encrypted with any of the cookie keys of the router are accepted, so keys can be rotated.
+// It returns [http.ErrNoCookie] if the cookie is missing and [ErrCookieDecryption] if it was tampered with.
+func (c *ctx) EncryptedCookie(name string) (string, error) {
+	if len(c.settings.cookieKeys) == 0 {
+		return "", ErrCookieKeys
+	}
+
+	cookie, err := c.request.Cookie(name)
+	if err != nil {
+		return "", err
+	}
+
+	sealed, err := base64.RawURLEncoding.DecodeString(cookie.Value)
+	if err != nil {
+		return "", ErrCookieDecryption
+	}
+
+	for _, key := range c.settings.cookieKeys {
+		nonceSize := key.encrypt.NonceSize()
+		if len(sealed) < nonceSize {
+			return "", ErrCookieDecryption
+		}
+
+		value, err := key.encrypt.Open(nil, sealed[:nonceSize], sealed[nonceSize:], []byte(name))
+		if err == nil {
+			return string(value), nil
+		}
+	}
+
+	return "", ErrCookieDecryption
+}
+
+// setEncodedCookie adds the Set-Cookie header with a copy of the cookie with the encoded value.
+func (c *ctx) setEncodedCookie(cookie *http.Cookie, value string) error {
+	encoded := *cookie
+	encoded.Value = value
+
+	if len(encoded.String()) > maxCookieSize {
+		return ErrCookieTooLarge
+	}
+
+	c.SetCookie(&encoded)
+
+	return nil
+}
@@ -0,0 +1,229 @@
+package wayes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCtxCookie tests the reading, setting and clearing of cookies.
+func TestCtxCookie(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(&http.Cookie{Name: "theme", Value: "dark"})
+
+	rr := httptest.NewRecorder()
+	context := NewCtx(nil, rr, req)
+
+	assert.Equal(t, "dark", context.Cookie("theme"))
+	assert.Empty(t, context.Cookie("missing"))
+
+	context.SetCookie(&http.Cookie{Name: "lang", Value: "en"})
+	context.ClearCookie("theme")
+
+	cookies := rr.Result().Cookies()
+	require.Len(t, cookies, 2)
+
+	assert.Equal(t, "lang", cookies[0].Name)
+	assert.Equal(t, "en", cookies[0].Value)
+	assert.Equal(t, "/", cookies[0].Path)
+	assert.Equal(t, http.SameSiteLaxMode, cookies[0].SameSite)
+
+	assert.Equal(t, "theme", cookies[1].Name)
+	assert.Equal(t, -1, cookies[1].MaxAge)
+}
+
+// roundTripCookie sets the cookie with the router keys and returns it as received by the client.
+func roundTripCookie(t *testing.T, keys [][]byte, set func(ctx Ctx) error) *http.Cookie {
+	rt := New()
+	rt.SetCookieKeys(keys...)
+	rt.Get("/set", set)
+
+	rr := httptest.NewRecorder()
+	rt.Mux().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/set", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	cookies := rr.Result().Cookies()
+	require.Len(t, cookies, 1)
+
+	return cookies[0]
+}
+
+// readCookie reads the cookie with the router keys.
+func readCookie(keys [][]byte, cookie *http.Cookie, get func(ctx Ctx, name string) (string, error)) (string, error) {
+	rt := New()
+	rt.SetCookieKeys(keys...)
+
+	var value string
+	var err error
+	rt.Get("/get", func(ctx Ctx) error {
+		value, err = get(ctx, "session")
+		return ctx.SendStatus(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/get", nil)
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	rt.Mux().ServeHTTP(httptest.NewRecorder(), req)
+
+	return value, err
+}
+
+// TestCtxSignedCookie tests the signing and verification of cookies including key rotation.
+func TestCtxSignedCookie(t *testing.T) {
+	oldKey := []byte("old secret key")
+	newKey := []byte("new secret key")
+
+	cookie := roundTripCookie(t, [][]byte{oldKey}, func(ctx Ctx) error {
+		if err := ctx.SetSignedCookie(&http.Cookie{Name: "session", Value: "user=1; admin"}); err != nil {
+			return err
+		}
+
+		return ctx.SendStatus(http.StatusOK)
+	})
+
+	tampered := *cookie
+	tampered.Value = "dXNlcj0y" + cookie.Value[strings.Index(cookie.Value, "."):]
+
+	renamed := *cookie
+	renamed.Name = "other"
+
+	cases := []struct {
+		name          string
+		keys          [][]byte
+		cookie        *http.Cookie
+		exceptedValue string
+		exceptedErr   error
+	}{
+		{
+			name:          "Valid signature",
+			keys:          [][]byte{oldKey},
+			cookie:        cookie,
+			exceptedValue: "user=1; admin",
+		},
+		{
+			name:          "Rotated key",
+			keys:          [][]byte{newKey, oldKey},
+			cookie:        cookie,
+			exceptedValue: "user=1; admin",
+		},
+		{
+			name:        "Unknown key",
+			keys:        [][]byte{newKey},
+			cookie:      cookie,
+			exceptedErr: ErrCookieSignature,
+		},
+		{
+			name:        "Tampered value",
+			keys:        [][]byte{oldKey},
+			cookie:      &tampered,
+			exceptedErr: ErrCookieSignature,
+		},
+		{
+			name:        "Missing cookie",
+			keys:        [][]byte{oldKey},
+			exceptedErr: http.ErrNoCookie,
+		},
+		{
+			name:        "Without keys",
+			cookie:      cookie,
+			exceptedErr: ErrCookieKeys,
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			value, err := readCookie(test.keys, test.cookie, Ctx.SignedCookie)
+
+			assert.ErrorIs(t, err, test.exceptedErr)
+			assert.Equal(t, test.exceptedValue, value)
+		})
+	}
+}
+
+// TestCtxEncryptedCookie tests the encryption and decryption of cookies including key rotation.
+func TestCtxEncryptedCookie(t *testing.T) {
+	oldKey := []byte("old secret key")
+	newKey := []byte("new secret key")
+
+	cookie := roundTripCookie(t, [][]byte{oldKey}, func(ctx Ctx) error {
+		if err := ctx.SetEncryptedCookie(&http.Cookie{Name: "session", Value: "user=1"}); err != nil {
+			return err
+		}
+
+		return ctx.SendStatus(http.StatusOK)
+	})
+	assert.NotContains(t, cookie.Value, "user")
+
+	tampered := *cookie
+	tampered.Value = "A" + cookie.Value[1:]
+	if cookie.Value[0] == 'A' {
+		tampered.Value = "B" + cookie.Value[1:]
+	}
+
+	cases := []struct {
+		name          string
+		keys          [][]byte
+		cookie        *http.Cookie
+		exceptedValue string
+		exceptedErr   error
+	}{
+		{
+			name:          "Valid cookie",
+			keys:          [][]byte{oldKey},
+			cookie:        cookie,
+			exceptedValue: "user=1",
+		},
+		{
+			name:          "Rotated key",
+			keys:          [][]byte{newKey, oldKey},
+			cookie:        cookie,
+			exceptedValue: "user=1",
+		},
+		{
+			name:        "Unknown key",
+			keys:        [][]byte{newKey},
+			cookie:      cookie,
+			exceptedErr: ErrCookieDecryption,
+		},
+		{
+			name:        "Tampered value",
+			keys:        [][]byte{oldKey},
+			cookie:      &tampered,
+			exceptedErr: ErrCookieDecryption,
+		},
+		{
+			name:        "Missing cookie",
+			keys:        [][]byte{oldKey},
+			exceptedErr: http.ErrNoCookie,
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			value, err := readCookie(test.keys, test.cookie, Ctx.EncryptedCookie)
+
+			assert.ErrorIs(t, err, test.exceptedErr)
+			assert.Equal(t, test.exceptedValue, value)
+		})
+	}
+}
+
+// TestCtxSetSignedCookie_errorTooLarge tests the rejection of cookies exceeding the size supported by browsers.
+func TestCtxSetSignedCookie_errorTooLarge(t *testing.T) {
+	rt := New()
+	rt.SetCookieKeys([]byte("secret"))
+	rt.Get("/set", func(ctx Ctx) error {
+		return ctx.SetSignedCookie(&http.Cookie{Name: "session", Value: strings.Repeat("a", maxCookieSize)})
+	})
+
+	rr := httptest.NewRecorder()
+	rt.Mux().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/set", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	assert.Empty(t, rr.Result().Cookies())
+}
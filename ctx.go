@@ -1,11 +1,17 @@
 package wayes
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"io"
+	"io/fs"
+	"mime/multipart"
 	"net/http"
+	"sync/atomic"
+	"time"
 )
 
 var (
@@ -14,8 +20,23 @@ var (
 
 	// errInvalidBody represents an error indicating an invalid request body.
 	errInvalidBody = errors.New("invalid body")
+
+	// errBodyTooLarge represents an error indicating that the request body exceeds the size limit.
+	errBodyTooLarge = errors.New("body too large")
 )
 
+// localsKey represents a type for keys of values stored by the wayes package in the context.
+type localsKey string
+
+// PrincipalKey is the key under which authentication middlewares store the authenticated principal in [Ctx.Locals].
+const PrincipalKey localsKey = "principal"
+
+// CSRFTokenKey is the key under which the CSRF middleware stores the token for templates in [Ctx.Locals].
+const CSRFTokenKey localsKey = "csrf"
+
+// CSPNonceKey is the key under which the Secure middleware stores the Content-Security-Policy nonce in [Ctx.Locals].
+const CSPNonceKey localsKey = "cspNonce"
+
 // Map represents a map of key-value pairs.
 type Map map[string]any
 
@@ -34,6 +55,18 @@ type Ctx interface {
 	// Request returns the underlying [http.Request] associated with the context.
 	Request() *http.Request
 
+	// SetResponse replaces the underlying [http.ResponseWriter] associated with the context.
+	SetResponse(w http.ResponseWriter)
+
+	// SetRequest replaces the underlying [http.Request] associated with the context.
+	SetRequest(r *http.Request)
+
+	// Copy returns a copy of the context that can be safely used in another goroutine.
+	Copy() Ctx
+
+	// Pattern returns the route pattern that matched the request, for example "GET /users/{id}".
+	Pattern() string
+
 	// Locals sets or retrieves values associated with the context using the provided key.
 	Locals(key any, value ...any) any
 
@@ -64,6 +97,125 @@ type Ctx interface {
 	// JSON sends a json object response message to the user.
 	JSON(data any) error
 
+	// CacheControl sets the Cache-Control header for the response, merging it with the existing value.
+	CacheControl(cc CacheControl)
+
+	// RequestCacheControl returns the parsed directives of the Cache-Control request header.
+	RequestCacheControl() CacheDirectives
+
+	// LastModified sets the Last-Modified header for the response.
+	LastModified(t time.Time)
+
+	// Expires sets the Expires header for the response.
+	Expires(t time.Time)
+
+	// Vary adds the request headers to the Vary header for the response.
+	Vary(headers ...string)
+
+	// IP returns the address of the client, honouring the forwarding headers of trusted proxies.
+	IP() string
+
+	// IPs returns the addresses of the client and the proxies, honouring the forwarding headers of trusted proxies.
+	IPs() []string
+
+	// Scheme returns the scheme of the request, honouring the forwarding headers of trusted proxies.
+	Scheme() string
+
+	// Hostname returns the host of the request without the port, honouring the forwarding headers of trusted proxies.
+	Hostname() string
+
+	// Protocol returns the protocol version of the request.
+	Protocol() string
+
+	// Cookie returns the value of the request cookie, an empty string if the cookie is missing.
+	Cookie(name string) string
+
+	// SetCookie adds the Set-Cookie header to the response.
+	SetCookie(cookie *http.Cookie)
+
+	// ClearCookie instructs the client to remove the cookie.
+	ClearCookie(name string)
+
+	// SetSignedCookie adds the Set-Cookie header with the value signed by the cookie key of the router.
+	SetSignedCookie(cookie *http.Cookie) error
+
+	// SignedCookie returns the value of the signed request cookie after verifying its signature.
+	SignedCookie(name string) (string, error)
+
+	// SetEncryptedCookie adds the Set-Cookie header with the value encrypted by the cookie key of the router.
+	SetEncryptedCookie(cookie *http.Cookie) error
+
+	// EncryptedCookie returns the decrypted value of the encrypted request cookie.
+	EncryptedCookie(name string) (string, error)
+
+	// SendFile sends the file from the path of the local file system.
+	SendFile(name string) error
+
+	// SendFS sends the file from the file system.
+	SendFS(fsys fs.FS, name string) error
+
+	// Attachment sets the Content-Disposition header to attachment with the optional filename.
+	Attachment(filename ...string)
+
+	// Download sends the file from the path of the local file system as an attachment.
+	Download(name string, filename ...string) error
+
+	// Stream copies the reader to the response, flushing every chunk to the client.
+	Stream(r io.Reader, contentType string) error
+
+	// StreamJSON streams the values encoded by the handler as newline-delimited JSON or as a JSON array.
+	StreamJSON(handler func(enc *StreamEncoder) error, config ...StreamJSONConfig) error
+
+	// StreamWriter streams the data written by the handler to the buffered writer.
+	StreamWriter(handler func(w *bufio.Writer) error) error
+
+	// Committed reports whether the response was sent by a streaming method or the connection was hijacked,
+	// so the response can no longer be replaced, for example by the error handler.
+	Committed() bool
+
+	// MultipartForm parses the multipart form of the request.
+	MultipartForm() (*multipart.Form, error)
+
+	// FormFile returns the first file uploaded with the form field name.
+	FormFile(name string) (*multipart.FileHeader, error)
+
+	// SaveFile saves the uploaded file to the destination path.
+	SaveFile(fh *multipart.FileHeader, dst string) error
+
+	// StreamMultipart reads the multipart form part by part without storing it.
+	StreamMultipart(handler func(part *UploadPart) error) error
+
+	// Redirect redirects the request to the trusted location with the status code, 302 Found by default.
+	Redirect(location string, status ...int) error
+
+	// RedirectLocal redirects the request to the target supplied by the user if it points to the host of the request,
+	// otherwise to the fallback.
+	RedirectLocal(target, fallback string, status ...int) error
+
+	// RedirectBack redirects the request to the page from the Referer header if it points to the host of the request,
+	// otherwise to the fallback.
+	RedirectBack(fallback string, status ...int) error
+
+	// RedirectToRoute redirects the request to the named route with the parameters given as key-value pairs.
+	RedirectToRoute(name string, params ...string) error
+
+	// RouteURL builds the URL of the named route with the parameters given as key-value pairs.
+	RouteURL(name string, params ...string) (string, error)
+
+	// Render renders the template with the renderer of the router and sends it as an HTML response.
+	Render(name string, data any, layouts ...string) error
+
+	// SSE streams server-sent events to the client until the handler returns or the client disconnects.
+	SSE(handler func(stream *EventStream) error, config ...SSEConfig) error
+
+	// Fresh reports whether the client cache of the response is still fresh according to
+	// the If-None-Match and If-Modified-Since request headers.
+	Fresh() bool
+
+	// Precondition evaluates the If-Match, If-Unmodified-Since and If-None-Match request headers
+	// and returns an [Error] with 412 Precondition Failed if they are not satisfied.
+	Precondition() error
+
 	// Next executes the next handler in the chain.
 	Next() error
 
@@ -76,19 +228,35 @@ type Ctx interface {
 
 // ctx represents a structure that implements the [Ctx] interface.
 type ctx struct {
+	settings  *settings
 	validator Validater
 	response  http.ResponseWriter
 	request   *http.Request
 	status    int
+	pattern   string
+	uploads   *uploads
+	committed *atomic.Bool
+	hijacked  bool
+	handlers  []Handler
+	index     int
 }
 
 // NewCtx creates a new instance of [Ctx].
 func NewCtx(validator Validater, w http.ResponseWriter, r *http.Request) Ctx {
+	return newCtx(newSettings(), validator, w, r)
+}
+
+// newCtx creates a new instance of [ctx] without handlers in the chain.
+func newCtx(settings *settings, validator Validater, w http.ResponseWriter, r *http.Request) *ctx {
 	return &ctx{
+		settings:  settings,
 		validator: validator,
 		response:  w,
 		request:   r,
 		status:    http.StatusOK,
+		uploads:   &uploads{},
+		committed: &atomic.Bool{},
+		index:     -1,
 	}
 }
 
@@ -102,6 +270,38 @@ func (c *ctx) Request() *http.Request {
 	return c.request
 }
 
+// SetResponse replaces the underlying [http.ResponseWriter] associated with the context.
+// It allows middlewares to wrap the response writer.
+func (c *ctx) SetResponse(w http.ResponseWriter) {
+	c.response = w
+}
+
+// SetRequest replaces the underlying [http.Request] associated with the context.
+// It allows middlewares to change the request, for example its context.
+func (c *ctx) SetRequest(r *http.Request) {
+	c.request = r
+}
+
+// Copy returns a copy of the context that can be safely used in another goroutine.
+// The copy continues the handler chain from the current position.
+func (c *ctx) Copy() Ctx {
+	clone := *c
+
+	return &clone
+}
+
+// Committed reports whether the response was sent by a streaming method or the connection was hijacked.
+// The state is shared between the copies of the context.
+func (c *ctx) Committed() bool {
+	return c.committed.Load()
+}
+
+// Pattern returns the route pattern that matched the request, for example "GET /users/{id}".
+// The pattern includes the prefixes of the route groups.
+func (c *ctx) Pattern() string {
+	return c.pattern
+}
+
 // Locals sets or retrieves values associated with the context using the provided key.
 // If only the key is provided, it retrieves the value associated with that key.
 // If key and value are provided, it sets the value associated with the key and returns the value.
@@ -150,6 +350,12 @@ func (c *ctx) ContentType(value string) {
 // Decode decodes the request body into the provided data.
 func (c *ctx) Decode(data any) error {
 	if err := json.NewDecoder(c.request.Body).Decode(data); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.Status(http.StatusRequestEntityTooLarge)
+			return errBodyTooLarge
+		}
+
 		c.Status(http.StatusBadRequest)
 
 		if (c.request.Method == http.MethodPost ||
@@ -184,8 +390,14 @@ func (c *ctx) Validate(data any) error {
 
 // Encode encodes the provided data into the response body.
 func (c *ctx) Encode(data any) error {
-	encoder := json.NewEncoder(c.response)
-	encoder.SetIndent("", "  ")
+	return encode(c.response, data, "  ")
+}
+
+// encode encodes the provided data as json followed by a newline into the writer.
+// The json is indented with the indent unless it is empty.
+func encode(w io.Writer, data any, indent string) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", indent)
 	if err := encoder.Encode(data); err != nil {
 		return err
 	}
@@ -196,29 +408,69 @@ func (c *ctx) Encode(data any) error {
 // Write sends a plain text response message to the user.
 func (c *ctx) Write(message string) error {
 	c.ContentType("text/plain; charset=utf-8")
-	c.response.WriteHeader(c.status)
 
-	if c.status == http.StatusNoContent {
-		return nil
+	return c.send([]byte(message))
+}
+
+// JSON sends a json object response message to the user.
+// The body is buffered to generate the ETag if it is enabled on the router.
+func (c *ctx) JSON(data any) error {
+	c.ContentType("application/json")
+
+	if c.settings.etag == ETagDisabled {
+		c.response.WriteHeader(c.status)
+		return c.Encode(data)
 	}
 
-	if _, err := c.response.Write([]byte(message)); err != nil {
+	var buf bytes.Buffer
+	if err := encode(&buf, data, "  "); err != nil {
 		return err
 	}
 
-	return nil
+	return c.send(buf.Bytes())
 }
 
-// JSON sends a json object response message to the user.
-func (c *ctx) JSON(data any) error {
-	c.ContentType("application/json")
+// send writes the status code and the body to the response.
+// If the generation of ETags is enabled, it sets the ETag header and
+// answers conditional GET and HEAD requests with 304 Not Modified or 412 Precondition Failed.
+func (c *ctx) send(body []byte) error {
+	if c.settings.etag != ETagDisabled && c.status >= http.StatusOK && c.status < http.StatusMultipleChoices {
+		if c.Get("ETag") == "" {
+			c.Set("ETag", GenerateETag(body, c.settings.etag == ETagWeak))
+		}
+
+		if c.request.Method == http.MethodGet || c.request.Method == http.MethodHead {
+			switch checkConditions(c.request, c.response.Header()) {
+			case http.StatusNotModified:
+				writeNotModified(c.response)
+				return nil
+			case http.StatusPreconditionFailed:
+				return NewError(http.StatusPreconditionFailed)
+			}
+		}
+	}
+
 	c.response.WriteHeader(c.status)
 
-	return c.Encode(data)
+	if c.status == http.StatusNoContent {
+		return nil
+	}
+
+	if _, err := c.response.Write(body); err != nil {
+		return err
+	}
+
+	return nil
 }
 
 // Next calls the next handler in the chain.
+// A middleware that does not call Next stops the chain, which allows it to respond on its own.
 func (c *ctx) Next() error {
+	c.index++
+	if c.index < len(c.handlers) {
+		return c.handlers[c.index](c)
+	}
+
 	return nil
 }
 
@@ -803,3 +803,41 @@ func TestCtxWrite_noContent(t *testing.T) {
 	assert.Equal(t, http.StatusNoContent, rr.Code)
 	assert.Equal(t, "", rr.Body.String())
 }
+
+// TestCtxCopy tests the replacement of the request and response and copying of the context.
+func TestCtxCopy(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/test", nil)
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	ctx := NewCtx(nil, rr, req)
+
+	clone := ctx.Copy()
+	rr2 := httptest.NewRecorder()
+	req2 := req.Clone(req.Context())
+	clone.SetResponse(rr2)
+	clone.SetRequest(req2)
+	clone.Locals("foo", "bar")
+
+	assert.Equal(t, rr, ctx.Response())
+	assert.Equal(t, req, ctx.Request())
+	assert.Nil(t, ctx.Locals("foo"))
+	assert.Equal(t, rr2, clone.Response())
+	assert.Equal(t, "bar", clone.Locals("foo"))
+}
+
+// TestCtxDecode_errorBodyTooLarge tests the decoding of a body that exceeds the size limit.
+func TestCtxDecode_errorBodyTooLarge(t *testing.T) {
+	req, err := http.NewRequest(http.MethodPost, "/test", bytes.NewBufferString(`{"name":"too large"}`))
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	req.Body = http.MaxBytesReader(rr, req.Body, 8)
+	context := NewCtx(nil, rr, req)
+
+	var data map[string]string
+	err = context.Decode(&data)
+
+	assert.Equal(t, errBodyTooLarge, err)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, context.(*ctx).status)
+}
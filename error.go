@@ -0,0 +1,54 @@
+package wayes
+
+import (
+	"errors"
+	"net/http"
+)
+
+// ErrorHandler defines a function signature for handling errors returned by handlers and middlewares.
+// It must not write the response when [Ctx.Committed] reports true, the error can only be logged then.
+type ErrorHandler func(ctx Ctx, err error)
+
+// Error represents an error with an HTTP status code.
+type Error struct {
+	Code    int
+	Message string
+}
+
+// NewError creates a new instance of [Error].
+// If the message is not provided, the status text of the code is used.
+func NewError(code int, message ...string) *Error {
+	err := &Error{
+		Code:    code,
+		Message: http.StatusText(code),
+	}
+
+	if len(message) > 0 && message[0] != "" {
+		err.Message = message[0]
+	}
+
+	return err
+}
+
+// Error returns the error message.
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// DefaultErrorHandler sends the error message as a plain text response.
+// The status code is taken from [Error], otherwise 500 Internal Server Error is used.
+// Nothing is sent when the response is already committed.
+func DefaultErrorHandler(ctx Ctx, err error) {
+	if ctx.Committed() {
+		return
+	}
+
+	code := http.StatusInternalServerError
+
+	var e *Error
+	if errors.As(err, &e) {
+		code = e.Code
+	}
+
+	http.Error(ctx.Response(), err.Error(), code)
+}
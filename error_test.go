@@ -0,0 +1,71 @@
+package wayes
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestErrorHandler tests the handling of errors returned by handlers.
+func TestErrorHandler(t *testing.T) {
+	cases := []struct {
+		name         string
+		err          error
+		errorHandler ErrorHandler
+		exceptedCode int
+		exceptedBody string
+	}{
+		{
+			name:         "Plain error",
+			err:          errors.New("test error"),
+			exceptedCode: http.StatusInternalServerError,
+			exceptedBody: "test error\n",
+		},
+		{
+			name:         "Error with code",
+			err:          NewError(http.StatusTooManyRequests),
+			exceptedCode: http.StatusTooManyRequests,
+			exceptedBody: "Too Many Requests\n",
+		},
+		{
+			name:         "Wrapped error with code",
+			err:          fmt.Errorf("wrapped: %w", NewError(http.StatusNotFound, "user not found")),
+			exceptedCode: http.StatusNotFound,
+			exceptedBody: "wrapped: user not found\n",
+		},
+		{
+			name: "Custom error handler",
+			err:  NewError(http.StatusForbidden, "access denied"),
+			errorHandler: func(ctx Ctx, err error) {
+				_ = ctx.Status(http.StatusTeapot).JSON(Response{Message: err.Error()})
+			},
+			exceptedCode: http.StatusTeapot,
+			exceptedBody: "{\n  \"success\": false,\n  \"message\": \"access denied\"\n}\n",
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			rt := New()
+			group := rt.Group("/group")
+			rt.SetErrorHandler(test.errorHandler)
+			group.Get("/test", func(ctx Ctx) error {
+				return test.err
+			})
+
+			req, err := http.NewRequest(http.MethodGet, "/group/test", nil)
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			rt.Mux().ServeHTTP(rr, req)
+
+			assert.Equal(t, test.exceptedCode, rr.Code)
+			assert.Equal(t, test.exceptedBody, rr.Body.String())
+		})
+	}
+}
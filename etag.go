@@ -0,0 +1,176 @@
+package wayes
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ETagMode represents the mode of the ETag generation.
+type ETagMode int
+
+const (
+	// ETagDisabled disables the generation of ETags.
+	ETagDisabled ETagMode = iota
+
+	// ETagStrong generates strong ETags.
+	ETagStrong
+
+	// ETagWeak generates weak ETags.
+	ETagWeak
+)
+
+// GenerateETag returns an ETag computed from the hash of the body.
+func GenerateETag(body []byte, weak bool) string {
+	sum := sha256.Sum256(body)
+	etag := `"` + base64.RawURLEncoding.EncodeToString(sum[:16]) + `"`
+
+	if weak {
+		return "W/" + etag
+	}
+
+	return etag
+}
+
+// Fresh reports whether the client cache of the response is still fresh according to
+// the If-None-Match and If-Modified-Since request headers.
+// The request headers are compared with the ETag and Last-Modified response headers,
+// so they should be set before calling Fresh.
+func (c *ctx) Fresh() bool {
+	if c.request.Method != http.MethodGet && c.request.Method != http.MethodHead {
+		return false
+	}
+
+	return checkConditions(c.request, c.response.Header()) == http.StatusNotModified
+}
+
+// Precondition evaluates the If-Match, If-Unmodified-Since and If-None-Match request headers
+// and returns an [Error] with 412 Precondition Failed if they are not satisfied.
+// It allows optimistic concurrency control for unsafe methods such as PUT and PATCH:
+// set the ETag or Last-Modified header of the current resource and call Precondition before changing it.
+func (c *ctx) Precondition() error {
+	if checkConditions(c.request, c.response.Header()) == http.StatusPreconditionFailed {
+		return NewError(http.StatusPreconditionFailed)
+	}
+
+	return nil
+}
+
+// checkConditions evaluates the conditional request headers in the order defined by RFC 9110.
+// It returns 304 Not Modified, 412 Precondition Failed or 0 if the request should be processed.
+func checkConditions(r *http.Request, header http.Header) int {
+	etag := header.Get("ETag")
+	lastModified, hasLastModified := parseHTTPTime(header.Get("Last-Modified"))
+
+	if ifMatch := r.Header.Get("If-Match"); ifMatch != "" {
+		if !matchETag(ifMatch, etag, false) {
+			return http.StatusPreconditionFailed
+		}
+	} else if since, ok := parseHTTPTime(r.Header.Get("If-Unmodified-Since")); ok && hasLastModified {
+		if lastModified.After(since) {
+			return http.StatusPreconditionFailed
+		}
+	}
+
+	safe := r.Method == http.MethodGet || r.Method == http.MethodHead
+
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		if matchETag(ifNoneMatch, etag, true) {
+			if safe {
+				return http.StatusNotModified
+			}
+
+			return http.StatusPreconditionFailed
+		}
+	} else if since, ok := parseHTTPTime(r.Header.Get("If-Modified-Since")); ok && hasLastModified && safe {
+		if !lastModified.After(since) {
+			return http.StatusNotModified
+		}
+	}
+
+	return 0
+}
+
+// matchETag reports whether the list of ETags from a conditional header matches the ETag.
+// Weak comparison ignores the weakness indicator, strong comparison requires both ETags to be strong.
+func matchETag(list, etag string, weak bool) bool {
+	if etag == "" {
+		return false
+	}
+
+	if strings.TrimSpace(list) == "*" {
+		return true
+	}
+
+	for _, candidate := range splitETags(list) {
+		if weak {
+			if strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+
+			continue
+		}
+
+		if !strings.HasPrefix(candidate, "W/") && !strings.HasPrefix(etag, "W/") && candidate == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// splitETags splits the comma-separated list of ETags, keeping commas inside quoted values.
+func splitETags(list string) []string {
+	etags := make([]string, 0, 1)
+
+	for {
+		list = strings.TrimLeft(list, " \t,")
+		if list == "" {
+			return etags
+		}
+
+		start := 0
+		if strings.HasPrefix(list, "W/") {
+			start = 2
+		}
+
+		if len(list) <= start || list[start] != '"' {
+			return etags
+		}
+
+		end := strings.IndexByte(list[start+1:], '"')
+		if end < 0 {
+			return etags
+		}
+
+		end += start + 2
+		etags = append(etags, list[:end])
+		list = list[end:]
+	}
+}
+
+// parseHTTPTime parses the value of a date header.
+func parseHTTPTime(value string) (time.Time, bool) {
+	if value == "" {
+		return time.Time{}, false
+	}
+
+	t, err := http.ParseTime(value)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return t, true
+}
+
+// writeNotModified responds with 304 Not Modified, removing the headers that describe the body.
+func writeNotModified(w http.ResponseWriter) {
+	header := w.Header()
+	header.Del("Content-Type")
+	header.Del("Content-Length")
+	header.Del("Content-Encoding")
+
+	w.WriteHeader(http.StatusNotModified)
+}
@@ -0,0 +1,199 @@
+package wayes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCtxETag tests the generation of ETags and handling of conditional requests by the context.
+func TestCtxETag(t *testing.T) {
+	body := "Test etag response"
+	strong := GenerateETag([]byte(body), false)
+	weak := GenerateETag([]byte(body), true)
+
+	cases := []struct {
+		name         string
+		mode         ETagMode
+		headers      map[string]string
+		exceptedCode int
+		exceptedETag string
+		exceptedBody string
+	}{
+		{
+			name:         "Disabled",
+			mode:         ETagDisabled,
+			headers:      map[string]string{"If-None-Match": strong},
+			exceptedCode: http.StatusOK,
+			exceptedBody: body,
+		},
+		{
+			name:         "Strong ETag",
+			mode:         ETagStrong,
+			exceptedCode: http.StatusOK,
+			exceptedETag: strong,
+			exceptedBody: body,
+		},
+		{
+			name:         "Weak ETag",
+			mode:         ETagWeak,
+			exceptedCode: http.StatusOK,
+			exceptedETag: weak,
+			exceptedBody: body,
+		},
+		{
+			name:         "If-None-Match matches",
+			mode:         ETagStrong,
+			headers:      map[string]string{"If-None-Match": `"other", ` + weak},
+			exceptedCode: http.StatusNotModified,
+			exceptedETag: strong,
+		},
+		{
+			name:         "If-None-Match does not match",
+			mode:         ETagStrong,
+			headers:      map[string]string{"If-None-Match": `"other"`},
+			exceptedCode: http.StatusOK,
+			exceptedETag: strong,
+			exceptedBody: body,
+		},
+		{
+			name:         "If-Match does not match",
+			mode:         ETagStrong,
+			headers:      map[string]string{"If-Match": `"other"`},
+			exceptedCode: http.StatusPreconditionFailed,
+			exceptedETag: strong,
+			exceptedBody: "Precondition Failed\n",
+		},
+		{
+			name:         "If-Match requires strong comparison",
+			mode:         ETagWeak,
+			headers:      map[string]string{"If-Match": weak},
+			exceptedCode: http.StatusPreconditionFailed,
+			exceptedETag: weak,
+			exceptedBody: "Precondition Failed\n",
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			rt := New()
+			rt.SetETag(test.mode)
+			rt.Get("/test", func(ctx Ctx) error {
+				return ctx.Write(body)
+			})
+
+			req, err := http.NewRequest(http.MethodGet, "/test", nil)
+			require.NoError(t, err)
+			for key, value := range test.headers {
+				req.Header.Set(key, value)
+			}
+
+			rr := httptest.NewRecorder()
+			rt.Mux().ServeHTTP(rr, req)
+
+			assert.Equal(t, test.exceptedCode, rr.Code)
+			assert.Equal(t, test.exceptedETag, rr.Header().Get("ETag"))
+			assert.Equal(t, test.exceptedBody, rr.Body.String())
+		})
+	}
+}
+
+// TestCtxFresh tests the validation of the client cache by the modification date.
+func TestCtxFresh(t *testing.T) {
+	modified := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name          string
+		method        string
+		ifModified    time.Time
+		exceptedFresh bool
+	}{
+		{
+			name:          "Not modified",
+			method:        http.MethodGet,
+			ifModified:    modified,
+			exceptedFresh: true,
+		},
+		{
+			name:       "Modified",
+			method:     http.MethodGet,
+			ifModified: modified.Add(-time.Hour),
+		},
+		{
+			name:       "Unsafe method",
+			method:     http.MethodPost,
+			ifModified: modified,
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			req, err := http.NewRequest(test.method, "/test", nil)
+			require.NoError(t, err)
+			req.Header.Set("If-Modified-Since", test.ifModified.Format(http.TimeFormat))
+
+			ctx := NewCtx(nil, httptest.NewRecorder(), req)
+			ctx.Set("Last-Modified", modified.Format(http.TimeFormat))
+
+			assert.Equal(t, test.exceptedFresh, ctx.Fresh())
+		})
+	}
+}
+
+// TestCtxPrecondition tests the optimistic concurrency control of unsafe requests.
+func TestCtxPrecondition(t *testing.T) {
+	cases := []struct {
+		name          string
+		headers       map[string]string
+		exceptedError bool
+	}{
+		{
+			name:    "Without conditions",
+			headers: map[string]string{},
+		},
+		{
+			name:    "If-Match matches",
+			headers: map[string]string{"If-Match": `"v2"`},
+		},
+		{
+			name:          "If-Match does not match",
+			headers:       map[string]string{"If-Match": `"v1"`},
+			exceptedError: true,
+		},
+		{
+			name:          "If-None-Match any",
+			headers:       map[string]string{"If-None-Match": "*"},
+			exceptedError: true,
+		},
+		{
+			name:          "If-Unmodified-Since",
+			headers:       map[string]string{"If-Unmodified-Since": "Mon, 01 Jan 2024 00:00:00 GMT"},
+			exceptedError: true,
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPut, "/test", nil)
+			require.NoError(t, err)
+			for key, value := range test.headers {
+				req.Header.Set(key, value)
+			}
+
+			ctx := NewCtx(nil, httptest.NewRecorder(), req)
+			ctx.Set("ETag", `"v2"`)
+			ctx.Set("Last-Modified", "Wed, 01 May 2024 00:00:00 GMT")
+
+			err = ctx.Precondition()
+			if test.exceptedError {
+				assert.Equal(t, NewError(http.StatusPreconditionFailed), err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package wayes
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// streamBufferSize is the size of the chunks flushed by [Ctx.Stream].
+const streamBufferSize = 32 * 1024
+
+// SendFile sends the file from the path of the local file system.
+// The Content-Type is detected by the extension or by the content, and Range, If-Range
+// and conditional requests are handled by [http.ServeContent].
+func (c *ctx) SendFile(name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return fileError(err)
+	}
+	defer f.Close()
+
+	return serveContent(c, f, filepath.Base(name))
+}
+
+// SendFS sends the file from the file system, for example an [embed.FS].
+func (c *ctx) SendFS(fsys fs.FS, name string) error {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return fileError(err)
+	}
+	defer f.Close()
+
+	return serveContent(c, f, path.Base(name))
+}
+
+// Attachment sets the Content-Disposition header to attachment, so the browser downloads the response.
+// If the filename is provided, it is included in the header and sets the Content-Type by its extension.
+func (c *ctx) Attachment(filename ...string) {
+	if len(filename) == 0 || filename[0] == "" {
+		c.Set("Content-Disposition", "attachment")
+		return
+	}
+
+	name := filepath.Base(filename[0])
+	if contentType := mime.TypeByExtension(filepath.Ext(name)); contentType != "" {
+		c.ContentType(contentType)
+	}
+
+	c.Set("Content-Disposition", contentDisposition("attachment", name))
+}
+
+// Download sends the file from the path of the local file system as an attachment.
+// The name of the downloaded file defaults to the base name of the path.
+func (c *ctx) Download(name string, filename ...string) error {
+	downloadName := filepath.Base(name)
+	if len(filename) > 0 && filename[0] != "" {
+		downloadName = filename[0]
+	}
+
+	c.Set("Content-Disposition", contentDisposition("attachment", downloadName))
+
+	return c.SendFile(name)
+}
+
+// Stream copies the reader to the response in chunks, flushing every chunk to the client.
+// Copying stops when the request context is cancelled. The reader is closed if it implements [io.Closer].
+func (c *ctx) Stream(r io.Reader, contentType string) error {
+	if closer, ok := r.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	if contentType != "" {
+		c.ContentType(contentType)
+	}
+	c.response.Header().Del("Content-Length")
+	c.response.WriteHeader(c.status)
+	c.committed.Store(true)
+
+	controller := http.NewResponseController(c.response)
+	done := c.request.Context().Done()
+	buf := make([]byte, streamBufferSize)
+
+	for {
+		select {
+		case <-done:
+			return c.request.Context().Err()
+		default:
+		}
+
+		n, err := r.Read(buf)
+		if n > 0 {
+			if _, err := c.response.Write(buf[:n]); err != nil {
+				return err
+			}
+
+			if err := controller.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+				return err
+			}
+		}
+
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// serveContent sends the file with [http.ServeContent].
+// Files that cannot seek are read into memory.
+func serveContent(ctx Ctx, f fs.File, name string) error {
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	if info.IsDir() {
+		return NewError(http.StatusNotFound)
+	}
+
+	content, ok := f.(io.ReadSeeker)
+	if !ok {
+		data, err := io.ReadAll(f)
+		if err != nil {
+			return err
+		}
+		content = bytes.NewReader(data)
+	}
+
+	http.ServeContent(ctx.Response(), ctx.Request(), name, info.ModTime(), content)
+
+	return nil
+}
+
+// fileError returns an [Error] with 404 Not Found or 403 Forbidden for the errors of opening files.
+func fileError(err error) error {
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return NewError(http.StatusNotFound)
+	case errors.Is(err, fs.ErrPermission):
+		return NewError(http.StatusForbidden)
+	}
+
+	return err
+}
+
+// contentDisposition returns the value of the Content-Disposition header with the filename encoded
+// according to RFC 6266: an ASCII fallback in the filename parameter and the UTF-8 name in filename*.
+func contentDisposition(disposition, filename string) string {
+	var fallback strings.Builder
+	ascii := true
+
+	for _, r := range filename {
+		if r < 0x20 || r >= 0x7f || r == '"' || r == '\\' || r == '%' {
+			fallback.WriteByte('_')
+			ascii = false
+			continue
+		}
+
+		fallback.WriteRune(r)
+	}
+
+	value := disposition + `; filename="` + fallback.String() + `"`
+	if ascii {
+		return value
+	}
+
+	var encoded strings.Builder
+	for _, b := range []byte(filename) {
+		if isAttrChar(b) {
+			encoded.WriteByte(b)
+			continue
+		}
+
+		fmt.Fprintf(&encoded, "%%%02X", b)
+	}
+
+	return value + "; filename*=UTF-8''" + encoded.String()
+}
+
+// isAttrChar reports whether the byte can be used unencoded in an extended parameter value (RFC 8187).
+func isAttrChar(b byte) bool {
+	switch {
+	case 'a' <= b && b <= 'z', 'A' <= b && b <= 'Z', '0' <= b && b <= '9':
+		return true
+	}
+
+	return strings.IndexByte("!#$&+-.^_`|~", b) >= 0
+}
@@ -0,0 +1,187 @@
+package wayes
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCtxSendFile tests the sending of files from the local file system and from an fs.FS.
+func TestCtxSendFile(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "report.csv"), []byte("id,name\n1,alice\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "data"), []byte("%PDF-1.7 test"), 0o600))
+
+	fsys := fstest.MapFS{"docs/readme.txt": {Data: []byte("readme")}}
+
+	cases := []struct {
+		name                string
+		path                string
+		headers             map[string]string
+		exceptedCode        int
+		exceptedBody        string
+		exceptedType        string
+		exceptedDisposition string
+	}{
+		{
+			name:         "Send file",
+			path:         "/file/report.csv",
+			exceptedCode: http.StatusOK,
+			exceptedBody: "id,name\n1,alice\n",
+			exceptedType: "text/csv; charset=utf-8",
+		},
+		{
+			name:         "Sniffed type",
+			path:         "/file/data",
+			exceptedCode: http.StatusOK,
+			exceptedBody: "%PDF-1.7 test",
+			exceptedType: "application/pdf",
+		},
+		{
+			name:         "Range",
+			path:         "/file/report.csv",
+			headers:      map[string]string{"Range": "bytes=8-"},
+			exceptedCode: http.StatusPartialContent,
+			exceptedBody: "1,alice\n",
+			exceptedType: "text/csv; charset=utf-8",
+		},
+		{
+			name:         "Missing file",
+			path:         "/file/missing.csv",
+			exceptedCode: http.StatusNotFound,
+			exceptedBody: "Not Found\n",
+			exceptedType: "text/plain; charset=utf-8",
+		},
+		{
+			name:         "Send FS",
+			path:         "/fs",
+			exceptedCode: http.StatusOK,
+			exceptedBody: "readme",
+			exceptedType: "text/plain; charset=utf-8",
+		},
+		{
+			name:                "Download",
+			path:                "/download",
+			exceptedCode:        http.StatusOK,
+			exceptedBody:        "id,name\n1,alice\n",
+			exceptedType:        "text/csv; charset=utf-8",
+			exceptedDisposition: `attachment; filename="_____ 2024.csv"; filename*=UTF-8''%D0%BE%D1%82%D1%87%D0%B5%D1%82%202024.csv`,
+		},
+	}
+
+	rt := New()
+	rt.Get("/file/{name}", func(ctx Ctx) error {
+		return ctx.SendFile(filepath.Join(dir, ctx.Request().PathValue("name")))
+	})
+	rt.Get("/fs", func(ctx Ctx) error {
+		return ctx.SendFS(fsys, "docs/readme.txt")
+	})
+	rt.Get("/download", func(ctx Ctx) error {
+		return ctx.Download(filepath.Join(dir, "report.csv"), "отчет 2024.csv")
+	})
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, test.path, nil)
+			for key, value := range test.headers {
+				req.Header.Set(key, value)
+			}
+
+			rr := httptest.NewRecorder()
+			rt.Mux().ServeHTTP(rr, req)
+
+			assert.Equal(t, test.exceptedCode, rr.Code)
+			assert.Equal(t, test.exceptedBody, rr.Body.String())
+			assert.Equal(t, test.exceptedType, rr.Header().Get("Content-Type"))
+			assert.Equal(t, test.exceptedDisposition, rr.Header().Get("Content-Disposition"))
+		})
+	}
+}
+
+// TestCtxAttachment tests the Content-Disposition header set by the context.
+func TestCtxAttachment(t *testing.T) {
+	cases := []struct {
+		name                string
+		filename            []string
+		exceptedType        string
+		exceptedDisposition string
+	}{
+		{
+			name:                "Without filename",
+			exceptedDisposition: "attachment",
+		},
+		{
+			name:                "ASCII filename",
+			filename:            []string{"/tmp/report.json"},
+			exceptedType:        "application/json",
+			exceptedDisposition: `attachment; filename="report.json"`,
+		},
+		{
+			name:                "Special characters",
+			filename:            []string{`a"b%c.txt`},
+			exceptedType:        "text/plain; charset=utf-8",
+			exceptedDisposition: `attachment; filename="a_b_c.txt"; filename*=UTF-8''a%22b%25c.txt`,
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			context := NewCtx(nil, rr, httptest.NewRequest(http.MethodGet, "/", nil))
+			context.Attachment(test.filename...)
+
+			assert.Equal(t, test.exceptedType, rr.Header().Get("Content-Type"))
+			assert.Equal(t, test.exceptedDisposition, rr.Header().Get("Content-Disposition"))
+		})
+	}
+}
+
+// closeReader is a reader that records whether it was closed.
+type closeReader struct {
+	io.Reader
+	closed bool
+}
+
+// Close records that the reader was closed.
+func (r *closeReader) Close() error {
+	r.closed = true
+	return nil
+}
+
+// TestCtxStream tests the streaming of readers to the response.
+func TestCtxStream(t *testing.T) {
+	body := strings.Repeat("chunk ", 10000)
+	reader := &closeReader{Reader: strings.NewReader(body)}
+
+	rr := httptest.NewRecorder()
+	context := NewCtx(nil, rr, httptest.NewRequest(http.MethodGet, "/", nil))
+	require.NoError(t, context.Stream(reader, "text/plain"))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "text/plain", rr.Header().Get("Content-Type"))
+	assert.Equal(t, body, rr.Body.String())
+	assert.True(t, rr.Flushed)
+	assert.True(t, reader.closed)
+}
+
+// TestCtxStream_errorCancelled tests that the streaming stops when the request context is cancelled.
+func TestCtxStream_errorCancelled(t *testing.T) {
+	reqCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	rr := httptest.NewRecorder()
+	c := NewCtx(nil, rr, httptest.NewRequest(http.MethodGet, "/", nil).WithContext(reqCtx))
+
+	err := c.Stream(strings.NewReader("data"), "text/plain")
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, rr.Body.String())
+}
@@ -0,0 +1,126 @@
+package wayes
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Authorizer is an interface that defines methods for retrieving the roles and permissions of a principal.
+type Authorizer interface {
+	// Roles returns the roles granted to the principal.
+	Roles(principal any) []string
+
+	// Permissions returns the permissions granted to the principal.
+	Permissions(principal any) []string
+}
+
+// RouteOption is an interface that configures a route during its registration.
+type RouteOption interface {
+	apply(e *endpoint)
+}
+
+// Guard represents an authorization requirement for the routes.
+// The principal is read from [Ctx.Locals] by [PrincipalKey] and inspected with the router [Authorizer].
+type Guard struct {
+	// Roles lists the roles of which the principal must have at least one.
+	Roles []string
+
+	// Permissions lists the permissions the principal must have all of.
+	Permissions []string
+}
+
+// RequireRoles creates a [Guard] that requires the principal to have at least one of the roles.
+func RequireRoles(roles ...string) Guard {
+	return Guard{Roles: roles}
+}
+
+// RequirePermissions creates a [Guard] that requires the principal to have all the permissions.
+func RequirePermissions(permissions ...string) Guard {
+	return Guard{Permissions: permissions}
+}
+
+// apply adds the guard to the route.
+func (g Guard) apply(e *endpoint) {
+	e.guards = append(e.guards, g)
+}
+
+// String returns a human-readable description of the guard.
+func (g Guard) String() string {
+	parts := make([]string, 0, 2)
+	if len(g.Roles) > 0 {
+		parts = append(parts, fmt.Sprintf("roles(%s)", strings.Join(g.Roles, "|")))
+	}
+
+	if len(g.Permissions) > 0 {
+		parts = append(parts, fmt.Sprintf("permissions(%s)", strings.Join(g.Permissions, "&")))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// allows reports whether the principal satisfies the guard.
+func (g Guard) allows(authorizer Authorizer, principal any) bool {
+	if len(g.Roles) > 0 {
+		roles := authorizer.Roles(principal)
+		if !slices.ContainsFunc(g.Roles, func(role string) bool {
+			return slices.Contains(roles, role)
+		}) {
+			return false
+		}
+	}
+
+	if len(g.Permissions) > 0 {
+		permissions := authorizer.Permissions(principal)
+		for _, permission := range g.Permissions {
+			if !slices.Contains(permissions, permission) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// authorize creates a handler that checks the guards before the route handler.
+// It returns a [Error] with 401 Unauthorized if there is no principal
+// and 403 Forbidden if the principal does not satisfy a guard.
+func authorize(settings *settings, guards []Guard) Handler {
+	return func(ctx Ctx) error {
+		principal := ctx.Locals(PrincipalKey)
+		if principal == nil {
+			return NewError(http.StatusUnauthorized)
+		}
+
+		for _, guard := range guards {
+			if !guard.allows(settings.authorizer, principal) {
+				return NewError(http.StatusForbidden)
+			}
+		}
+
+		return ctx.Next()
+	}
+}
+
+// defaultAuthorizer represents a structure that implements the [Authorizer] interface.
+// It retrieves the roles and permissions from principals with the Roles and Permissions methods.
+type defaultAuthorizer struct{}
+
+// Roles returns the roles of the principal if it has the Roles method.
+func (defaultAuthorizer) Roles(principal any) []string {
+	if p, ok := principal.(interface{ Roles() []string }); ok {
+		return p.Roles()
+	}
+
+	return nil
+}
+
+// Permissions returns the permissions of the principal if it has the Permissions method.
+func (defaultAuthorizer) Permissions(principal any) []string {
+	if p, ok := principal.(interface{ Permissions() []string }); ok {
+		return p.Permissions()
+	}
+
+	return nil
+}
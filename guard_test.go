@@ -0,0 +1,132 @@
+package wayes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// PrincipalMock represents a principal with roles and permissions.
+type PrincipalMock struct {
+	roles       []string
+	permissions []string
+}
+
+// Roles returns the roles of the principal.
+func (p PrincipalMock) Roles() []string {
+	return p.roles
+}
+
+// Permissions returns the permissions of the principal.
+func (p PrincipalMock) Permissions() []string {
+	return p.permissions
+}
+
+// TestWayesGuard tests the authorization of routes and groups by roles and permissions.
+func TestWayesGuard(t *testing.T) {
+	cases := []struct {
+		name         string
+		path         string
+		principal    any
+		exceptedCode int
+	}{
+		{
+			name:         "Unprotected route",
+			path:         "/public",
+			exceptedCode: http.StatusOK,
+		},
+		{
+			name:         "Without principal",
+			path:         "/admin/users",
+			exceptedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "Missing role",
+			path:         "/admin/users",
+			principal:    PrincipalMock{roles: []string{"user"}},
+			exceptedCode: http.StatusForbidden,
+		},
+		{
+			name:         "One of the roles",
+			path:         "/admin/users",
+			principal:    PrincipalMock{roles: []string{"editor"}},
+			exceptedCode: http.StatusOK,
+		},
+		{
+			name:         "Missing permission",
+			path:         "/admin/settings",
+			principal:    PrincipalMock{roles: []string{"admin"}, permissions: []string{"settings:read"}},
+			exceptedCode: http.StatusForbidden,
+		},
+		{
+			name:         "All permissions",
+			path:         "/admin/settings",
+			principal:    PrincipalMock{roles: []string{"admin"}, permissions: []string{"settings:read", "settings:write"}},
+			exceptedCode: http.StatusOK,
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			rt := New()
+			rt.Use(func(ctx Ctx) error {
+				if test.principal != nil {
+					ctx.Locals(PrincipalKey, test.principal)
+				}
+
+				return ctx.Next()
+			})
+			rt.Get("/public", func(ctx Ctx) error {
+				return ctx.Write("public")
+			})
+
+			admin := rt.Group("/admin")
+			admin.Guard(RequireRoles("admin", "editor"))
+			admin.Get("/users", func(ctx Ctx) error {
+				return ctx.Write("users")
+			})
+			admin.Get("/settings", func(ctx Ctx) error {
+				return ctx.Write("settings")
+			}, RequirePermissions("settings:read", "settings:write"))
+
+			req, err := http.NewRequest(http.MethodGet, test.path, nil)
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			rt.Mux().ServeHTTP(rr, req)
+
+			assert.Equal(t, test.exceptedCode, rr.Code)
+		})
+	}
+}
+
+// TestWayesRoutes tests the introspection of the registered routes and their guards.
+func TestWayesRoutes(t *testing.T) {
+	handler := func(ctx Ctx) error {
+		return nil
+	}
+
+	rt := New()
+	rt.Get("/public", handler)
+	rt.Post("/public", handler)
+
+	admin := rt.Group("/admin")
+	admin.Guard(RequireRoles("admin"))
+	admin.Delete("/users/{id}", handler, RequirePermissions("users:delete"))
+
+	routes := rt.Routes()
+	require.Len(t, routes, 3)
+
+	assert.Equal(t, Route{Method: http.MethodGet, Path: "/public"}, routes[0])
+	assert.False(t, routes[0].Protected())
+	assert.False(t, routes[1].Protected())
+
+	assert.Equal(t, http.MethodDelete, routes[2].Method)
+	assert.Equal(t, "/admin/users/{id}", routes[2].Path)
+	assert.True(t, routes[2].Protected())
+	assert.Equal(t, []Guard{RequireRoles("admin"), RequirePermissions("users:delete")}, routes[2].Guards)
+	assert.Equal(t, "roles(admin)", routes[2].Guards[0].String())
+}
@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+
+	"github.com/eliofery/wayes"
+)
+
+// BasicAuthConfig represents a structure for the [BasicAuth] middleware configuration.
+type BasicAuthConfig struct {
+	// Users is a map of usernames to passwords.
+	Users map[string]string
+
+	// Validator validates the credentials, it is consulted when the user is not found in Users.
+	Validator func(username, password string) bool
+
+	// Realm is the protection space reported in the WWW-Authenticate header. Defaults to "Restricted".
+	Realm string
+
+	// ContextKey is the key under which the username is stored in [wayes.Ctx.Locals].
+	// Defaults to [wayes.PrincipalKey].
+	ContextKey any
+}
+
+// BasicAuth creates a middleware that implements the HTTP Basic authentication scheme.
+// On success the username is stored in [wayes.Ctx.Locals], otherwise a [wayes.Error]
+// with 401 Unauthorized and the WWW-Authenticate header is returned.
+func BasicAuth(config BasicAuthConfig) wayes.Handler {
+	if config.Realm == "" {
+		config.Realm = "Restricted"
+	}
+
+	if config.ContextKey == nil {
+		config.ContextKey = wayes.PrincipalKey
+	}
+
+	challenge := fmt.Sprintf("Basic realm=%q, charset=\"UTF-8\"", config.Realm)
+
+	return func(ctx wayes.Ctx) error {
+		username, password, ok := ctx.Request().BasicAuth()
+		if ok && config.valid(username, password) {
+			ctx.Locals(config.ContextKey, username)
+			return ctx.Next()
+		}
+
+		ctx.Set("WWW-Authenticate", challenge)
+
+		return wayes.NewError(http.StatusUnauthorized)
+	}
+}
+
+// valid reports whether the credentials are valid.
+// Passwords from Users are compared in constant time.
+func (config BasicAuthConfig) valid(username, password string) bool {
+	if expected, ok := config.Users[username]; ok {
+		return secureCompare(expected, password)
+	}
+
+	return config.Validator != nil && config.Validator(username, password)
+}
+
+// secureCompare compares two strings in constant time, regardless of their lengths.
+func secureCompare(a, b string) bool {
+	ha := sha256.Sum256([]byte(a))
+	hb := sha256.Sum256([]byte(b))
+
+	return subtle.ConstantTimeCompare(ha[:], hb[:]) == 1
+}
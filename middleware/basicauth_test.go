@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/eliofery/wayes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestBasicAuth tests the authentication by the HTTP Basic scheme.
+func TestBasicAuth(t *testing.T) {
+	cases := []struct {
+		name         string
+		username     string
+		password     string
+		exceptedCode int
+		exceptedBody string
+	}{
+		{
+			name:         "Valid user",
+			username:     "admin",
+			password:     "secret",
+			exceptedCode: http.StatusOK,
+			exceptedBody: "admin",
+		},
+		{
+			name:         "Valid user from validator",
+			username:     "guest",
+			password:     "guest",
+			exceptedCode: http.StatusOK,
+			exceptedBody: "guest",
+		},
+		{
+			name:         "Invalid password",
+			username:     "admin",
+			password:     "wrong",
+			exceptedCode: http.StatusUnauthorized,
+			exceptedBody: "Unauthorized\n",
+		},
+		{
+			name:         "Missing credentials",
+			exceptedCode: http.StatusUnauthorized,
+			exceptedBody: "Unauthorized\n",
+		},
+	}
+
+	rt := wayes.New()
+	rt.Use(BasicAuth(BasicAuthConfig{
+		Users: map[string]string{"admin": "secret"},
+		Validator: func(username, password string) bool {
+			return username == "guest" && password == "guest"
+		},
+		Realm: "Admin",
+	}))
+	rt.Get("/test", func(ctx wayes.Ctx) error {
+		return ctx.Write(ctx.Locals(wayes.PrincipalKey).(string))
+	})
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, "/test", nil)
+			require.NoError(t, err)
+			if test.username != "" {
+				req.SetBasicAuth(test.username, test.password)
+			}
+
+			rr := httptest.NewRecorder()
+			rt.Mux().ServeHTTP(rr, req)
+
+			assert.Equal(t, test.exceptedCode, rr.Code)
+			assert.Equal(t, test.exceptedBody, rr.Body.String())
+			if test.exceptedCode == http.StatusUnauthorized {
+				assert.Equal(t, `Basic realm="Admin", charset="UTF-8"`, rr.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
@@ -0,0 +1,389 @@
+package middleware
+
+import (
+	"container/list"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eliofery/wayes"
+)
+
+// CachedResponse represents a structure for a response stored in the cache.
+type CachedResponse struct {
+	// Status is the status code of the response.
+	Status int
+
+	// Header is the header of the response.
+	Header http.Header
+
+	// Body is the body of the response.
+	Body []byte
+
+	// Vary holds the values of the request headers listed in the Vary response header.
+	Vary map[string]string
+
+	// Shared reports whether the response is marked public or s-maxage,
+	// so it can be served to requests with credentials.
+	Shared bool
+
+	// Created is the time the response was stored.
+	Created time.Time
+}
+
+// size returns the approximate size of the response in bytes.
+func (r *CachedResponse) size() int64 {
+	size := int64(len(r.Body))
+	for key, values := range r.Header {
+		size += int64(len(key))
+		for _, value := range values {
+			size += int64(len(value))
+		}
+	}
+
+	return size
+}
+
+// CacheStore is an interface that defines methods for storing cached responses.
+// Implementations must be safe for concurrent use.
+type CacheStore interface {
+	// Get returns the response stored by the key.
+	Get(key string) (*CachedResponse, bool)
+
+	// Set stores the response by the key for the duration of ttl.
+	Set(key string, response *CachedResponse, ttl time.Duration)
+
+	// Delete removes the response stored by the key.
+	Delete(key string)
+}
+
+// CacheConfig represents a structure for the [Cache] middleware configuration.
+type CacheConfig struct {
+	// TTL is the time the responses are cached for when the handler does not set max-age. Defaults to one minute.
+	TTL time.Duration
+
+	// Store holds the cached responses. Defaults to an in-memory LRU store limited to 64 MB.
+	Store CacheStore
+
+	// Query is a list of query parameters included in the cache key. Defaults to all query parameters.
+	Query []string
+
+	// Headers is a list of request headers included in the cache key.
+	Headers []string
+
+	// IgnoreCookie handles requests with the Cookie header like requests without credentials.
+	// By default, they are treated like requests with the Authorization header.
+	IgnoreCookie bool
+}
+
+// cacheableStatus is the list of status codes of responses that are cached.
+var cacheableStatus = []int{
+	http.StatusOK,
+	http.StatusNonAuthoritativeInfo,
+	http.StatusNoContent,
+	http.StatusMultipleChoices,
+	http.StatusMovedPermanently,
+	http.StatusPermanentRedirect,
+	http.StatusNotFound,
+	http.StatusGone,
+}
+
+// Cache creates a middleware that caches complete responses of GET and HEAD requests.
+// The key consists of the method, the route pattern, the path and the selected query parameters and headers.
+// Requests with Cache-Control no-store bypass the cache, no-cache and max-age=0 force revalidation.
+// Responses with Cache-Control no-store, no-cache or private, Set-Cookie or Vary: * are not cached,
+// max-age and s-maxage of the response override the TTL. The X-Cache header reports HIT or MISS.
+//
+// Responses to requests with the Authorization or Cookie header are stored and served only when
+// they are marked public or s-maxage. The middleware must be registered after the authentication
+// middlewares, otherwise cached responses are served without authentication.
+func Cache(config ...CacheConfig) wayes.Handler {
+	cfg := CacheConfig{}
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+
+	if cfg.TTL <= 0 {
+		cfg.TTL = time.Minute
+	}
+
+	if cfg.Store == nil {
+		cfg.Store = NewMemoryCacheStore(64 << 20)
+	}
+
+	return func(ctx wayes.Ctx) error {
+		r := ctx.Request()
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			return ctx.Next()
+		}
+
+		directives := ctx.RequestCacheControl()
+		if directives.Has("no-store") {
+			return ctx.Next()
+		}
+
+		key := cfg.key(ctx)
+		credentials := cfg.credentials(r)
+
+		noCache := directives.Has("no-cache")
+		if maxAge, ok := directives.Duration("max-age"); ok && maxAge == 0 {
+			noCache = true
+		}
+
+		if cached, ok := cfg.Store.Get(key); ok && !noCache && cached.matches(r) && (cached.Shared || !credentials) {
+			return serveCached(ctx, cached)
+		}
+
+		original := ctx.Response()
+		cw := newCaptureWriter(original)
+		cw.header.Set("X-Cache", "MISS")
+
+		ctx.SetResponse(cw)
+		err := ctx.Next()
+		ctx.SetResponse(original)
+
+		if err == nil && !cw.streaming {
+			if ttl, shared, ok := cfg.ttl(cw); ok && (shared || !credentials) {
+				cfg.Store.Set(key, &CachedResponse{
+					Status:  cw.code,
+					Header:  cw.modifiedHeader(),
+					Body:    slices.Clone(cw.buf.Bytes()),
+					Vary:    varyValues(r, cw.header),
+					Shared:  shared,
+					Created: time.Now(),
+				}, ttl)
+			}
+		}
+
+		cw.flush()
+
+		return err
+	}
+}
+
+// key returns the cache key of the request.
+// The path of the request is stripped of the prefixes of the route groups, so the key includes
+// the route pattern that keeps them.
+func (cfg CacheConfig) key(ctx wayes.Ctx) string {
+	r := ctx.Request()
+
+	var key strings.Builder
+	key.WriteString(r.Method)
+	key.WriteByte(' ')
+	key.WriteString(ctx.Pattern())
+	key.WriteByte(' ')
+	key.WriteString(r.URL.Path)
+
+	query := r.URL.Query()
+	if cfg.Query != nil {
+		selected := make(url.Values, len(cfg.Query))
+		for _, name := range cfg.Query {
+			if values, ok := query[name]; ok {
+				selected[name] = values
+			}
+		}
+		query = selected
+	}
+
+	if encoded := query.Encode(); encoded != "" {
+		key.WriteByte('?')
+		key.WriteString(encoded)
+	}
+
+	for _, name := range cfg.Headers {
+		key.WriteByte('\n')
+		key.WriteString(http.CanonicalHeaderKey(name))
+		key.WriteByte(':')
+		key.WriteString(r.Header.Get(name))
+	}
+
+	return key.String()
+}
+
+// credentials reports whether the request carries credentials, see RFC 9111 section 3.5.
+func (cfg CacheConfig) credentials(r *http.Request) bool {
+	return r.Header.Get("Authorization") != "" || (!cfg.IgnoreCookie && r.Header.Get("Cookie") != "")
+}
+
+// ttl returns the time the captured response can be cached for, whether it is marked public or s-maxage
+// and whether it can be cached at all.
+func (cfg CacheConfig) ttl(cw *captureWriter) (time.Duration, bool, bool) {
+	if !slices.Contains(cacheableStatus, cw.code) {
+		return 0, false, false
+	}
+
+	header := cw.header
+	if header.Get("Set-Cookie") != "" || slices.Contains(header.Values("Vary"), "*") {
+		return 0, false, false
+	}
+
+	directives := wayes.ParseCacheControl(header.Values("Cache-Control")...)
+	for _, name := range []string{"no-store", "no-cache", "private"} {
+		if directives.Has(name) {
+			return 0, false, false
+		}
+	}
+
+	shared := directives.Has("public") || directives.Has("s-maxage")
+
+	for _, name := range []string{"s-maxage", "max-age"} {
+		if directives.Has(name) {
+			maxAge, ok := directives.Duration(name)
+			if !ok || maxAge == 0 {
+				return 0, false, false
+			}
+
+			return maxAge, shared, true
+		}
+	}
+
+	return cfg.TTL, shared, true
+}
+
+// matches reports whether the request has the same values of the headers the response varies by.
+func (r *CachedResponse) matches(req *http.Request) bool {
+	for name, value := range r.Vary {
+		if req.Header.Get(name) != value {
+			return false
+		}
+	}
+
+	return true
+}
+
+// varyValues returns the values of the request headers listed in the Vary response header.
+func varyValues(r *http.Request, header http.Header) map[string]string {
+	values := make(map[string]string)
+	for _, value := range header.Values("Vary") {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				values[name] = r.Header.Get(name)
+			}
+		}
+	}
+
+	return values
+}
+
+// serveCached writes the cached response.
+func serveCached(ctx wayes.Ctx, cached *CachedResponse) error {
+	header := ctx.Response().Header()
+	for key, values := range cached.Header {
+		header[key] = slices.Clone(values)
+	}
+
+	header.Set("X-Cache", "HIT")
+	header.Set("Age", strconv.Itoa(int(time.Since(cached.Created).Seconds())))
+	ctx.Response().WriteHeader(cached.Status)
+
+	if ctx.Request().Method == http.MethodHead {
+		return nil
+	}
+
+	_, err := ctx.Response().Write(cached.Body)
+
+	return err
+}
+
+// cacheEntry represents a structure for an entry of the in-memory cache store.
+type cacheEntry struct {
+	key      string
+	response *CachedResponse
+	expires  time.Time
+	size     int64
+}
+
+// memoryCacheStore represents a structure that implements the [CacheStore] interface
+// with an in-memory least recently used eviction.
+type memoryCacheStore struct {
+	mu      sync.Mutex
+	maxSize int64
+	size    int64
+	entries map[string]*list.Element
+	lru     *list.List
+	now     func() time.Time
+}
+
+// NewMemoryCacheStore creates a new in-memory [CacheStore] limited to the maximum size in bytes.
+// The least recently used responses are evicted when the limit is exceeded.
+func NewMemoryCacheStore(maxSize int64) CacheStore {
+	return &memoryCacheStore{
+		maxSize: maxSize,
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+		now:     time.Now,
+	}
+}
+
+// Get returns the response stored by the key if it is not expired.
+func (s *memoryCacheStore) Get(key string) (*CachedResponse, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	element, ok := s.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry := element.Value.(*cacheEntry)
+	if !s.now().Before(entry.expires) {
+		s.remove(element)
+		return nil, false
+	}
+
+	s.lru.MoveToFront(element)
+
+	return entry.response, true
+}
+
+// Set stores the response by the key and evicts the least recently used responses if needed.
+// Responses larger than the maximum size are not stored.
+func (s *memoryCacheStore) Set(key string, response *CachedResponse, ttl time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if element, ok := s.entries[key]; ok {
+		s.remove(element)
+	}
+
+	entry := &cacheEntry{
+		key:      key,
+		response: response,
+		expires:  s.now().Add(ttl),
+		size:     response.size() + int64(len(key)),
+	}
+
+	if entry.size > s.maxSize {
+		return
+	}
+
+	s.entries[key] = s.lru.PushFront(entry)
+	s.size += entry.size
+
+	for s.size > s.maxSize {
+		s.remove(s.lru.Back())
+	}
+}
+
+// Delete removes the response stored by the key.
+func (s *memoryCacheStore) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if element, ok := s.entries[key]; ok {
+		s.remove(element)
+	}
+}
+
+// remove removes the element from the store, the caller must hold the lock.
+func (s *memoryCacheStore) remove(element *list.Element) {
+	entry := element.Value.(*cacheEntry)
+
+	s.lru.Remove(element)
+	delete(s.entries, entry.key)
+	s.size -= entry.size
+}
@@ -0,0 +1,346 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/eliofery/wayes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCache tests the caching of responses by the middleware.
+func TestCache(t *testing.T) {
+	cases := []struct {
+		name          string
+		method        string
+		path          string
+		headers       map[string]string
+		exceptedCode  int
+		exceptedCache string
+		exceptedBody  string
+	}{
+		{
+			name:          "First request",
+			method:        http.MethodGet,
+			path:          "/counter?page=1&utm=a",
+			exceptedCode:  http.StatusOK,
+			exceptedCache: "MISS",
+			exceptedBody:  "1",
+		},
+		{
+			name:          "Cached response",
+			method:        http.MethodGet,
+			path:          "/counter?page=1&utm=b",
+			exceptedCode:  http.StatusOK,
+			exceptedCache: "HIT",
+			exceptedBody:  "1",
+		},
+		{
+			name:          "Other query",
+			method:        http.MethodGet,
+			path:          "/counter?page=2",
+			exceptedCode:  http.StatusOK,
+			exceptedCache: "MISS",
+			exceptedBody:  "2",
+		},
+		{
+			name:          "Other header",
+			method:        http.MethodGet,
+			path:          "/counter?page=1",
+			headers:       map[string]string{"Accept-Language": "ru"},
+			exceptedCode:  http.StatusOK,
+			exceptedCache: "MISS",
+			exceptedBody:  "3",
+		},
+		{
+			name:          "Request no-cache",
+			method:        http.MethodGet,
+			path:          "/counter?page=1",
+			headers:       map[string]string{"Cache-Control": "no-cache"},
+			exceptedCode:  http.StatusOK,
+			exceptedCache: "MISS",
+			exceptedBody:  "4",
+		},
+		{
+			name:          "Revalidated response",
+			method:        http.MethodGet,
+			path:          "/counter?page=1",
+			exceptedCode:  http.StatusOK,
+			exceptedCache: "HIT",
+			exceptedBody:  "4",
+		},
+		{
+			name:         "Request no-store",
+			method:       http.MethodGet,
+			path:         "/counter?page=1",
+			headers:      map[string]string{"Cache-Control": "no-store"},
+			exceptedCode: http.StatusOK,
+			exceptedBody: "5",
+		},
+		{
+			name:          "Private response",
+			method:        http.MethodGet,
+			path:          "/private",
+			exceptedCode:  http.StatusOK,
+			exceptedCache: "MISS",
+			exceptedBody:  "1",
+		},
+		{
+			name:          "Private response not cached",
+			method:        http.MethodGet,
+			path:          "/private",
+			exceptedCode:  http.StatusOK,
+			exceptedCache: "MISS",
+			exceptedBody:  "2",
+		},
+		{
+			name:          "Uncacheable status",
+			method:        http.MethodGet,
+			path:          "/error",
+			exceptedCode:  http.StatusInternalServerError,
+			exceptedCache: "MISS",
+			exceptedBody:  "Internal Server Error",
+		},
+		{
+			name:         "Unsafe method",
+			method:       http.MethodPost,
+			path:         "/counter",
+			exceptedCode: http.StatusOK,
+			exceptedBody: "ok",
+		},
+	}
+
+	counters := make(map[string]int)
+	counter := func(ctx wayes.Ctx) error {
+		counters[ctx.Pattern()]++
+		return ctx.Write(fmt.Sprint(counters[ctx.Pattern()]))
+	}
+
+	rt := wayes.New()
+	rt.Use(Cache(CacheConfig{
+		Query:   []string{"page"},
+		Headers: []string{"Accept-Language"},
+	}))
+	rt.Get("/counter", counter)
+	rt.Post("/counter", func(ctx wayes.Ctx) error {
+		return ctx.Write("ok")
+	})
+	rt.Get("/private", func(ctx wayes.Ctx) error {
+		ctx.Set("Cache-Control", "private, max-age=60")
+		return counter(ctx)
+	})
+	rt.Get("/error", func(ctx wayes.Ctx) error {
+		return ctx.SendStatus(http.StatusInternalServerError)
+	})
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			req, err := http.NewRequest(test.method, test.path, nil)
+			require.NoError(t, err)
+			for key, value := range test.headers {
+				req.Header.Set(key, value)
+			}
+
+			rr := httptest.NewRecorder()
+			rt.Mux().ServeHTTP(rr, req)
+
+			assert.Equal(t, test.exceptedCode, rr.Code)
+			assert.Equal(t, test.exceptedCache, rr.Header().Get("X-Cache"))
+			assert.Equal(t, test.exceptedBody, rr.Body.String())
+		})
+	}
+}
+
+// TestCache_headers tests that the cached response restores the headers set by the handler.
+func TestCache_headers(t *testing.T) {
+	rt := wayes.New()
+	rt.Use(Cache())
+	rt.Get("/test", func(ctx wayes.Ctx) error {
+		ctx.Set("X-Custom", "value")
+		return ctx.Status(http.StatusNonAuthoritativeInfo).Write("Test cache response")
+	})
+
+	for _, exceptedCache := range []string{"MISS", "HIT"} {
+		rr := httptest.NewRecorder()
+		rt.Mux().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/test", nil))
+
+		assert.Equal(t, http.StatusNonAuthoritativeInfo, rr.Code)
+		assert.Equal(t, exceptedCache, rr.Header().Get("X-Cache"))
+		assert.Equal(t, "value", rr.Header().Get("X-Custom"))
+		assert.Equal(t, "Test cache response", rr.Body.String())
+	}
+}
+
+// TestCache_credentials tests that responses to requests with credentials are cached only when marked shared.
+func TestCache_credentials(t *testing.T) {
+	cases := []struct {
+		name          string
+		path          string
+		headers       map[string]string
+		exceptedCache string
+		exceptedBody  string
+	}{
+		{
+			name:          "Anonymous request",
+			path:          "/private",
+			exceptedCache: "MISS",
+			exceptedBody:  "1",
+		},
+		{
+			name:          "Authorization not served from cache",
+			path:          "/private",
+			headers:       map[string]string{"Authorization": "Bearer token"},
+			exceptedCache: "MISS",
+			exceptedBody:  "2",
+		},
+		{
+			name:          "Cookie not served from cache",
+			path:          "/private",
+			headers:       map[string]string{"Cookie": "session=id"},
+			exceptedCache: "MISS",
+			exceptedBody:  "3",
+		},
+		{
+			name:          "Authorization not stored",
+			path:          "/auth",
+			headers:       map[string]string{"Authorization": "Bearer token"},
+			exceptedCache: "MISS",
+			exceptedBody:  "1",
+		},
+		{
+			name:          "Anonymous request after authorization",
+			path:          "/auth",
+			exceptedCache: "MISS",
+			exceptedBody:  "2",
+		},
+		{
+			name:          "Public response stored",
+			path:          "/public",
+			headers:       map[string]string{"Authorization": "Bearer token"},
+			exceptedCache: "MISS",
+			exceptedBody:  "1",
+		},
+		{
+			name:          "Public response served",
+			path:          "/public",
+			headers:       map[string]string{"Cookie": "session=id"},
+			exceptedCache: "HIT",
+			exceptedBody:  "1",
+		},
+	}
+
+	counters := make(map[string]int)
+	counter := func(ctx wayes.Ctx) error {
+		counters[ctx.Pattern()]++
+		return ctx.Write(fmt.Sprint(counters[ctx.Pattern()]))
+	}
+
+	rt := wayes.New()
+	rt.Use(Cache())
+	rt.Get("/private", counter)
+	rt.Get("/auth", counter)
+	rt.Get("/public", func(ctx wayes.Ctx) error {
+		ctx.Set("Cache-Control", "public, max-age=60")
+		return counter(ctx)
+	})
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, test.path, nil)
+			for key, value := range test.headers {
+				req.Header.Set(key, value)
+			}
+
+			rr := httptest.NewRecorder()
+			rt.Mux().ServeHTTP(rr, req)
+
+			assert.Equal(t, test.exceptedCache, rr.Header().Get("X-Cache"))
+			assert.Equal(t, test.exceptedBody, rr.Body.String())
+		})
+	}
+
+	rt = wayes.New()
+	rt.Use(Cache(CacheConfig{IgnoreCookie: true}))
+	rt.Get("/test", func(ctx wayes.Ctx) error {
+		return ctx.Write("Test cache response")
+	})
+
+	for _, exceptedCache := range []string{"MISS", "HIT"} {
+		req := httptest.NewRequest(http.MethodGet, "/test", nil)
+		req.Header.Set("Cookie", "theme=dark")
+
+		rr := httptest.NewRecorder()
+		rt.Mux().ServeHTTP(rr, req)
+
+		assert.Equal(t, exceptedCache, rr.Header().Get("X-Cache"))
+	}
+}
+
+// TestCache_groups tests that the routes of different groups with the same path do not share cached responses.
+func TestCache_groups(t *testing.T) {
+	rt := wayes.New()
+	rt.Use(Cache())
+	rt.Group("/admin").Get("/users", func(ctx wayes.Ctx) error {
+		return ctx.Write("admin users")
+	})
+	rt.Group("/api").Get("/users", func(ctx wayes.Ctx) error {
+		return ctx.Write("api users")
+	})
+
+	cases := []struct {
+		path          string
+		exceptedCache string
+		exceptedBody  string
+	}{
+		{path: "/admin/users", exceptedCache: "MISS", exceptedBody: "admin users"},
+		{path: "/api/users", exceptedCache: "MISS", exceptedBody: "api users"},
+		{path: "/admin/users", exceptedCache: "HIT", exceptedBody: "admin users"},
+		{path: "/api/users", exceptedCache: "HIT", exceptedBody: "api users"},
+	}
+
+	for _, test := range cases {
+		rr := httptest.NewRecorder()
+		rt.Mux().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, test.path, nil))
+
+		assert.Equal(t, test.exceptedCache, rr.Header().Get("X-Cache"), test.path)
+		assert.Equal(t, test.exceptedBody, rr.Body.String(), test.path)
+	}
+}
+
+// TestMemoryCacheStore tests the expiration and the least recently used eviction of the store.
+func TestMemoryCacheStore(t *testing.T) {
+	now := time.Now()
+	store := NewMemoryCacheStore(20).(*memoryCacheStore)
+	store.now = func() time.Time { return now }
+
+	store.Set("a", &CachedResponse{Body: []byte("1234567")}, time.Minute)
+	store.Set("b", &CachedResponse{Body: []byte("1234567")}, time.Minute)
+
+	_, ok := store.Get("a")
+	assert.True(t, ok)
+
+	store.Set("c", &CachedResponse{Body: []byte("1234567")}, time.Minute)
+
+	_, ok = store.Get("b")
+	assert.False(t, ok, "least recently used entry must be evicted")
+
+	_, ok = store.Get("a")
+	assert.True(t, ok)
+
+	store.Set("large", &CachedResponse{Body: make([]byte, 100)}, time.Minute)
+	_, ok = store.Get("large")
+	assert.False(t, ok, "entry larger than the store must not be stored")
+
+	now = now.Add(time.Minute)
+	_, ok = store.Get("a")
+	assert.False(t, ok, "expired entry must not be returned")
+
+	store.Delete("c")
+	_, ok = store.Get("c")
+	assert.False(t, ok)
+	assert.Zero(t, store.size)
+}
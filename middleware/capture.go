@@ -0,0 +1,110 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"slices"
+)
+
+// captureWriter represents a structure that captures the response of the next handlers,
+// so that a middleware can inspect or store it before it is sent.
+// A flushed response switches the writer to streaming and is written through.
+type captureWriter struct {
+	http.ResponseWriter
+
+	header    http.Header
+	initial   http.Header
+	buf       bytes.Buffer
+	code      int
+	streaming bool
+}
+
+// newCaptureWriter creates a new instance of [captureWriter] with a copy of the current response header.
+func newCaptureWriter(w http.ResponseWriter) *captureWriter {
+	return &captureWriter{
+		ResponseWriter: w,
+		header:         w.Header().Clone(),
+		initial:        w.Header().Clone(),
+	}
+}
+
+// Header returns the captured header map.
+func (cw *captureWriter) Header() http.Header {
+	return cw.header
+}
+
+// WriteHeader records the status code of the response.
+func (cw *captureWriter) WriteHeader(code int) {
+	if cw.streaming {
+		cw.ResponseWriter.WriteHeader(code)
+		return
+	}
+
+	if cw.code == 0 {
+		cw.code = code
+	}
+}
+
+// Write buffers the data until the response is flushed.
+func (cw *captureWriter) Write(p []byte) (int, error) {
+	if cw.code == 0 {
+		cw.code = http.StatusOK
+	}
+
+	if cw.streaming {
+		return cw.ResponseWriter.Write(p)
+	}
+
+	return cw.buf.Write(p)
+}
+
+// Flush sends the captured response and switches the writer to streaming.
+func (cw *captureWriter) Flush() {
+	cw.flush()
+	cw.streaming = true
+
+	_ = http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+// Unwrap returns the underlying writer for [http.ResponseController].
+func (cw *captureWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// copyHeader copies the captured header map to the underlying writer.
+func (cw *captureWriter) copyHeader() {
+	dst := cw.ResponseWriter.Header()
+	for key, values := range cw.header {
+		dst[key] = values
+	}
+}
+
+// modifiedHeader returns the header fields set or changed by the next handlers.
+func (cw *captureWriter) modifiedHeader() http.Header {
+	header := make(http.Header, len(cw.header))
+	for key, values := range cw.header {
+		if !slices.Equal(cw.initial[key], values) {
+			header[key] = slices.Clone(values)
+		}
+	}
+
+	return header
+}
+
+// flush writes the header, the status code and the captured body to the underlying writer.
+func (cw *captureWriter) flush() {
+	if cw.streaming {
+		return
+	}
+
+	cw.copyHeader()
+	if cw.code == 0 {
+		return
+	}
+
+	cw.ResponseWriter.WriteHeader(cw.code)
+	if cw.buf.Len() > 0 {
+		_, _ = cw.ResponseWriter.Write(cw.buf.Bytes())
+		cw.buf.Reset()
+	}
+}
@@ -0,0 +1,375 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/eliofery/wayes"
+)
+
+// Compressor is an interface that defines methods of a reusable compressing writer.
+// It is implemented by [gzip.Writer], [flate.Writer] and the writers of popular brotli and zstd packages.
+type Compressor interface {
+	io.WriteCloser
+
+	// Flush writes any pending data to the underlying writer.
+	Flush() error
+
+	// Reset discards the state of the compressor and makes it write to w.
+	Reset(w io.Writer)
+}
+
+// Encoder defines a function signature for creating compressors of a content coding.
+type Encoder func(w io.Writer, level int) (Compressor, error)
+
+var (
+	// encodersMu guards the encoders registry.
+	encodersMu sync.RWMutex
+
+	// encoders holds the registered encoders by the name of the content coding.
+	encoders = map[string]Encoder{
+		"gzip": func(w io.Writer, level int) (Compressor, error) {
+			return gzip.NewWriterLevel(w, level)
+		},
+		"deflate": func(w io.Writer, level int) (Compressor, error) {
+			return flate.NewWriter(w, level)
+		},
+	}
+)
+
+// RegisterEncoder registers an encoder for the content coding, for example "br" or "zstd".
+// Registered encoders are used by the [Compress] middlewares created afterwards.
+func RegisterEncoder(name string, encoder Encoder) {
+	encodersMu.Lock()
+	defer encodersMu.Unlock()
+
+	encoders[strings.ToLower(name)] = encoder
+}
+
+// defaultCompressTypes is the list of compressible media types used when the configuration does not define them.
+var defaultCompressTypes = []string{
+	"text/",
+	"application/json",
+	"application/javascript",
+	"application/xml",
+	"application/x-ndjson",
+	"application/wasm",
+	"image/svg+xml",
+}
+
+// CompressConfig represents a structure for the [Compress] middleware configuration.
+type CompressConfig struct {
+	// Level is the compression level passed to the encoders. Defaults to -1, the default level of the encoder.
+	// Zero is treated as the default level, use a level of 1 for the fastest compression.
+	Level int
+
+	// MinLength is the minimum response size in bytes to be compressed. Defaults to 1024.
+	MinLength int
+
+	// ContentTypes is a list of media types or their prefixes ending with "/" that are compressed.
+	// Defaults to text, JSON, JavaScript, XML, WebAssembly and SVG.
+	ContentTypes []string
+
+	// Encodings is a list of content codings in order of preference.
+	// Defaults to "br", "zstd", "gzip" and "deflate", codings without a registered encoder are skipped.
+	Encodings []string
+}
+
+// Compress creates a middleware that compresses responses with the content coding negotiated by Accept-Encoding.
+// Responses smaller than the minimum length, of other content types, already encoded, partial or streamed
+// before reaching the minimum length are sent uncompressed.
+func Compress(config ...CompressConfig) wayes.Handler {
+	cfg := CompressConfig{}
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+
+	if cfg.Level == 0 {
+		cfg.Level = -1
+	}
+
+	if cfg.MinLength <= 0 {
+		cfg.MinLength = 1024
+	}
+
+	if len(cfg.ContentTypes) == 0 {
+		cfg.ContentTypes = defaultCompressTypes
+	}
+
+	if len(cfg.Encodings) == 0 {
+		cfg.Encodings = []string{"br", "zstd", "gzip", "deflate"}
+	}
+
+	encodersMu.RLock()
+	pools := make(map[string]*sync.Pool, len(cfg.Encodings))
+	supported := make([]string, 0, len(cfg.Encodings))
+	for _, name := range cfg.Encodings {
+		name = strings.ToLower(name)
+
+		encoder, ok := encoders[name]
+		if !ok {
+			continue
+		}
+
+		if _, err := encoder(io.Discard, cfg.Level); err != nil {
+			panic(fmt.Sprintf("wayes: invalid %s compression level: %v", name, err))
+		}
+
+		supported = append(supported, name)
+		pools[name] = &sync.Pool{New: func() any {
+			compressor, _ := encoder(io.Discard, cfg.Level)
+			return compressor
+		}}
+	}
+	encodersMu.RUnlock()
+
+	return func(ctx wayes.Ctx) error {
+		ctx.Vary("Accept-Encoding")
+
+		encoding := negotiateEncoding(ctx.Request().Header.Get("Accept-Encoding"), supported)
+		if encoding == "" || ctx.Request().Method == http.MethodHead {
+			return ctx.Next()
+		}
+
+		original := ctx.Response()
+		cw := &compressWriter{
+			ResponseWriter: original,
+			config:         &cfg,
+			encoding:       encoding,
+			pool:           pools[encoding],
+		}
+
+		ctx.SetResponse(cw)
+		defer ctx.SetResponse(original)
+
+		err := ctx.Next()
+		if closeErr := cw.Close(); err == nil {
+			err = closeErr
+		}
+
+		return err
+	}
+}
+
+// negotiateEncoding returns the supported content coding with the highest quality in the Accept-Encoding header.
+// Codings of the same quality are chosen in order of the server preference.
+func negotiateEncoding(header string, supported []string) string {
+	if header == "" {
+		return ""
+	}
+
+	qualities := make(map[string]float64)
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				q = parsed
+			}
+		}
+
+		qualities[name] = q
+	}
+
+	best, bestQ := "", 0.0
+	for _, name := range supported {
+		q, ok := qualities[name]
+		if !ok {
+			q, ok = qualities["*"]
+		}
+
+		if ok && q > bestQ {
+			best, bestQ = name, q
+		}
+	}
+
+	return best
+}
+
+// compressWriter represents a structure that compresses the response once it is known to be eligible.
+type compressWriter struct {
+	http.ResponseWriter
+
+	config   *CompressConfig
+	encoding string
+	pool     *sync.Pool
+
+	buf        bytes.Buffer
+	code       int
+	decided    bool
+	compressor Compressor
+}
+
+// WriteHeader records the status code, the header is written once the compression is decided.
+func (cw *compressWriter) WriteHeader(code int) {
+	if cw.decided {
+		cw.ResponseWriter.WriteHeader(code)
+		return
+	}
+
+	if cw.code == 0 {
+		cw.code = code
+	}
+}
+
+// Write buffers the data until the minimum length is reached and then writes it compressed or as is.
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if cw.code == 0 {
+		cw.code = http.StatusOK
+	}
+
+	if !cw.decided {
+		cw.buf.Write(p)
+		if cw.buf.Len() < cw.config.MinLength && cw.Header().Get("Content-Length") == "" {
+			return len(p), nil
+		}
+
+		if err := cw.decide(); err != nil {
+			return 0, err
+		}
+
+		return len(p), nil
+	}
+
+	if cw.compressor != nil {
+		return cw.compressor.Write(p)
+	}
+
+	return cw.ResponseWriter.Write(p)
+}
+
+// Flush sends the buffered data, a response flushed before reaching the minimum length is not compressed.
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		if cw.code == 0 {
+			cw.code = http.StatusOK
+		}
+
+		if err := cw.decide(); err != nil {
+			return
+		}
+	}
+
+	if cw.compressor != nil {
+		_ = cw.compressor.Flush()
+	}
+
+	_ = http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+// Unwrap returns the underlying writer for [http.ResponseController].
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// Close decides the compression for short responses and releases the compressor.
+func (cw *compressWriter) Close() error {
+	if !cw.decided {
+		if cw.code == 0 {
+			return nil
+		}
+
+		if err := cw.decide(); err != nil {
+			return err
+		}
+	}
+
+	if cw.compressor == nil {
+		return nil
+	}
+
+	err := cw.compressor.Close()
+	cw.compressor.Reset(io.Discard)
+	cw.pool.Put(cw.compressor)
+	cw.compressor = nil
+
+	return err
+}
+
+// decide writes the header and the buffered data, compressing them if the response is eligible.
+func (cw *compressWriter) decide() error {
+	cw.decided = true
+
+	if cw.eligible() {
+		cw.compressor = cw.pool.Get().(Compressor)
+		cw.compressor.Reset(cw.ResponseWriter)
+
+		header := cw.Header()
+		header.Set("Content-Encoding", cw.encoding)
+		header.Del("Content-Length")
+	}
+
+	cw.ResponseWriter.WriteHeader(cw.code)
+
+	if cw.buf.Len() == 0 {
+		return nil
+	}
+
+	var err error
+	if cw.compressor != nil {
+		_, err = cw.compressor.Write(cw.buf.Bytes())
+	} else {
+		_, err = cw.ResponseWriter.Write(cw.buf.Bytes())
+	}
+	cw.buf.Reset()
+
+	return err
+}
+
+// eligible reports whether the response can be compressed.
+func (cw *compressWriter) eligible() bool {
+	header := cw.Header()
+
+	if cw.code < http.StatusOK || cw.code == http.StatusNoContent ||
+		cw.code == http.StatusNotModified || cw.code == http.StatusPartialContent {
+		return false
+	}
+
+	if header.Get("Content-Encoding") != "" || header.Get("Content-Range") != "" {
+		return false
+	}
+
+	if strings.Contains(header.Get("Cache-Control"), "no-transform") {
+		return false
+	}
+
+	length := cw.buf.Len()
+	if value := header.Get("Content-Length"); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil {
+			length = parsed
+		}
+	}
+
+	if length < cw.config.MinLength {
+		return false
+	}
+
+	contentType := header.Get("Content-Type")
+	if contentType == "" {
+		contentType = http.DetectContentType(cw.buf.Bytes())
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType == "text/event-stream" {
+		return false
+	}
+
+	return slices.ContainsFunc(cw.config.ContentTypes, func(allowed string) bool {
+		if strings.HasSuffix(allowed, "/") {
+			return strings.HasPrefix(mediaType, allowed)
+		}
+
+		return mediaType == allowed
+	})
+}
@@ -0,0 +1,172 @@
+package middleware
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/eliofery/wayes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCompress tests the compression of responses.
+func TestCompress(t *testing.T) {
+	large := strings.Repeat("Test compress response. ", 100)
+
+	cases := []struct {
+		name             string
+		acceptEncoding   string
+		handler          wayes.Handler
+		exceptedEncoding string
+		exceptedBody     string
+	}{
+		{
+			name:           "Gzip",
+			acceptEncoding: "gzip, deflate",
+			handler: func(ctx wayes.Ctx) error {
+				return ctx.Write(large)
+			},
+			exceptedEncoding: "gzip",
+			exceptedBody:     large,
+		},
+		{
+			name:           "Deflate by quality",
+			acceptEncoding: "gzip;q=0.5, deflate",
+			handler: func(ctx wayes.Ctx) error {
+				return ctx.Write(large)
+			},
+			exceptedEncoding: "deflate",
+			exceptedBody:     large,
+		},
+		{
+			name:           "Unsupported encoding",
+			acceptEncoding: "br",
+			handler: func(ctx wayes.Ctx) error {
+				return ctx.Write(large)
+			},
+			exceptedBody: large,
+		},
+		{
+			name:           "Excluded encoding",
+			acceptEncoding: "gzip;q=0",
+			handler: func(ctx wayes.Ctx) error {
+				return ctx.Write(large)
+			},
+			exceptedBody: large,
+		},
+		{
+			name:           "Small response",
+			acceptEncoding: "gzip",
+			handler: func(ctx wayes.Ctx) error {
+				return ctx.Write("small")
+			},
+			exceptedBody: "small",
+		},
+		{
+			name:           "Not allowed content type",
+			acceptEncoding: "gzip",
+			handler: func(ctx wayes.Ctx) error {
+				ctx.ContentType("image/png")
+				ctx.Response().WriteHeader(http.StatusOK)
+				_, err := ctx.Response().Write([]byte(large))
+				return err
+			},
+			exceptedBody: large,
+		},
+		{
+			name:           "Already encoded",
+			acceptEncoding: "gzip",
+			handler: func(ctx wayes.Ctx) error {
+				ctx.Set("Content-Encoding", "identity")
+				return ctx.Write(large)
+			},
+			exceptedEncoding: "identity",
+			exceptedBody:     large,
+		},
+		{
+			name:           "Streaming response",
+			acceptEncoding: "gzip",
+			handler: func(ctx wayes.Ctx) error {
+				ctx.ContentType("text/plain")
+				if _, err := ctx.Response().Write([]byte("first")); err != nil {
+					return err
+				}
+
+				if err := http.NewResponseController(ctx.Response()).Flush(); err != nil {
+					return err
+				}
+
+				_, err := ctx.Response().Write([]byte(large))
+				return err
+			},
+			exceptedBody: "first" + large,
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			rt := wayes.New()
+			rt.Use(Compress())
+			rt.Get("/test", test.handler)
+
+			req, err := http.NewRequest(http.MethodGet, "/test", nil)
+			require.NoError(t, err)
+			req.Header.Set("Accept-Encoding", test.acceptEncoding)
+
+			rr := httptest.NewRecorder()
+			rt.Mux().ServeHTTP(rr, req)
+
+			assert.Equal(t, http.StatusOK, rr.Code)
+			assert.Equal(t, test.exceptedEncoding, rr.Header().Get("Content-Encoding"))
+			assert.Equal(t, "Accept-Encoding", rr.Header().Get("Vary"))
+
+			var body io.Reader = rr.Body
+			switch test.exceptedEncoding {
+			case "gzip":
+				body, err = gzip.NewReader(rr.Body)
+				require.NoError(t, err)
+			case "deflate":
+				body = flate.NewReader(rr.Body)
+			}
+
+			data, err := io.ReadAll(body)
+			require.NoError(t, err)
+			assert.Equal(t, test.exceptedBody, string(data))
+		})
+	}
+}
+
+// TestRegisterEncoder tests the usage of a registered encoder.
+func TestRegisterEncoder(t *testing.T) {
+	RegisterEncoder("x-test", func(w io.Writer, level int) (Compressor, error) {
+		return gzip.NewWriterLevel(w, level)
+	})
+
+	rt := wayes.New()
+	rt.Use(Compress(CompressConfig{Encodings: []string{"x-test"}, MinLength: 1}))
+	rt.Get("/test", func(ctx wayes.Ctx) error {
+		return ctx.JSON(wayes.Map{"foo": "bar"})
+	})
+
+	req, err := http.NewRequest(http.MethodGet, "/test", nil)
+	require.NoError(t, err)
+	req.Header.Set("Accept-Encoding", "x-test")
+
+	rr := httptest.NewRecorder()
+	rt.Mux().ServeHTTP(rr, req)
+
+	assert.Equal(t, "x-test", rr.Header().Get("Content-Encoding"))
+	assert.Empty(t, rr.Header().Get("Content-Length"))
+
+	reader, err := gzip.NewReader(rr.Body)
+	require.NoError(t, err)
+
+	data, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"foo":"bar"}`, string(data))
+}
@@ -0,0 +1,175 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eliofery/wayes"
+)
+
+// defaultCORSMethods is the list of methods allowed when neither the configuration nor the route defines them.
+var defaultCORSMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+}
+
+// CORSConfig represents a structure for the [CORS] middleware configuration.
+type CORSConfig struct {
+	// AllowOrigins is a list of origins a cross-domain request can be executed from.
+	// An origin may contain a wildcard subdomain, for example "https://*.example.com".
+	// The value "*" allows all origins. Defaults to "*" when AllowOriginFunc is not set.
+	AllowOrigins []string
+
+	// AllowOriginFunc is a custom predicate that validates the origin.
+	// It is consulted when the origin does not match AllowOrigins.
+	AllowOriginFunc func(origin string) bool
+
+	// AllowMethods is a list of methods the client is allowed to use.
+	// Defaults to the methods registered for the route.
+	AllowMethods []string
+
+	// AllowHeaders is a list of headers the client is allowed to use.
+	// Defaults to the headers requested in the preflight request.
+	AllowHeaders []string
+
+	// ExposeHeaders is a list of headers that are safe to expose to the client.
+	ExposeHeaders []string
+
+	// AllowCredentials indicates whether the request can include user credentials.
+	// It requires an explicit list of origins or AllowOriginFunc, the wildcard origin "*" is not allowed.
+	AllowCredentials bool
+
+	// MaxAge indicates how long the results of a preflight request can be cached.
+	MaxAge time.Duration
+}
+
+// CORS creates a middleware that implements Cross-Origin Resource Sharing.
+// Preflight requests are answered with 204 No Content without calling the next handlers.
+// It panics if credentials are allowed for all origins, which would expose credentialed responses to any site.
+func CORS(config ...CORSConfig) wayes.Handler {
+	cfg := CORSConfig{}
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+
+	if len(cfg.AllowOrigins) == 0 && cfg.AllowOriginFunc == nil {
+		cfg.AllowOrigins = []string{"*"}
+	}
+
+	allowAll := false
+	origins := make([]string, 0, len(cfg.AllowOrigins))
+	for _, origin := range cfg.AllowOrigins {
+		if origin == "*" {
+			allowAll = true
+			continue
+		}
+
+		origins = append(origins, strings.ToLower(strings.TrimSuffix(origin, "/")))
+	}
+
+	if allowAll && cfg.AllowCredentials {
+		panic("wayes: CORS with credentials requires an explicit list of origins or AllowOriginFunc")
+	}
+
+	allowMethods := strings.Join(cfg.AllowMethods, ", ")
+	allowHeaders := strings.Join(cfg.AllowHeaders, ", ")
+	exposeHeaders := strings.Join(cfg.ExposeHeaders, ", ")
+	maxAge := strconv.Itoa(int(cfg.MaxAge.Seconds()))
+
+	allowOrigin := func(origin string) bool {
+		if allowAll {
+			return true
+		}
+
+		lower := strings.ToLower(origin)
+		for _, allowed := range origins {
+			if matchOrigin(allowed, lower) {
+				return true
+			}
+		}
+
+		return cfg.AllowOriginFunc != nil && cfg.AllowOriginFunc(origin)
+	}
+
+	return func(ctx wayes.Ctx) error {
+		r := ctx.Request()
+		origin := r.Header.Get("Origin")
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+		ctx.Vary("Origin")
+		if preflight {
+			ctx.Vary("Access-Control-Request-Method", "Access-Control-Request-Headers")
+		}
+
+		if origin == "" {
+			return ctx.Next()
+		}
+
+		if !allowOrigin(origin) {
+			if preflight {
+				return ctx.SendStatus(http.StatusNoContent)
+			}
+
+			return ctx.Next()
+		}
+
+		if allowAll {
+			ctx.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			ctx.Set("Access-Control-Allow-Origin", origin)
+		}
+
+		if cfg.AllowCredentials {
+			ctx.Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if !preflight {
+			if exposeHeaders != "" {
+				ctx.Set("Access-Control-Expose-Headers", exposeHeaders)
+			}
+
+			return ctx.Next()
+		}
+
+		methods := allowMethods
+		if methods == "" {
+			// The router sets the Allow header for the automatic OPTIONS handling.
+			methods = ctx.Get("Allow", strings.Join(defaultCORSMethods, ", "))
+		}
+		ctx.Set("Access-Control-Allow-Methods", methods)
+
+		headers := allowHeaders
+		if headers == "" {
+			headers = r.Header.Get("Access-Control-Request-Headers")
+		}
+		if headers != "" {
+			ctx.Set("Access-Control-Allow-Headers", headers)
+		}
+
+		if cfg.MaxAge > 0 {
+			ctx.Set("Access-Control-Max-Age", maxAge)
+		}
+
+		return ctx.SendStatus(http.StatusNoContent)
+	}
+}
+
+// matchOrigin reports whether the origin matches the allowed origin,
+// which may contain a wildcard subdomain.
+func matchOrigin(allowed, origin string) bool {
+	prefix, suffix, wildcard := strings.Cut(allowed, "*")
+	if !wildcard {
+		return allowed == origin
+	}
+
+	return len(origin) > len(prefix)+len(suffix) &&
+		strings.HasPrefix(origin, prefix) &&
+		strings.HasSuffix(origin, suffix) &&
+		!strings.ContainsAny(origin[len(prefix):len(origin)-len(suffix)], "/:")
+}
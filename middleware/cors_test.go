@@ -0,0 +1,194 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eliofery/wayes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCORS tests the handling of simple and preflight cross-origin requests.
+func TestCORS(t *testing.T) {
+	cases := []struct {
+		name            string
+		config          CORSConfig
+		method          string
+		headers         map[string]string
+		exceptedCode    int
+		exceptedHeaders map[string]string
+	}{
+		{
+			name:         "Without origin",
+			method:       http.MethodGet,
+			exceptedCode: http.StatusOK,
+			exceptedHeaders: map[string]string{
+				"Access-Control-Allow-Origin": "",
+				"Vary":                        "Origin",
+			},
+		},
+		{
+			name:         "Allow all origins",
+			method:       http.MethodGet,
+			headers:      map[string]string{"Origin": "https://foo.com"},
+			exceptedCode: http.StatusOK,
+			exceptedHeaders: map[string]string{
+				"Access-Control-Allow-Origin": "*",
+			},
+		},
+		{
+			name: "Allowed origin with credentials",
+			config: CORSConfig{
+				AllowOrigins:     []string{"https://foo.com"},
+				AllowCredentials: true,
+				ExposeHeaders:    []string{"X-Total", "X-Page"},
+			},
+			method:       http.MethodGet,
+			headers:      map[string]string{"Origin": "https://foo.com"},
+			exceptedCode: http.StatusOK,
+			exceptedHeaders: map[string]string{
+				"Access-Control-Allow-Origin":      "https://foo.com",
+				"Access-Control-Allow-Credentials": "true",
+				"Access-Control-Expose-Headers":    "X-Total, X-Page",
+			},
+		},
+		{
+			name:         "Disallowed origin",
+			config:       CORSConfig{AllowOrigins: []string{"https://foo.com"}},
+			method:       http.MethodGet,
+			headers:      map[string]string{"Origin": "https://bar.com"},
+			exceptedCode: http.StatusOK,
+			exceptedHeaders: map[string]string{
+				"Access-Control-Allow-Origin": "",
+			},
+		},
+		{
+			name:         "Wildcard subdomain",
+			config:       CORSConfig{AllowOrigins: []string{"https://*.foo.com"}},
+			method:       http.MethodGet,
+			headers:      map[string]string{"Origin": "https://api.foo.com"},
+			exceptedCode: http.StatusOK,
+			exceptedHeaders: map[string]string{
+				"Access-Control-Allow-Origin": "https://api.foo.com",
+			},
+		},
+		{
+			name:         "Wildcard subdomain does not match the apex domain",
+			config:       CORSConfig{AllowOrigins: []string{"https://*.foo.com"}},
+			method:       http.MethodGet,
+			headers:      map[string]string{"Origin": "https://foo.com"},
+			exceptedCode: http.StatusOK,
+			exceptedHeaders: map[string]string{
+				"Access-Control-Allow-Origin": "",
+			},
+		},
+		{
+			name: "Origin func",
+			config: CORSConfig{AllowOriginFunc: func(origin string) bool {
+				return strings.HasSuffix(origin, ".local")
+			}},
+			method:       http.MethodGet,
+			headers:      map[string]string{"Origin": "http://app.local"},
+			exceptedCode: http.StatusOK,
+			exceptedHeaders: map[string]string{
+				"Access-Control-Allow-Origin": "http://app.local",
+			},
+		},
+		{
+			name:   "Preflight with route methods",
+			config: CORSConfig{MaxAge: time.Hour},
+			method: http.MethodOptions,
+			headers: map[string]string{
+				"Origin":                         "https://foo.com",
+				"Access-Control-Request-Method":  "POST",
+				"Access-Control-Request-Headers": "Content-Type",
+			},
+			exceptedCode: http.StatusNoContent,
+			exceptedHeaders: map[string]string{
+				"Access-Control-Allow-Origin":  "*",
+				"Access-Control-Allow-Methods": "GET, HEAD, POST, OPTIONS",
+				"Access-Control-Allow-Headers": "Content-Type",
+				"Access-Control-Max-Age":       "3600",
+			},
+		},
+		{
+			name: "Preflight with configured methods and headers",
+			config: CORSConfig{
+				AllowMethods: []string{"GET"},
+				AllowHeaders: []string{"Authorization"},
+			},
+			method: http.MethodOptions,
+			headers: map[string]string{
+				"Origin":                         "https://foo.com",
+				"Access-Control-Request-Method":  "GET",
+				"Access-Control-Request-Headers": "Content-Type",
+			},
+			exceptedCode: http.StatusNoContent,
+			exceptedHeaders: map[string]string{
+				"Access-Control-Allow-Methods": "GET",
+				"Access-Control-Allow-Headers": "Authorization",
+				"Access-Control-Max-Age":       "",
+			},
+		},
+		{
+			name:   "Preflight with disallowed origin",
+			config: CORSConfig{AllowOrigins: []string{"https://foo.com"}},
+			method: http.MethodOptions,
+			headers: map[string]string{
+				"Origin":                        "https://bar.com",
+				"Access-Control-Request-Method": "GET",
+			},
+			exceptedCode: http.StatusNoContent,
+			exceptedHeaders: map[string]string{
+				"Access-Control-Allow-Origin":  "",
+				"Access-Control-Allow-Methods": "",
+			},
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			rt := wayes.New()
+			rt.Use(CORS(test.config))
+			rt.Get("/test", func(ctx wayes.Ctx) error {
+				return ctx.Write("Test cors response")
+			})
+			rt.Post("/test", func(ctx wayes.Ctx) error {
+				return ctx.Write("Test cors response")
+			})
+
+			req, err := http.NewRequest(test.method, "/test", nil)
+			require.NoError(t, err)
+			for key, value := range test.headers {
+				req.Header.Set(key, value)
+			}
+
+			rr := httptest.NewRecorder()
+			rt.Mux().ServeHTTP(rr, req)
+
+			assert.Equal(t, test.exceptedCode, rr.Code)
+			for key, value := range test.exceptedHeaders {
+				assert.Equal(t, value, rr.Header().Get(key), key)
+			}
+		})
+	}
+}
+
+// TestCORS_panic tests that the middleware rejects credentials for all origins.
+func TestCORS_panic(t *testing.T) {
+	assert.Panics(t, func() {
+		CORS(CORSConfig{AllowCredentials: true})
+	})
+
+	assert.Panics(t, func() {
+		CORS(CORSConfig{AllowOrigins: []string{"https://foo.com", "*"}, AllowCredentials: true})
+	})
+
+	assert.NotPanics(t, func() {
+		CORS(CORSConfig{AllowOriginFunc: func(origin string) bool { return true }, AllowCredentials: true})
+	})
+}
@@ -0,0 +1,277 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/eliofery/wayes"
+	"github.com/eliofery/wayes/session"
+)
+
+var (
+	// errCSRFMissing represents an error indicating that the request does not contain a CSRF token.
+	errCSRFMissing = errors.New("missing CSRF token")
+
+	// errCSRFInvalid represents an error indicating that the CSRF token of the request is invalid.
+	errCSRFInvalid = errors.New("invalid CSRF token")
+
+	// errCSRFOrigin represents an error indicating that the origin of the request is not trusted.
+	errCSRFOrigin = errors.New("untrusted request origin")
+
+	// errCSRFSession represents an error indicating that the session middleware does not run before CSRF.
+	errCSRFSession = errors.New("wayes: CSRF with Session requires the session middleware")
+)
+
+// csrfTokenLength is the length of the CSRF token in bytes.
+const csrfTokenLength = 32
+
+// csrfSessionKey is the key under which the CSRF token is stored in the session.
+const csrfSessionKey = "csrf"
+
+// CSRFConfig represents a structure for the [CSRF] middleware configuration.
+type CSRFConfig struct {
+	// TokenLookup is a comma-separated list of "<source>:<name>" pairs the token is looked up in,
+	// where the source is one of header, query or form. Defaults to "header:X-CSRF-Token, form:_csrf".
+	TokenLookup string
+
+	// Session stores the token in the session of the [session.Middleware] instead of a cookie.
+	// The session middleware must run before CSRF.
+	Session bool
+
+	// CookieName is the name of the cookie with the token. Defaults to "csrf".
+	CookieName string
+
+	// CookiePath is the path of the cookie with the token. Defaults to "/".
+	CookiePath string
+
+	// CookieDomain is the domain of the cookie with the token.
+	CookieDomain string
+
+	// CookieSecure restricts the cookie with the token to HTTPS requests.
+	CookieSecure bool
+
+	// CookieHTTPOnly hides the cookie with the token from JavaScript.
+	// The cookie value is signed, so JavaScript clients should take the token from a response instead.
+	CookieHTTPOnly bool
+
+	// CookieSameSite is the SameSite attribute of the cookie with the token. Defaults to Lax.
+	CookieSameSite http.SameSite
+
+	// TrustedOrigins is a list of origins, other than the host of the request,
+	// that are allowed to send unsafe requests over HTTPS, for example "https://admin.example.com".
+	TrustedOrigins []string
+
+	// ContextKey is the key under which the token for templates is stored in [wayes.Ctx.Locals].
+	// Defaults to [wayes.CSRFTokenKey].
+	ContextKey any
+}
+
+// csrfSafeMethods is the list of methods that do not require a CSRF token.
+var csrfSafeMethods = []string{http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace}
+
+// CSRF creates a middleware that protects against cross-site request forgery.
+// The token is kept in a cookie signed with the cookie keys of the router (double-submit cookie)
+// or in the session and is stored in [wayes.Ctx.Locals]
+// masked with a random value for every request, so it can be rendered into forms without leaking the secret.
+// Unsafe requests must send the token in the header or in the form, HTTPS requests are additionally
+// checked by the Origin or Referer header. A [wayes.Error] with 403 Forbidden is returned on mismatch.
+func CSRF(config ...CSRFConfig) wayes.Handler {
+	cfg := CSRFConfig{}
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+
+	if cfg.TokenLookup == "" {
+		cfg.TokenLookup = "header:X-CSRF-Token, form:_csrf"
+	}
+
+	if cfg.CookieName == "" {
+		cfg.CookieName = "csrf"
+	}
+
+	if cfg.CookiePath == "" {
+		cfg.CookiePath = "/"
+	}
+
+	if cfg.CookieSameSite == 0 {
+		cfg.CookieSameSite = http.SameSiteLaxMode
+	}
+
+	if cfg.ContextKey == nil {
+		cfg.ContextKey = wayes.CSRFTokenKey
+	}
+
+	for _, part := range strings.Split(cfg.TokenLookup, ",") {
+		if part = strings.TrimSpace(part); strings.HasPrefix(part, "cookie:") {
+			panic(fmt.Sprintf("wayes: CSRF token lookup %q must not read cookies", part))
+		}
+	}
+
+	extract := newExtractor(cfg.TokenLookup, "")
+
+	return func(ctx wayes.Ctx) error {
+		secret, err := cfg.secret(ctx)
+		if err != nil {
+			return err
+		}
+
+		if !slices.Contains(csrfSafeMethods, ctx.Request().Method) {
+			if err := cfg.checkOrigin(ctx); err != nil {
+				return wayes.NewError(http.StatusForbidden, err.Error())
+			}
+
+			token := extract(ctx)
+			if token == "" {
+				return wayes.NewError(http.StatusForbidden, errCSRFMissing.Error())
+			}
+
+			if secret == nil || !validCSRFToken(secret, token) {
+				return wayes.NewError(http.StatusForbidden, errCSRFInvalid.Error())
+			}
+		}
+
+		if secret == nil {
+			secret = make([]byte, csrfTokenLength)
+			if _, err := rand.Read(secret); err != nil {
+				return err
+			}
+
+			if err := cfg.store(ctx, secret); err != nil {
+				return err
+			}
+		}
+
+		ctx.Vary("Cookie")
+
+		masked, err := maskCSRFToken(secret)
+		if err != nil {
+			return err
+		}
+		ctx.Locals(cfg.ContextKey, masked)
+
+		return ctx.Next()
+	}
+}
+
+// secret returns the stored token of the client, nil if there is none or the cookie was tampered with.
+func (cfg CSRFConfig) secret(ctx wayes.Ctx) ([]byte, error) {
+	var encoded string
+	if cfg.Session {
+		sess := session.From(ctx)
+		if sess == nil {
+			return nil, errCSRFSession
+		}
+		encoded, _ = sess.Get(csrfSessionKey).(string)
+	} else {
+		value, err := ctx.SignedCookie(cfg.CookieName)
+		if errors.Is(err, wayes.ErrCookieKeys) {
+			return nil, fmt.Errorf("wayes: CSRF cookie: %w", err)
+		}
+		encoded = value
+	}
+
+	secret, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil || len(secret) != csrfTokenLength {
+		return nil, nil
+	}
+
+	return secret, nil
+}
+
+// store saves the token of the client in the session or in the signed cookie.
+func (cfg CSRFConfig) store(ctx wayes.Ctx, secret []byte) error {
+	encoded := base64.RawURLEncoding.EncodeToString(secret)
+
+	if cfg.Session {
+		session.From(ctx).Set(csrfSessionKey, encoded)
+		return nil
+	}
+
+	err := ctx.SetSignedCookie(&http.Cookie{
+		Name:     cfg.CookieName,
+		Value:    encoded,
+		Path:     cfg.CookiePath,
+		Domain:   cfg.CookieDomain,
+		Secure:   cfg.CookieSecure,
+		HttpOnly: cfg.CookieHTTPOnly,
+		SameSite: cfg.CookieSameSite,
+	})
+	if err != nil {
+		return fmt.Errorf("wayes: CSRF cookie: %w", err)
+	}
+
+	return nil
+}
+
+// checkOrigin verifies that unsafe HTTPS requests are sent from the host of the request or a trusted origin.
+// The Origin header is checked if present, otherwise the Referer header is required.
+func (cfg CSRFConfig) checkOrigin(ctx wayes.Ctx) error {
+	if ctx.Scheme() != "https" {
+		return nil
+	}
+
+	r := ctx.Request()
+
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		origin = r.Header.Get("Referer")
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return errCSRFOrigin
+	}
+
+	if u.Scheme == "https" && strings.EqualFold(u.Hostname(), ctx.Hostname()) {
+		return nil
+	}
+
+	origin = u.Scheme + "://" + u.Host
+	for _, trusted := range cfg.TrustedOrigins {
+		if strings.EqualFold(origin, trusted) {
+			return nil
+		}
+	}
+
+	return errCSRFOrigin
+}
+
+// maskCSRFToken returns the token XORed with a random one-time pad, prefixed with the pad.
+// Masking changes the token on every response, which protects it from BREACH attacks.
+func maskCSRFToken(secret []byte) (string, error) {
+	masked := make([]byte, 2*len(secret))
+	if _, err := rand.Read(masked[:len(secret)]); err != nil {
+		return "", err
+	}
+
+	subtle.XORBytes(masked[len(secret):], masked[:len(secret)], secret)
+
+	return base64.RawURLEncoding.EncodeToString(masked), nil
+}
+
+// validCSRFToken reports whether the token sent by the client matches the secret.
+// Both masked and unmasked tokens are accepted.
+func validCSRFToken(secret []byte, token string) bool {
+	decoded, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return false
+	}
+
+	switch len(decoded) {
+	case len(secret):
+	case 2 * len(secret):
+		subtle.XORBytes(decoded[len(secret):], decoded[:len(secret)], decoded[len(secret):])
+		decoded = decoded[len(secret):]
+	default:
+		return false
+	}
+
+	return subtle.ConstantTimeCompare(decoded, secret) == 1
+}
@@ -0,0 +1,321 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/eliofery/wayes"
+	"github.com/eliofery/wayes/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCSRF tests the validation of CSRF tokens with the double-submit cookie.
+func TestCSRF(t *testing.T) {
+	rt := wayes.New()
+	rt.SetCookieKeys([]byte("secret"))
+	rt.Use(CSRF(CSRFConfig{TrustedOrigins: []string{"https://admin.example.com"}}))
+	rt.Get("/form", func(ctx wayes.Ctx) error {
+		return ctx.Write(ctx.Locals(wayes.CSRFTokenKey).(string))
+	})
+	rt.Post("/form", func(ctx wayes.Ctx) error {
+		return ctx.Write("ok")
+	})
+
+	rr := httptest.NewRecorder()
+	rt.Mux().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/form", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	cookies := rr.Result().Cookies()
+	require.Len(t, cookies, 1)
+	cookie := cookies[0]
+	token := rr.Body.String()
+
+	value, _, _ := strings.Cut(cookie.Value, ".")
+	unmasked, err := base64.RawURLEncoding.DecodeString(value)
+	require.NoError(t, err)
+
+	forged := base64.RawURLEncoding.EncodeToString(make([]byte, csrfTokenLength))
+
+	rr = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/form", nil)
+	req.AddCookie(cookie)
+	rt.Mux().ServeHTTP(rr, req)
+	assert.Empty(t, rr.Result().Cookies(), "existing token must be reused")
+	assert.NotEqual(t, token, rr.Body.String(), "token must be masked for every response")
+
+	cases := []struct {
+		name         string
+		https        bool
+		cookie       bool
+		forged       bool
+		headers      map[string]string
+		form         url.Values
+		exceptedCode int
+		exceptedBody string
+	}{
+		{
+			name:         "Header token",
+			cookie:       true,
+			headers:      map[string]string{"X-CSRF-Token": token},
+			exceptedCode: http.StatusOK,
+			exceptedBody: "ok",
+		},
+		{
+			name:         "Form token",
+			cookie:       true,
+			form:         url.Values{"_csrf": {token}},
+			exceptedCode: http.StatusOK,
+			exceptedBody: "ok",
+		},
+		{
+			name:         "Unmasked token",
+			cookie:       true,
+			headers:      map[string]string{"X-CSRF-Token": string(unmasked)},
+			exceptedCode: http.StatusOK,
+			exceptedBody: "ok",
+		},
+		{
+			name:         "Unsigned cookie",
+			forged:       true,
+			headers:      map[string]string{"X-CSRF-Token": forged},
+			exceptedCode: http.StatusForbidden,
+			exceptedBody: "invalid CSRF token\n",
+		},
+		{
+			name:         "Missing token",
+			cookie:       true,
+			exceptedCode: http.StatusForbidden,
+			exceptedBody: "missing CSRF token\n",
+		},
+		{
+			name:         "Missing cookie",
+			headers:      map[string]string{"X-CSRF-Token": token},
+			exceptedCode: http.StatusForbidden,
+			exceptedBody: "invalid CSRF token\n",
+		},
+		{
+			name:         "Invalid token",
+			cookie:       true,
+			headers:      map[string]string{"X-CSRF-Token": "invalid"},
+			exceptedCode: http.StatusForbidden,
+			exceptedBody: "invalid CSRF token\n",
+		},
+		{
+			name:         "Same origin",
+			https:        true,
+			cookie:       true,
+			headers:      map[string]string{"X-CSRF-Token": token, "Origin": "https://example.com"},
+			exceptedCode: http.StatusOK,
+			exceptedBody: "ok",
+		},
+		{
+			name:         "Trusted referer",
+			https:        true,
+			cookie:       true,
+			headers:      map[string]string{"X-CSRF-Token": token, "Referer": "https://admin.example.com/users"},
+			exceptedCode: http.StatusOK,
+			exceptedBody: "ok",
+		},
+		{
+			name:         "Cross origin",
+			https:        true,
+			cookie:       true,
+			headers:      map[string]string{"X-CSRF-Token": token, "Origin": "https://evil.com"},
+			exceptedCode: http.StatusForbidden,
+			exceptedBody: "untrusted request origin\n",
+		},
+		{
+			name:         "Missing origin",
+			https:        true,
+			cookie:       true,
+			headers:      map[string]string{"X-CSRF-Token": token},
+			exceptedCode: http.StatusForbidden,
+			exceptedBody: "untrusted request origin\n",
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "http://example.com/form", strings.NewReader(test.form.Encode()))
+			if test.form != nil {
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			}
+			if test.https {
+				req.TLS = &tls.ConnectionState{}
+			}
+			if test.cookie {
+				req.AddCookie(cookie)
+			}
+			if test.forged {
+				req.AddCookie(&http.Cookie{Name: cookie.Name, Value: forged})
+			}
+			for key, value := range test.headers {
+				req.Header.Set(key, value)
+			}
+
+			rr := httptest.NewRecorder()
+			rt.Mux().ServeHTTP(rr, req)
+
+			assert.Equal(t, test.exceptedCode, rr.Code)
+			assert.Equal(t, test.exceptedBody, rr.Body.String())
+		})
+	}
+}
+
+// TestCSRF_multipart tests that the token of multipart forms is read with the multipart limits of the router.
+func TestCSRF_multipart(t *testing.T) {
+	rt := wayes.New()
+	rt.SetCookieKeys([]byte("secret"))
+	rt.SetMultipart(wayes.MultipartConfig{MaxTotalSize: 1024})
+	rt.Use(CSRF())
+	rt.Get("/form", func(ctx wayes.Ctx) error {
+		return ctx.Write(ctx.Locals(wayes.CSRFTokenKey).(string))
+	})
+	rt.Post("/form", func(ctx wayes.Ctx) error {
+		fh, err := ctx.FormFile("file")
+		if err != nil {
+			return err
+		}
+
+		return ctx.Write(fh.Filename)
+	})
+
+	rr := httptest.NewRecorder()
+	rt.Mux().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/form", nil))
+
+	cookies := rr.Result().Cookies()
+	require.Len(t, cookies, 1)
+	token := rr.Body.String()
+
+	cases := []struct {
+		name         string
+		size         int
+		exceptedCode int
+		exceptedBody string
+	}{
+		{
+			name:         "Small upload",
+			size:         100,
+			exceptedCode: http.StatusOK,
+			exceptedBody: "file.txt",
+		},
+		{
+			name:         "Upload over the limit",
+			size:         100 << 10,
+			exceptedCode: http.StatusForbidden,
+			exceptedBody: "missing CSRF token\n",
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			var body bytes.Buffer
+			writer := multipart.NewWriter(&body)
+			require.NoError(t, writer.WriteField("_csrf", token))
+			part, err := writer.CreateFormFile("file", "file.txt")
+			require.NoError(t, err)
+			_, err = part.Write(bytes.Repeat([]byte("a"), test.size))
+			require.NoError(t, err)
+			require.NoError(t, writer.Close())
+
+			req := httptest.NewRequest(http.MethodPost, "/form", &body)
+			req.Header.Set("Content-Type", writer.FormDataContentType())
+			req.AddCookie(cookies[0])
+
+			rr := httptest.NewRecorder()
+			rt.Mux().ServeHTTP(rr, req)
+
+			assert.Equal(t, test.exceptedCode, rr.Code)
+			assert.Equal(t, test.exceptedBody, rr.Body.String())
+		})
+	}
+}
+
+// TestCSRF_session tests the validation of CSRF tokens bound to the session.
+func TestCSRF_session(t *testing.T) {
+	rt := wayes.New()
+	rt.SetCookieKeys([]byte("secret"))
+	rt.Use(session.Middleware(), CSRF(CSRFConfig{Session: true}))
+	rt.Get("/form", func(ctx wayes.Ctx) error {
+		return ctx.Write(ctx.Locals(wayes.CSRFTokenKey).(string))
+	})
+	rt.Post("/form", func(ctx wayes.Ctx) error {
+		return ctx.Write("ok")
+	})
+
+	rr := httptest.NewRecorder()
+	rt.Mux().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/form", nil))
+
+	cookies := rr.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, "session", cookies[0].Name)
+	token := rr.Body.String()
+
+	for _, withSession := range []bool{true, false} {
+		req := httptest.NewRequest(http.MethodPost, "/form", nil)
+		req.Header.Set("X-CSRF-Token", token)
+		if withSession {
+			req.AddCookie(cookies[0])
+		}
+
+		rr = httptest.NewRecorder()
+		rt.Mux().ServeHTTP(rr, req)
+
+		if withSession {
+			assert.Equal(t, http.StatusOK, rr.Code)
+		} else {
+			assert.Equal(t, http.StatusForbidden, rr.Code)
+		}
+	}
+}
+
+// TestCSRF_errorConfig tests the errors of the misconfigured middleware.
+func TestCSRF_errorConfig(t *testing.T) {
+	cases := []struct {
+		name          string
+		config        CSRFConfig
+		exceptedError error
+	}{
+		{
+			name:          "Missing cookie keys",
+			exceptedError: wayes.ErrCookieKeys,
+		},
+		{
+			name:          "Missing session middleware",
+			config:        CSRFConfig{Session: true},
+			exceptedError: errCSRFSession,
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			var handled error
+
+			rt := wayes.New()
+			rt.SetErrorHandler(func(ctx wayes.Ctx, err error) { handled = err })
+			rt.Use(CSRF(test.config))
+			rt.Get("/form", func(ctx wayes.Ctx) error {
+				return ctx.Write("ok")
+			})
+
+			rt.Mux().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/form", nil))
+
+			assert.ErrorIs(t, handled, test.exceptedError)
+		})
+	}
+}
+
+// TestCSRF_panic tests that the token is not looked up in cookies, which defeats the double-submit check.
+func TestCSRF_panic(t *testing.T) {
+	assert.PanicsWithValue(t, `wayes: CSRF token lookup "cookie:csrf" must not read cookies`, func() {
+		CSRF(CSRFConfig{TokenLookup: "header:X-CSRF-Token, cookie:csrf"})
+	})
+}
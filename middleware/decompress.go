@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/eliofery/wayes"
+)
+
+// DecompressConfig represents a structure for the [Decompress] middleware configuration.
+type DecompressConfig struct {
+	// MaxSize is the maximum size in bytes of the decompressed body. Defaults to 10 MB.
+	MaxSize int64
+}
+
+// Decompress creates a middleware that transparently inflates request bodies encoded with gzip or deflate.
+// The decompressed body is limited by the maximum size to protect against decompression bombs,
+// reading beyond the limit fails with [http.MaxBytesError] and [wayes.Ctx.Decode] responds with 413.
+// Bodies with other content codings are rejected with 415 Unsupported Media Type.
+func Decompress(config ...DecompressConfig) wayes.Handler {
+	cfg := DecompressConfig{}
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+
+	if cfg.MaxSize <= 0 {
+		cfg.MaxSize = 10 << 20
+	}
+
+	return func(ctx wayes.Ctx) error {
+		r := ctx.Request()
+
+		encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding")))
+		if encoding == "" || encoding == "identity" || r.Body == nil || r.Body == http.NoBody {
+			return ctx.Next()
+		}
+
+		var (
+			reader io.ReadCloser
+			err    error
+		)
+
+		switch encoding {
+		case "gzip", "x-gzip":
+			reader, err = gzip.NewReader(r.Body)
+		case "deflate":
+			reader, err = newDeflateReader(r.Body)
+		default:
+			return wayes.NewError(http.StatusUnsupportedMediaType)
+		}
+
+		if err != nil {
+			return wayes.NewError(http.StatusBadRequest, "invalid body encoding")
+		}
+		defer reader.Close()
+
+		req := r.WithContext(r.Context())
+		req.Header = r.Header.Clone()
+		req.Header.Del("Content-Encoding")
+		req.Header.Del("Content-Length")
+		req.ContentLength = -1
+		req.Body = http.MaxBytesReader(ctx.Response(), reader, cfg.MaxSize)
+		ctx.SetRequest(req)
+
+		return ctx.Next()
+	}
+}
+
+// newDeflateReader creates a reader for the deflate content coding.
+// The coding is defined as the zlib format, but some clients send raw deflate data, both are accepted.
+func newDeflateReader(body io.Reader) (io.ReadCloser, error) {
+	buffered := bufio.NewReader(body)
+
+	header, err := buffered.Peek(2)
+	if err != nil {
+		return nil, err
+	}
+
+	if header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		return zlib.NewReader(buffered)
+	}
+
+	return flate.NewReader(buffered), nil
+}
@@ -0,0 +1,131 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/eliofery/wayes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// compressBody compresses the data with the writer created by the constructor.
+func compressBody(t *testing.T, data string, newWriter func(w io.Writer) io.WriteCloser) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w := newWriter(&buf)
+	_, err := w.Write([]byte(data))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	return buf.Bytes()
+}
+
+// TestDecompress tests the decompression of request bodies.
+func TestDecompress(t *testing.T) {
+	data := `{"name":"wayes"}`
+
+	cases := []struct {
+		name         string
+		encoding     string
+		body         []byte
+		exceptedCode int
+		exceptedBody string
+	}{
+		{
+			name:         "Plain body",
+			body:         []byte(data),
+			exceptedCode: http.StatusOK,
+			exceptedBody: "wayes",
+		},
+		{
+			name:     "Gzip body",
+			encoding: "gzip",
+			body: compressBody(t, data, func(w io.Writer) io.WriteCloser {
+				return gzip.NewWriter(w)
+			}),
+			exceptedCode: http.StatusOK,
+			exceptedBody: "wayes",
+		},
+		{
+			name:     "Zlib deflate body",
+			encoding: "deflate",
+			body: compressBody(t, data, func(w io.Writer) io.WriteCloser {
+				return zlib.NewWriter(w)
+			}),
+			exceptedCode: http.StatusOK,
+			exceptedBody: "wayes",
+		},
+		{
+			name:     "Raw deflate body",
+			encoding: "deflate",
+			body: compressBody(t, data, func(w io.Writer) io.WriteCloser {
+				fw, _ := flate.NewWriter(w, flate.DefaultCompression)
+				return fw
+			}),
+			exceptedCode: http.StatusOK,
+			exceptedBody: "wayes",
+		},
+		{
+			name:     "Decompression bomb",
+			encoding: "gzip",
+			body: compressBody(t, `{"name":"`+strings.Repeat("a", 1<<20)+`"}`, func(w io.Writer) io.WriteCloser {
+				return gzip.NewWriter(w)
+			}),
+			exceptedCode: http.StatusRequestEntityTooLarge,
+			exceptedBody: "body too large\n",
+		},
+		{
+			name:         "Invalid gzip body",
+			encoding:     "gzip",
+			body:         []byte(data),
+			exceptedCode: http.StatusBadRequest,
+			exceptedBody: "invalid body encoding\n",
+		},
+		{
+			name:         "Unsupported encoding",
+			encoding:     "compress",
+			body:         []byte(data),
+			exceptedCode: http.StatusUnsupportedMediaType,
+			exceptedBody: "Unsupported Media Type\n",
+		},
+	}
+
+	rt := wayes.New()
+	rt.Use(Decompress(DecompressConfig{MaxSize: 1024}))
+	rt.Post("/test", func(ctx wayes.Ctx) error {
+		var user struct {
+			Name string `json:"name"`
+		}
+
+		if err := ctx.Decode(&user); err != nil {
+			return ctx.SendError(err)
+		}
+
+		return ctx.Write(user.Name)
+	})
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, "/test", bytes.NewReader(test.body))
+			require.NoError(t, err)
+			if test.encoding != "" {
+				req.Header.Set("Content-Encoding", test.encoding)
+			}
+
+			rr := httptest.NewRecorder()
+			rt.Mux().ServeHTTP(rr, req)
+
+			assert.Equal(t, test.exceptedCode, rr.Code)
+			assert.Equal(t, test.exceptedBody, rr.Body.String())
+		})
+	}
+}
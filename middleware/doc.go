@@ -0,0 +1,18 @@
+/*
+Package middleware provides ready-to-use middlewares for the [github.com/eliofery/wayes] router.
+
+Every middleware is a constructor that returns a [github.com/eliofery/wayes.Handler]
+and can be registered for the whole router or for a route group with Use.
+
+	router := wayes.New()
+
+	// Define middleware for the all routes.
+	router.Use(middleware.RequestID())
+
+	router.Get("/welcome", func(ctx wayes.Ctx) error {
+		id := wayes.RequestIDFrom(ctx.Request().Context())
+
+		return ctx.Write(id)
+	})
+*/
+package middleware
@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/eliofery/wayes"
+)
+
+// ETagConfig represents a structure for the [ETag] middleware configuration.
+type ETagConfig struct {
+	// Weak indicates whether weak ETags are generated.
+	Weak bool
+}
+
+// ETag creates a middleware that generates ETags for successful GET and HEAD responses
+// which do not set one, and answers conditional requests with 304 Not Modified or
+// a [wayes.Error] with 412 Precondition Failed. Streamed responses are sent as is.
+func ETag(config ...ETagConfig) wayes.Handler {
+	cfg := ETagConfig{}
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+
+	return func(ctx wayes.Ctx) error {
+		method := ctx.Request().Method
+		if method != http.MethodGet && method != http.MethodHead {
+			return ctx.Next()
+		}
+
+		original := ctx.Response()
+		cw := newCaptureWriter(original)
+
+		ctx.SetResponse(cw)
+		err := ctx.Next()
+		ctx.SetResponse(original)
+
+		if err != nil || cw.streaming || cw.code < http.StatusOK || cw.code >= http.StatusMultipleChoices {
+			cw.flush()
+			return err
+		}
+
+		cw.copyHeader()
+		if ctx.Get("ETag") == "" {
+			ctx.Set("ETag", wayes.GenerateETag(cw.buf.Bytes(), cfg.Weak))
+		}
+
+		if ctx.Fresh() {
+			header := original.Header()
+			header.Del("Content-Type")
+			header.Del("Content-Length")
+			original.WriteHeader(http.StatusNotModified)
+
+			return nil
+		}
+
+		if err := ctx.Precondition(); err != nil {
+			return err
+		}
+
+		cw.flush()
+
+		return nil
+	}
+}
@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/eliofery/wayes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestETag tests the generation of ETags and handling of conditional requests by the middleware.
+func TestETag(t *testing.T) {
+	body := "Test etag response"
+	etag := wayes.GenerateETag([]byte(body), true)
+
+	cases := []struct {
+		name         string
+		method       string
+		path         string
+		headers      map[string]string
+		exceptedCode int
+		exceptedETag string
+		exceptedBody string
+	}{
+		{
+			name:         "Generated ETag",
+			method:       http.MethodGet,
+			path:         "/test",
+			exceptedCode: http.StatusOK,
+			exceptedETag: etag,
+			exceptedBody: body,
+		},
+		{
+			name:         "Not modified",
+			method:       http.MethodGet,
+			path:         "/test",
+			headers:      map[string]string{"If-None-Match": etag},
+			exceptedCode: http.StatusNotModified,
+			exceptedETag: etag,
+		},
+		{
+			name:         "Precondition failed",
+			method:       http.MethodGet,
+			path:         "/test",
+			headers:      map[string]string{"If-Match": `"other"`},
+			exceptedCode: http.StatusPreconditionFailed,
+			exceptedETag: etag,
+			exceptedBody: "Precondition Failed\n",
+		},
+		{
+			name:         "Handler ETag",
+			method:       http.MethodGet,
+			path:         "/versioned",
+			headers:      map[string]string{"If-None-Match": `"v1"`},
+			exceptedCode: http.StatusNotModified,
+			exceptedETag: `"v1"`,
+		},
+		{
+			name:         "Error response",
+			method:       http.MethodGet,
+			path:         "/missing",
+			headers:      map[string]string{"If-None-Match": "*"},
+			exceptedCode: http.StatusNotFound,
+			exceptedBody: "Not Found",
+		},
+		{
+			name:         "Unsafe method",
+			method:       http.MethodPost,
+			path:         "/test",
+			exceptedCode: http.StatusOK,
+			exceptedBody: body,
+		},
+	}
+
+	rt := wayes.New()
+	rt.Use(ETag(ETagConfig{Weak: true}))
+	rt.Get("/test", func(ctx wayes.Ctx) error {
+		return ctx.Write(body)
+	})
+	rt.Post("/test", func(ctx wayes.Ctx) error {
+		return ctx.Write(body)
+	})
+	rt.Get("/versioned", func(ctx wayes.Ctx) error {
+		ctx.Set("ETag", `"v1"`)
+		return ctx.Write(body)
+	})
+	rt.Get("/missing", func(ctx wayes.Ctx) error {
+		return ctx.SendStatus(http.StatusNotFound)
+	})
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			req, err := http.NewRequest(test.method, test.path, nil)
+			require.NoError(t, err)
+			for key, value := range test.headers {
+				req.Header.Set(key, value)
+			}
+
+			rr := httptest.NewRecorder()
+			rt.Mux().ServeHTTP(rr, req)
+
+			assert.Equal(t, test.exceptedCode, rr.Code)
+			assert.Equal(t, test.exceptedETag, rr.Header().Get("ETag"))
+			assert.Equal(t, test.exceptedBody, rr.Body.String())
+		})
+	}
+}
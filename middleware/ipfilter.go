@@ -0,0 +1,194 @@
+package middleware
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/eliofery/wayes"
+)
+
+// IPFilterConfig represents a structure for the [IPFilter] middleware configuration.
+type IPFilterConfig struct {
+	// Allow is a list of CIDR ranges or IP addresses that are allowed.
+	// If the list is empty, all addresses that are not denied are allowed.
+	Allow []string
+
+	// Deny is a list of CIDR ranges or IP addresses that are denied. Denied addresses take precedence.
+	Deny []string
+
+	// File is the path to a file with additional lists, which is reloaded when it changes.
+	// Every line contains "allow" or "deny" followed by a CIDR range or an IP address,
+	// empty lines and lines starting with # are ignored.
+	File string
+
+	// ReloadInterval is the minimum interval between checks of the file for changes. Defaults to 10 seconds.
+	ReloadInterval time.Duration
+}
+
+// ipLists represents a structure for the allowed and denied ranges.
+type ipLists struct {
+	allow []netip.Prefix
+	deny  []netip.Prefix
+}
+
+// allowed reports whether the address is allowed by the lists.
+func (l *ipLists) allowed(addr netip.Addr) bool {
+	if containsAddr(l.deny, addr) {
+		return false
+	}
+
+	return len(l.allow) == 0 || containsAddr(l.allow, addr)
+}
+
+// ipFilter represents a structure for the state of the [IPFilter] middleware.
+type ipFilter struct {
+	static   ipLists
+	file     string
+	interval time.Duration
+	lists    atomic.Pointer[ipLists]
+	mu       sync.Mutex
+	checked  time.Time
+	modified time.Time
+	now      func() time.Time
+}
+
+// IPFilter creates a middleware that allows or denies requests by the client IP address.
+// The address is resolved by [wayes.Ctx.IP], so the router trusted proxies are honoured.
+// A [wayes.Error] with 403 Forbidden is returned for denied requests.
+// It panics if the lists contain invalid ranges or the file cannot be loaded.
+func IPFilter(config IPFilterConfig) wayes.Handler {
+	if config.ReloadInterval <= 0 {
+		config.ReloadInterval = 10 * time.Second
+	}
+
+	allow, err := wayes.ParsePrefixes(config.Allow)
+	if err != nil {
+		panic(fmt.Sprintf("wayes: %v", err))
+	}
+
+	deny, err := wayes.ParsePrefixes(config.Deny)
+	if err != nil {
+		panic(fmt.Sprintf("wayes: %v", err))
+	}
+
+	filter := &ipFilter{
+		static:   ipLists{allow: allow, deny: deny},
+		file:     config.File,
+		interval: config.ReloadInterval,
+		now:      time.Now,
+	}
+	filter.lists.Store(&filter.static)
+
+	if filter.file != "" {
+		if err := filter.load(); err != nil {
+			panic(fmt.Sprintf("wayes: %v", err))
+		}
+	}
+
+	return func(ctx wayes.Ctx) error {
+		filter.reload()
+
+		addr, err := netip.ParseAddr(ctx.IP())
+		if err != nil || !filter.lists.Load().allowed(addr.Unmap()) {
+			return wayes.NewError(http.StatusForbidden)
+		}
+
+		return ctx.Next()
+	}
+}
+
+// reload loads the file again if the reload interval has passed and the file has changed.
+// Errors are ignored and the previous lists are kept, so a broken file does not open or close the access.
+func (f *ipFilter) reload() {
+	if f.file == "" {
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := f.now()
+	if now.Sub(f.checked) < f.interval {
+		return
+	}
+	f.checked = now
+
+	_ = f.load()
+}
+
+// load reads the lists from the file if it was modified since the last load, the caller must hold the lock.
+func (f *ipFilter) load() error {
+	info, err := os.Stat(f.file)
+	if err != nil {
+		return err
+	}
+
+	if info.ModTime().Equal(f.modified) {
+		return nil
+	}
+
+	file, err := os.Open(f.file)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	lists := ipLists{
+		allow: append([]netip.Prefix(nil), f.static.allow...),
+		deny:  append([]netip.Prefix(nil), f.static.deny...),
+	}
+
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return fmt.Errorf("%s:%d: invalid rule %q", f.file, line, text)
+		}
+
+		prefixes, err := wayes.ParsePrefixes(fields[1:])
+		if err != nil {
+			return fmt.Errorf("%s:%d: %w", f.file, line, err)
+		}
+
+		switch strings.ToLower(fields[0]) {
+		case "allow":
+			lists.allow = append(lists.allow, prefixes...)
+		case "deny":
+			lists.deny = append(lists.deny, prefixes...)
+		default:
+			return fmt.Errorf("%s:%d: invalid action %q", f.file, line, fields[0])
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	f.modified = info.ModTime()
+	f.lists.Store(&lists)
+
+	return nil
+}
+
+// containsAddr reports whether the address belongs to one of the ranges.
+func containsAddr(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
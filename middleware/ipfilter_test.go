@@ -0,0 +1,130 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/eliofery/wayes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestIPFilter tests the allowing and denying of requests by the client IP address.
+func TestIPFilter(t *testing.T) {
+	cases := []struct {
+		name         string
+		remoteAddr   string
+		forwarded    string
+		exceptedCode int
+	}{
+		{
+			name:         "Allowed IPv4",
+			remoteAddr:   "192.168.1.10:1234",
+			exceptedCode: http.StatusOK,
+		},
+		{
+			name:         "Denied IPv4",
+			remoteAddr:   "192.168.1.66:1234",
+			exceptedCode: http.StatusForbidden,
+		},
+		{
+			name:         "Allowed IPv6",
+			remoteAddr:   "[2001:db8::1]:1234",
+			exceptedCode: http.StatusOK,
+		},
+		{
+			name:         "IPv4-mapped IPv6",
+			remoteAddr:   "[::ffff:192.168.1.10]:1234",
+			exceptedCode: http.StatusOK,
+		},
+		{
+			name:         "Not allowed",
+			remoteAddr:   "203.0.113.7:1234",
+			exceptedCode: http.StatusForbidden,
+		},
+		{
+			name:         "Client behind trusted proxy",
+			remoteAddr:   "10.0.0.1:1234",
+			forwarded:    "192.168.1.66",
+			exceptedCode: http.StatusForbidden,
+		},
+	}
+
+	rt := wayes.New()
+	require.NoError(t, rt.SetTrustedProxies("10.0.0.0/8"))
+	rt.Use(IPFilter(IPFilterConfig{
+		Allow: []string{"192.168.1.0/24", "2001:db8::/32", "10.0.0.1"},
+		Deny:  []string{"192.168.1.66"},
+	}))
+	rt.Get("/test", func(ctx wayes.Ctx) error {
+		return ctx.Write("Test ip filter response")
+	})
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/test", nil)
+			req.RemoteAddr = test.remoteAddr
+			if test.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", test.forwarded)
+			}
+
+			rr := httptest.NewRecorder()
+			rt.Mux().ServeHTTP(rr, req)
+
+			assert.Equal(t, test.exceptedCode, rr.Code)
+		})
+	}
+}
+
+// TestIPFilter_file tests the hot reload of the lists from the file.
+func TestIPFilter_file(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ips.txt")
+	require.NoError(t, os.WriteFile(path, []byte("# blocked clients\ndeny 203.0.113.0/24\n"), 0o600))
+
+	rt := wayes.New()
+	rt.Use(IPFilter(IPFilterConfig{File: path, ReloadInterval: time.Nanosecond}))
+	rt.Get("/test", func(ctx wayes.Ctx) error {
+		return ctx.Write("Test ip filter response")
+	})
+
+	request := func(remoteAddr string) int {
+		req := httptest.NewRequest(http.MethodGet, "/test", nil)
+		req.RemoteAddr = remoteAddr
+
+		rr := httptest.NewRecorder()
+		rt.Mux().ServeHTTP(rr, req)
+
+		return rr.Code
+	}
+
+	assert.Equal(t, http.StatusForbidden, request("203.0.113.7:1234"))
+	assert.Equal(t, http.StatusOK, request("198.51.100.1:1234"))
+
+	modified := time.Now().Add(time.Minute)
+	require.NoError(t, os.WriteFile(path, []byte("deny 198.51.100.0/24\n"), 0o600))
+	require.NoError(t, os.Chtimes(path, modified, modified))
+
+	assert.Equal(t, http.StatusOK, request("203.0.113.7:1234"))
+	assert.Equal(t, http.StatusForbidden, request("198.51.100.1:1234"))
+
+	modified = modified.Add(time.Minute)
+	require.NoError(t, os.WriteFile(path, []byte("block everyone\n"), 0o600))
+	require.NoError(t, os.Chtimes(path, modified, modified))
+
+	assert.Equal(t, http.StatusForbidden, request("198.51.100.1:1234"), "invalid file must keep the previous lists")
+}
+
+// TestIPFilter_panic tests that the middleware rejects invalid ranges.
+func TestIPFilter_panic(t *testing.T) {
+	assert.Panics(t, func() {
+		IPFilter(IPFilterConfig{Allow: []string{"10.0.0.0/40"}})
+	})
+
+	assert.Panics(t, func() {
+		IPFilter(IPFilterConfig{File: filepath.Join(t.TempDir(), "missing.txt")})
+	})
+}
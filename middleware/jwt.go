@@ -0,0 +1,468 @@
+package middleware
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/eliofery/wayes"
+)
+
+var (
+	// ErrTokenMalformed represents an error indicating that the token cannot be parsed.
+	ErrTokenMalformed = errors.New("token is malformed")
+
+	// ErrTokenSignature represents an error indicating that the token signature is not valid.
+	ErrTokenSignature = errors.New("token signature is invalid")
+
+	// ErrTokenAlgorithm represents an error indicating that the token algorithm is not allowed.
+	ErrTokenAlgorithm = errors.New("token algorithm is not allowed")
+
+	// ErrTokenExpired represents an error indicating that the token is expired.
+	ErrTokenExpired = errors.New("token is expired")
+
+	// ErrTokenNotValidYet represents an error indicating that the token is used before its nbf claim.
+	ErrTokenNotValidYet = errors.New("token is not valid yet")
+
+	// ErrTokenAudience represents an error indicating that the token audience does not match.
+	ErrTokenAudience = errors.New("token audience is invalid")
+
+	// ErrTokenIssuer represents an error indicating that the token issuer does not match.
+	ErrTokenIssuer = errors.New("token issuer is invalid")
+
+	// ErrKeyNotFound represents an error indicating that no key matches the token.
+	ErrKeyNotFound = errors.New("key not found")
+)
+
+// Claims represents the claims of a JSON Web Token.
+type Claims map[string]any
+
+// Subject returns the value of the sub claim.
+func (c Claims) Subject() string {
+	sub, _ := c["sub"].(string)
+	return sub
+}
+
+// Issuer returns the value of the iss claim.
+func (c Claims) Issuer() string {
+	iss, _ := c["iss"].(string)
+	return iss
+}
+
+// Audience returns the value of the aud claim, which may be a string or an array of strings.
+func (c Claims) Audience() []string {
+	return c.strings("aud")
+}
+
+// Roles returns the value of the roles claim, so that the claims can be used with [wayes.RequireRoles].
+func (c Claims) Roles() []string {
+	return c.strings("roles")
+}
+
+// Permissions returns the value of the permissions claim or the space-separated scope claim,
+// so that the claims can be used with [wayes.RequirePermissions].
+func (c Claims) Permissions() []string {
+	if _, ok := c["permissions"]; ok {
+		return c.strings("permissions")
+	}
+
+	scope, _ := c["scope"].(string)
+
+	return strings.Fields(scope)
+}
+
+// strings returns the value of the claim, which may be a string or an array of strings.
+func (c Claims) strings(name string) []string {
+	switch value := c[name].(type) {
+	case string:
+		return []string{value}
+	case []any:
+		values := make([]string, 0, len(value))
+		for _, v := range value {
+			if s, ok := v.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	default:
+		return nil
+	}
+}
+
+// time returns the value of the numeric date claim.
+func (c Claims) time(name string) (time.Time, bool) {
+	value, ok := c[name].(float64)
+	if !ok {
+		return time.Time{}, false
+	}
+
+	return time.Unix(int64(value), 0), true
+}
+
+// JWTConfig represents a structure for the [JWT] middleware configuration.
+type JWTConfig struct {
+	// Secret is the key used to verify tokens signed with HS256.
+	Secret []byte
+
+	// PublicKey is the key used to verify tokens signed with RS256 (*rsa.PublicKey) or ES256 (*ecdsa.PublicKey).
+	PublicKey crypto.PublicKey
+
+	// KeySet is the set of keys used to verify tokens, the key is selected by the kid header.
+	KeySet *JWKS
+
+	// Algorithms is a list of allowed signing algorithms. Defaults to HS256, RS256 and ES256.
+	Algorithms []string
+
+	// Audience is the expected value of the aud claim, it is not checked when empty.
+	Audience string
+
+	// Issuer is the expected value of the iss claim, it is not checked when empty.
+	Issuer string
+
+	// Leeway is the allowed clock skew for the exp and nbf claims.
+	Leeway time.Duration
+
+	// TokenLookup is a comma-separated list of "<source>:<name>" pairs the token is looked up in,
+	// where the source is one of header, query or cookie. Defaults to "header:Authorization".
+	TokenLookup string
+
+	// ContextKey is the key under which the [Claims] are stored in [wayes.Ctx.Locals].
+	// Defaults to [wayes.PrincipalKey].
+	ContextKey any
+}
+
+// JWT creates a middleware that authenticates requests by a JSON Web Token.
+// The token signature is verified with HS256, RS256 or ES256 and the exp, nbf, aud and iss claims are checked.
+// On success the [Claims] are stored in [wayes.Ctx.Locals], otherwise a [wayes.Error]
+// with 401 Unauthorized and the WWW-Authenticate header is returned.
+func JWT(config JWTConfig) wayes.Handler {
+	if config.Secret == nil && config.PublicKey == nil && config.KeySet == nil {
+		panic("wayes: JWT requires a secret, a public key or a key set")
+	}
+
+	if len(config.Algorithms) == 0 {
+		config.Algorithms = []string{"HS256", "RS256", "ES256"}
+	}
+
+	if config.TokenLookup == "" {
+		config.TokenLookup = "header:Authorization"
+	}
+
+	if config.ContextKey == nil {
+		config.ContextKey = wayes.PrincipalKey
+	}
+
+	extract := newExtractor(config.TokenLookup, "Bearer")
+
+	return func(ctx wayes.Ctx) error {
+		token := extract(ctx)
+		if token == "" {
+			return unauthorized(ctx, "Bearer", "Restricted", errMissingKey)
+		}
+
+		claims, err := config.parse(token, time.Now())
+		if err != nil {
+			return unauthorized(ctx, "Bearer", "Restricted", err)
+		}
+
+		ctx.Locals(config.ContextKey, claims)
+
+		return ctx.Next()
+	}
+}
+
+// parse verifies the token and returns its claims.
+func (config JWTConfig) parse(token string, now time.Time) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrTokenMalformed
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, err
+	}
+
+	if !slices.Contains(config.Algorithms, header.Alg) {
+		return nil, ErrTokenAlgorithm
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrTokenMalformed
+	}
+
+	key, err := config.key(header.Alg, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := verify(header.Alg, key, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, err
+	}
+
+	if exp, ok := claims.time("exp"); ok && !now.Before(exp.Add(config.Leeway)) {
+		return nil, ErrTokenExpired
+	}
+
+	if nbf, ok := claims.time("nbf"); ok && now.Add(config.Leeway).Before(nbf) {
+		return nil, ErrTokenNotValidYet
+	}
+
+	if config.Issuer != "" && claims.Issuer() != config.Issuer {
+		return nil, ErrTokenIssuer
+	}
+
+	if config.Audience != "" && !slices.Contains(claims.Audience(), config.Audience) {
+		return nil, ErrTokenAudience
+	}
+
+	return claims, nil
+}
+
+// key returns the key that verifies the token with the given algorithm and key identifier.
+// HS256 tokens are verified with the secret, other tokens with the public key or the key set.
+func (config JWTConfig) key(alg, kid string) (any, error) {
+	if alg == "HS256" && config.Secret != nil {
+		return config.Secret, nil
+	}
+
+	if config.KeySet != nil {
+		return config.KeySet.Key(kid)
+	}
+
+	if config.PublicKey != nil {
+		return config.PublicKey, nil
+	}
+
+	return config.Secret, nil
+}
+
+// verify checks the signature of the signing input with the algorithm and the key.
+// The type of the key must match the algorithm to prevent algorithm confusion.
+func verify(alg string, key any, input string, signature []byte) error {
+	digest := sha256.Sum256([]byte(input))
+
+	switch alg {
+	case "HS256":
+		secret, ok := key.([]byte)
+		if !ok {
+			return ErrTokenAlgorithm
+		}
+
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(input))
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return ErrTokenSignature
+		}
+	case "RS256":
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return ErrTokenAlgorithm
+		}
+
+		if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], signature); err != nil {
+			return ErrTokenSignature
+		}
+	case "ES256":
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok || pub.Curve != elliptic.P256() {
+			return ErrTokenAlgorithm
+		}
+
+		if len(signature) != 64 {
+			return ErrTokenSignature
+		}
+
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(pub, digest[:], r, s) {
+			return ErrTokenSignature
+		}
+	default:
+		return ErrTokenAlgorithm
+	}
+
+	return nil
+}
+
+// decodeSegment decodes the base64url encoded JSON segment of the token.
+func decodeSegment(segment string, data any) error {
+	raw, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return ErrTokenMalformed
+	}
+
+	if err := json.Unmarshal(raw, data); err != nil {
+		return ErrTokenMalformed
+	}
+
+	return nil
+}
+
+// JWKS represents a JSON Web Key Set.
+type JWKS struct {
+	keys map[string]any
+}
+
+// jwk represents a structure for a JSON Web Key.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	K   string `json:"k"`
+}
+
+// ParseJWKS reads a JSON Web Key Set with RSA, EC and symmetric keys.
+// Keys intended for encryption are skipped.
+func ParseJWKS(r io.Reader) (*JWKS, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(r).Decode(&set); err != nil {
+		return nil, fmt.Errorf("decode jwks: %w", err)
+	}
+
+	jwks := &JWKS{keys: make(map[string]any, len(set.Keys))}
+	for _, key := range set.Keys {
+		if key.Use != "" && key.Use != "sig" {
+			continue
+		}
+
+		parsed, err := key.parse()
+		if err != nil {
+			return nil, fmt.Errorf("parse jwk %q: %w", key.Kid, err)
+		}
+
+		jwks.keys[key.Kid] = parsed
+	}
+
+	return jwks, nil
+}
+
+// LoadJWKSFile reads a JSON Web Key Set from the file.
+func LoadJWKSFile(path string) (*JWKS, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ParseJWKS(file)
+}
+
+// LoadJWKSURL fetches a JSON Web Key Set from the URL, for example a local identity provider.
+func LoadJWKSURL(url string) (*JWKS, error) {
+	client := http.Client{Timeout: 10 * time.Second}
+
+	res, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch jwks: unexpected status %s", res.Status)
+	}
+
+	return ParseJWKS(res.Body)
+}
+
+// Key returns the key with the given identifier.
+// If the identifier is empty and the set contains a single key, that key is returned.
+func (s *JWKS) Key(kid string) (any, error) {
+	if key, ok := s.keys[kid]; ok {
+		return key, nil
+	}
+
+	if kid == "" && len(s.keys) == 1 {
+		for _, key := range s.keys {
+			return key, nil
+		}
+	}
+
+	return nil, ErrKeyNotFound
+}
+
+// parse converts the JSON Web Key into a Go key.
+func (k jwk) parse() (any, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "oct":
+		return base64.RawURLEncoding.DecodeString(k.K)
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+// decodeBigInt decodes the base64url encoded big-endian integer.
+func decodeBigInt(value string) (*big.Int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return new(big.Int).SetBytes(raw), nil
+}
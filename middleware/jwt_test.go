@@ -0,0 +1,330 @@
+package middleware
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eliofery/wayes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// signToken creates a signed JSON Web Token for the tests.
+func signToken(t *testing.T, alg, kid string, key any, claims Claims) string {
+	t.Helper()
+
+	header, err := json.Marshal(map[string]string{"alg": alg, "typ": "JWT", "kid": kid})
+	require.NoError(t, err)
+
+	payload, err := json.Marshal(claims)
+	require.NoError(t, err)
+
+	input := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(input))
+
+	var signature []byte
+	switch k := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(input))
+		signature = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		signature, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+		require.NoError(t, err)
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest[:])
+		require.NoError(t, err)
+		signature = make([]byte, 64)
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:])
+	}
+
+	return input + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// TestJWT tests the verification of tokens and their claims.
+func TestJWT(t *testing.T) {
+	secret := []byte("test-secret")
+	now := time.Now()
+
+	cases := []struct {
+		name          string
+		token         func() string
+		exceptedCode  int
+		exceptedError string
+	}{
+		{
+			name: "Valid token",
+			token: func() string {
+				return signToken(t, "HS256", "", secret, Claims{
+					"sub": "user-1",
+					"iss": "wayes",
+					"aud": []string{"api", "web"},
+					"exp": now.Add(time.Hour).Unix(),
+					"nbf": now.Add(-time.Hour).Unix(),
+				})
+			},
+			exceptedCode: http.StatusOK,
+		},
+		{
+			name: "Expired token",
+			token: func() string {
+				return signToken(t, "HS256", "", secret, Claims{
+					"iss": "wayes", "aud": "api", "exp": now.Add(-time.Hour).Unix(),
+				})
+			},
+			exceptedCode:  http.StatusUnauthorized,
+			exceptedError: ErrTokenExpired.Error(),
+		},
+		{
+			name: "Token is not valid yet",
+			token: func() string {
+				return signToken(t, "HS256", "", secret, Claims{
+					"iss": "wayes", "aud": "api", "nbf": now.Add(time.Hour).Unix(),
+				})
+			},
+			exceptedCode:  http.StatusUnauthorized,
+			exceptedError: ErrTokenNotValidYet.Error(),
+		},
+		{
+			name: "Wrong audience",
+			token: func() string {
+				return signToken(t, "HS256", "", secret, Claims{"iss": "wayes", "aud": "web"})
+			},
+			exceptedCode:  http.StatusUnauthorized,
+			exceptedError: ErrTokenAudience.Error(),
+		},
+		{
+			name: "Wrong issuer",
+			token: func() string {
+				return signToken(t, "HS256", "", secret, Claims{"iss": "other", "aud": "api"})
+			},
+			exceptedCode:  http.StatusUnauthorized,
+			exceptedError: ErrTokenIssuer.Error(),
+		},
+		{
+			name: "Wrong signature",
+			token: func() string {
+				return signToken(t, "HS256", "", []byte("other"), Claims{"iss": "wayes", "aud": "api"})
+			},
+			exceptedCode:  http.StatusUnauthorized,
+			exceptedError: ErrTokenSignature.Error(),
+		},
+		{
+			name: "None algorithm",
+			token: func() string {
+				token := signToken(t, "none", "", secret, Claims{"iss": "wayes", "aud": "api"})
+				return token[:strings.LastIndex(token, ".")+1]
+			},
+			exceptedCode:  http.StatusUnauthorized,
+			exceptedError: ErrTokenAlgorithm.Error(),
+		},
+		{
+			name: "Malformed token",
+			token: func() string {
+				return "not-a-token"
+			},
+			exceptedCode:  http.StatusUnauthorized,
+			exceptedError: ErrTokenMalformed.Error(),
+		},
+	}
+
+	rt := wayes.New()
+	rt.Use(JWT(JWTConfig{
+		Secret:     secret,
+		Algorithms: []string{"HS256"},
+		Audience:   "api",
+		Issuer:     "wayes",
+	}))
+	rt.Get("/test", func(ctx wayes.Ctx) error {
+		return ctx.Write(ctx.Locals(wayes.PrincipalKey).(Claims).Subject())
+	})
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, "/test", nil)
+			require.NoError(t, err)
+			req.Header.Set("Authorization", "Bearer "+test.token())
+
+			rr := httptest.NewRecorder()
+			rt.Mux().ServeHTTP(rr, req)
+
+			assert.Equal(t, test.exceptedCode, rr.Code)
+			if test.exceptedCode == http.StatusOK {
+				assert.Equal(t, "user-1", rr.Body.String())
+			} else {
+				assert.Contains(t, rr.Header().Get("WWW-Authenticate"), test.exceptedError)
+			}
+		})
+	}
+}
+
+// TestJWT_keySet tests the verification of asymmetric tokens with keys from a JSON Web Key Set.
+func TestJWT_keySet(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	encode := func(i *big.Int) string {
+		return base64.RawURLEncoding.EncodeToString(i.Bytes())
+	}
+
+	set := fmt.Sprintf(`{"keys":[
+		{"kty":"RSA","kid":"rsa","use":"sig","n":%q,"e":%q},
+		{"kty":"EC","kid":"ec","crv":"P-256","x":%q,"y":%q},
+		{"kty":"RSA","kid":"enc","use":"enc","n":%q,"e":%q}
+	]}`,
+		encode(rsaKey.N), encode(big.NewInt(int64(rsaKey.E))),
+		encode(ecKey.X), encode(ecKey.Y),
+		encode(rsaKey.N), encode(big.NewInt(int64(rsaKey.E))),
+	)
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, []byte(set), 0o600))
+
+	jwks, err := LoadJWKSFile(path)
+	require.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(set))
+	}))
+	defer server.Close()
+
+	fetched, err := LoadJWKSURL(server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, jwks, fetched)
+
+	_, err = jwks.Key("enc")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+
+	cases := []struct {
+		name         string
+		token        string
+		exceptedCode int
+	}{
+		{
+			name:         "RS256",
+			token:        signToken(t, "RS256", "rsa", rsaKey, Claims{"sub": "user-1"}),
+			exceptedCode: http.StatusOK,
+		},
+		{
+			name:         "ES256",
+			token:        signToken(t, "ES256", "ec", ecKey, Claims{"sub": "user-1"}),
+			exceptedCode: http.StatusOK,
+		},
+		{
+			name:         "Unknown key",
+			token:        signToken(t, "RS256", "unknown", rsaKey, Claims{"sub": "user-1"}),
+			exceptedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "Algorithm does not match the key",
+			token:        signToken(t, "ES256", "rsa", ecKey, Claims{"sub": "user-1"}),
+			exceptedCode: http.StatusUnauthorized,
+		},
+	}
+
+	rt := wayes.New()
+	rt.Use(JWT(JWTConfig{KeySet: jwks}))
+	rt.Get("/test", func(ctx wayes.Ctx) error {
+		return ctx.Write(ctx.Locals(wayes.PrincipalKey).(Claims).Subject())
+	})
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, "/test", nil)
+			require.NoError(t, err)
+			req.Header.Set("Authorization", "Bearer "+test.token)
+
+			rr := httptest.NewRecorder()
+			rt.Mux().ServeHTTP(rr, req)
+
+			assert.Equal(t, test.exceptedCode, rr.Code)
+		})
+	}
+}
+
+// TestJWT_keys tests the selection of the key by the algorithm when both the secret and the public key are set.
+func TestJWT_keys(t *testing.T) {
+	secret := []byte("test-secret")
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	cases := []struct {
+		name         string
+		token        string
+		exceptedCode int
+	}{
+		{
+			name:         "HS256",
+			token:        signToken(t, "HS256", "", secret, Claims{"sub": "user-1"}),
+			exceptedCode: http.StatusOK,
+		},
+		{
+			name:         "RS256",
+			token:        signToken(t, "RS256", "", rsaKey, Claims{"sub": "user-1"}),
+			exceptedCode: http.StatusOK,
+		},
+		{
+			name:         "Wrong secret",
+			token:        signToken(t, "HS256", "", []byte("other"), Claims{"sub": "user-1"}),
+			exceptedCode: http.StatusUnauthorized,
+		},
+	}
+
+	rt := wayes.New()
+	rt.Use(JWT(JWTConfig{Secret: secret, PublicKey: &rsaKey.PublicKey}))
+	rt.Get("/test", func(ctx wayes.Ctx) error {
+		return ctx.Write(ctx.Locals(wayes.PrincipalKey).(Claims).Subject())
+	})
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, "/test", nil)
+			require.NoError(t, err)
+			req.Header.Set("Authorization", "Bearer "+test.token)
+
+			rr := httptest.NewRecorder()
+			rt.Mux().ServeHTTP(rr, req)
+
+			assert.Equal(t, test.exceptedCode, rr.Code)
+		})
+	}
+}
+
+// TestClaims tests the retrieval of the registered and authorization claims.
+func TestClaims(t *testing.T) {
+	claims := Claims{
+		"sub":   "user-1",
+		"aud":   "api",
+		"roles": []any{"admin", "editor"},
+		"scope": "users:read users:write",
+	}
+
+	assert.Equal(t, "user-1", claims.Subject())
+	assert.Equal(t, []string{"api"}, claims.Audience())
+	assert.Equal(t, []string{"admin", "editor"}, claims.Roles())
+	assert.Equal(t, []string{"users:read", "users:write"}, claims.Permissions())
+
+	claims["permissions"] = []any{"posts:read"}
+	assert.Equal(t, []string{"posts:read"}, claims.Permissions())
+}
@@ -0,0 +1,218 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/eliofery/wayes"
+)
+
+// errMissingKey represents an error indicating that the request does not contain a key.
+var errMissingKey = errors.New("missing key")
+
+// maxFormSize is the maximum size of a urlencoded body read by the form lookup source.
+const maxFormSize = 1 << 20
+
+// tokenErrors is the list of errors whose messages are safe to report in the WWW-Authenticate header.
+var tokenErrors = []error{
+	ErrTokenMalformed,
+	ErrTokenSignature,
+	ErrTokenAlgorithm,
+	ErrTokenExpired,
+	ErrTokenNotValidYet,
+	ErrTokenAudience,
+	ErrTokenIssuer,
+	ErrKeyNotFound,
+}
+
+// KeyAuthConfig represents a structure for the [KeyAuth] middleware configuration.
+type KeyAuthConfig struct {
+	// KeyLookup is a comma-separated list of "<source>:<name>" pairs the key is looked up in,
+	// where the source is one of header, query, cookie or form. Defaults to "header:Authorization".
+	KeyLookup string
+
+	// AuthScheme is the scheme that prefixes the key in the Authorization header. Defaults to "Bearer".
+	AuthScheme string
+
+	// Validator validates the key and returns the principal associated with it.
+	Validator func(ctx wayes.Ctx, key string) (any, error)
+
+	// Realm is the protection space reported in the WWW-Authenticate header. Defaults to "Restricted".
+	Realm string
+
+	// ContextKey is the key under which the principal is stored in [wayes.Ctx.Locals].
+	// Defaults to [wayes.PrincipalKey].
+	ContextKey any
+}
+
+// KeyAuth creates a middleware that authenticates requests by an API key or a bearer token.
+// On success the principal returned by the validator is stored in [wayes.Ctx.Locals], otherwise
+// a [wayes.Error] with 401 Unauthorized and the WWW-Authenticate header is returned.
+func KeyAuth(config KeyAuthConfig) wayes.Handler {
+	if config.Validator == nil {
+		panic("wayes: KeyAuth requires a validator")
+	}
+
+	if config.KeyLookup == "" {
+		config.KeyLookup = "header:Authorization"
+	}
+
+	if config.AuthScheme == "" {
+		config.AuthScheme = "Bearer"
+	}
+
+	if config.Realm == "" {
+		config.Realm = "Restricted"
+	}
+
+	if config.ContextKey == nil {
+		config.ContextKey = wayes.PrincipalKey
+	}
+
+	extract := newExtractor(config.KeyLookup, config.AuthScheme)
+
+	return func(ctx wayes.Ctx) error {
+		key := extract(ctx)
+		if key == "" {
+			return unauthorized(ctx, config.AuthScheme, config.Realm, errMissingKey)
+		}
+
+		principal, err := config.Validator(ctx, key)
+		if err != nil {
+			return unauthorized(ctx, config.AuthScheme, config.Realm, err)
+		}
+
+		if principal == nil {
+			principal = key
+		}
+		ctx.Locals(config.ContextKey, principal)
+
+		return ctx.Next()
+	}
+}
+
+// newExtractor creates a function that returns the first non-empty value from the lookup sources.
+// The scheme prefix is removed from the values of the Authorization header.
+func newExtractor(lookup, scheme string) func(ctx wayes.Ctx) string {
+	type source struct {
+		kind string
+		name string
+	}
+
+	sources := make([]source, 0, 1)
+	for _, part := range strings.Split(lookup, ",") {
+		kind, name, ok := strings.Cut(strings.TrimSpace(part), ":")
+		if !ok || name == "" {
+			panic(fmt.Sprintf("wayes: invalid lookup %q", part))
+		}
+
+		switch kind {
+		case "header", "query", "cookie", "form":
+		default:
+			panic(fmt.Sprintf("wayes: unsupported lookup source %q", kind))
+		}
+
+		sources = append(sources, source{kind: kind, name: strings.TrimSpace(name)})
+	}
+
+	return func(ctx wayes.Ctx) string {
+		r := ctx.Request()
+
+		for _, src := range sources {
+			var value string
+
+			switch src.kind {
+			case "header":
+				value = r.Header.Get(src.name)
+				if strings.EqualFold(src.name, "Authorization") && scheme != "" {
+					prefix, token, ok := strings.Cut(value, " ")
+					if !ok || !strings.EqualFold(prefix, scheme) {
+						continue
+					}
+					value = token
+				}
+			case "query":
+				value = r.URL.Query().Get(src.name)
+			case "cookie":
+				if cookie, err := r.Cookie(src.name); err == nil {
+					value = cookie.Value
+				}
+			case "form":
+				value = formValue(ctx, src.name)
+			}
+
+			if value = strings.TrimSpace(value); value != "" {
+				return value
+			}
+		}
+
+		return ""
+	}
+}
+
+// unauthorized sets the WWW-Authenticate header and returns a [wayes.Error] with 401 Unauthorized.
+// A [wayes.Error] returned by a validator is passed through as is.
+func unauthorized(ctx wayes.Ctx, scheme, realm string, err error) error {
+	challenge := fmt.Sprintf("%s realm=%q", scheme, realm)
+	if !errors.Is(err, errMissingKey) {
+		challenge += fmt.Sprintf(", error=\"invalid_token\", error_description=%q", description(err))
+	}
+	ctx.Set("WWW-Authenticate", challenge)
+
+	var e *wayes.Error
+	if errors.As(err, &e) {
+		return err
+	}
+
+	return wayes.NewError(http.StatusUnauthorized)
+}
+
+// description returns the error description reported to the client.
+// Only the messages of the token errors and of [wayes.Error] are reported, so details of other
+// validator errors do not leak, otherwise "invalid_token" is used.
+func description(err error) string {
+	for _, tokenErr := range tokenErrors {
+		if errors.Is(err, tokenErr) {
+			return tokenErr.Error()
+		}
+	}
+
+	var e *wayes.Error
+	if errors.As(err, &e) {
+		return e.Message
+	}
+
+	return "invalid_token"
+}
+
+// formValue returns the value of the form field of the request.
+// Multipart forms are read by [wayes.Ctx.MultipartForm] with the limits of the router,
+// urlencoded bodies are read up to maxFormSize, other bodies are not read.
+func formValue(ctx wayes.Ctx, name string) string {
+	r := ctx.Request()
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "multipart/form-data":
+		form, err := ctx.MultipartForm()
+		if err != nil || len(form.Value[name]) == 0 {
+			return ""
+		}
+
+		return form.Value[name][0]
+	case "application/x-www-form-urlencoded":
+		if r.PostForm == nil {
+			r.Body = http.MaxBytesReader(ctx.Response(), r.Body, maxFormSize)
+			if err := r.ParseForm(); err != nil {
+				return ""
+			}
+		}
+
+		return r.PostForm.Get(name)
+	}
+
+	return ""
+}
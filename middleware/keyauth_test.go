@@ -0,0 +1,121 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/eliofery/wayes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestKeyAuth tests the authentication by an API key from different sources.
+func TestKeyAuth(t *testing.T) {
+	cases := []struct {
+		name           string
+		prepare        func(req *http.Request)
+		exceptedCode   int
+		exceptedBody   string
+		exceptedHeader string
+	}{
+		{
+			name: "Bearer header",
+			prepare: func(req *http.Request) {
+				req.Header.Set("Authorization", "Bearer valid-key")
+			},
+			exceptedCode: http.StatusOK,
+			exceptedBody: "user-1",
+		},
+		{
+			name: "Query parameter",
+			prepare: func(req *http.Request) {
+				req.URL.RawQuery = "api_key=valid-key"
+			},
+			exceptedCode: http.StatusOK,
+			exceptedBody: "user-1",
+		},
+		{
+			name: "Cookie",
+			prepare: func(req *http.Request) {
+				req.AddCookie(&http.Cookie{Name: "token", Value: "valid-key"})
+			},
+			exceptedCode: http.StatusOK,
+			exceptedBody: "user-1",
+		},
+		{
+			name: "Wrong scheme",
+			prepare: func(req *http.Request) {
+				req.Header.Set("Authorization", "Basic valid-key")
+			},
+			exceptedCode:   http.StatusUnauthorized,
+			exceptedBody:   "Unauthorized\n",
+			exceptedHeader: `Bearer realm="Restricted"`,
+		},
+		{
+			name: "Invalid key",
+			prepare: func(req *http.Request) {
+				req.Header.Set("Authorization", "Bearer invalid-key")
+			},
+			exceptedCode:   http.StatusUnauthorized,
+			exceptedBody:   "Unauthorized\n",
+			exceptedHeader: `Bearer realm="Restricted", error="invalid_token", error_description="invalid_token"`,
+		},
+		{
+			name: "Token error",
+			prepare: func(req *http.Request) {
+				req.Header.Set("Authorization", "Bearer expired-key")
+			},
+			exceptedCode:   http.StatusUnauthorized,
+			exceptedBody:   "Unauthorized\n",
+			exceptedHeader: `Bearer realm="Restricted", error="invalid_token", error_description="token is expired"`,
+		},
+		{
+			name: "Router error",
+			prepare: func(req *http.Request) {
+				req.Header.Set("Authorization", "Bearer revoked-key")
+			},
+			exceptedCode:   http.StatusForbidden,
+			exceptedBody:   "key is revoked\n",
+			exceptedHeader: `Bearer realm="Restricted", error="invalid_token", error_description="key is revoked"`,
+		},
+	}
+
+	rt := wayes.New()
+	rt.Use(KeyAuth(KeyAuthConfig{
+		KeyLookup: "header:Authorization, query:api_key, cookie:token",
+		Validator: func(ctx wayes.Ctx, key string) (any, error) {
+			switch key {
+			case "valid-key":
+			case "expired-key":
+				return nil, fmt.Errorf("lookup key: %w", ErrTokenExpired)
+			case "revoked-key":
+				return nil, wayes.NewError(http.StatusForbidden, "key is revoked")
+			default:
+				return nil, errors.New("unknown key in table api_keys")
+			}
+
+			return "user-1", nil
+		},
+	}))
+	rt.Get("/test", func(ctx wayes.Ctx) error {
+		return ctx.Write(ctx.Locals(wayes.PrincipalKey).(string))
+	})
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, "/test", nil)
+			require.NoError(t, err)
+			test.prepare(req)
+
+			rr := httptest.NewRecorder()
+			rt.Mux().ServeHTTP(rr, req)
+
+			assert.Equal(t, test.exceptedCode, rr.Code)
+			assert.Equal(t, test.exceptedBody, rr.Body.String())
+			assert.Equal(t, test.exceptedHeader, rr.Header().Get("WWW-Authenticate"))
+		})
+	}
+}
@@ -0,0 +1,328 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/eliofery/wayes"
+)
+
+// RateLimitAlgorithm represents the algorithm of the in-memory rate limiter.
+type RateLimitAlgorithm int
+
+const (
+	// TokenBucket allows bursts up to the limit and refills tokens evenly during the window.
+	TokenBucket RateLimitAlgorithm = iota
+
+	// SlidingWindow counts requests in a window that slides with time.
+	SlidingWindow
+)
+
+// gcInterval is the interval between removals of expired keys from the in-memory stores.
+const gcInterval = time.Minute
+
+// RateLimitResult represents a structure for the state of the limiter for a key.
+type RateLimitResult struct {
+	// Allowed indicates whether the request is allowed.
+	Allowed bool
+
+	// Limit is the maximum number of requests in the window.
+	Limit int
+
+	// Remaining is the number of requests left in the current window.
+	Remaining int
+
+	// Reset is the time until the quota is fully restored.
+	Reset time.Duration
+
+	// RetryAfter is the time until the next request is allowed, it is set when the request is denied.
+	RetryAfter time.Duration
+}
+
+// RateLimitStore is an interface that defines methods for storing the state of the limiter.
+// Implementations must be safe for concurrent use.
+type RateLimitStore interface {
+	// Take consumes one request for the key and returns the state of the limiter.
+	Take(key string, limit int, window time.Duration) (RateLimitResult, error)
+}
+
+// RateLimitConfig represents a structure for the [RateLimit] middleware configuration.
+type RateLimitConfig struct {
+	// Max is the maximum number of requests in the window. Defaults to 60.
+	Max int
+
+	// Window is the duration of the window. Defaults to one minute.
+	Window time.Duration
+
+	// Algorithm is the algorithm of the in-memory store. Ignored when Store is set.
+	Algorithm RateLimitAlgorithm
+
+	// KeyFunc returns the key the requests are counted by. Defaults to [KeyByIP].
+	KeyFunc func(ctx wayes.Ctx) string
+
+	// Store holds the state of the limiter. Defaults to an in-memory store of the Algorithm.
+	Store RateLimitStore
+}
+
+// RateLimit creates a middleware that limits the number of requests per key.
+// It sets the RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers and
+// returns a [wayes.Error] with 429 Too Many Requests and the Retry-After header when the limit is exceeded.
+func RateLimit(config ...RateLimitConfig) wayes.Handler {
+	cfg := RateLimitConfig{}
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+
+	if cfg.Max <= 0 {
+		cfg.Max = 60
+	}
+
+	if cfg.Window <= 0 {
+		cfg.Window = time.Minute
+	}
+
+	if cfg.KeyFunc == nil {
+		cfg.KeyFunc = KeyByIP()
+	}
+
+	if cfg.Store == nil {
+		switch cfg.Algorithm {
+		case SlidingWindow:
+			cfg.Store = NewSlidingWindowStore()
+		default:
+			cfg.Store = NewTokenBucketStore()
+		}
+	}
+
+	return func(ctx wayes.Ctx) error {
+		result, err := cfg.Store.Take(cfg.KeyFunc(ctx), cfg.Max, cfg.Window)
+		if err != nil {
+			return err
+		}
+
+		ctx.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+		ctx.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		ctx.Set("RateLimit-Reset", seconds(result.Reset))
+
+		if !result.Allowed {
+			ctx.Set("Retry-After", seconds(result.RetryAfter))
+			return wayes.NewError(http.StatusTooManyRequests)
+		}
+
+		return ctx.Next()
+	}
+}
+
+// KeyByIP returns a key extractor that counts requests by the client IP address.
+// The address is resolved by [wayes.Ctx.IP], so the router trusted proxies are honoured.
+func KeyByIP() func(ctx wayes.Ctx) string {
+	return func(ctx wayes.Ctx) string {
+		return ctx.IP()
+	}
+}
+
+// KeyByHeader returns a key extractor that counts requests by the value of the request header.
+// Requests without the header are counted by the client IP address.
+func KeyByHeader(name string) func(ctx wayes.Ctx) string {
+	byIP := KeyByIP()
+
+	return func(ctx wayes.Ctx) string {
+		if value := ctx.Request().Header.Get(name); value != "" {
+			return "header:" + value
+		}
+
+		return byIP(ctx)
+	}
+}
+
+// KeyByLocals returns a key extractor that counts requests by the value stored in [wayes.Ctx.Locals],
+// for example the identifier of the authenticated user.
+// Requests without the value are counted by the client IP address.
+func KeyByLocals(key any) func(ctx wayes.Ctx) string {
+	byIP := KeyByIP()
+
+	return func(ctx wayes.Ctx) string {
+		if value := ctx.Locals(key); value != nil {
+			return fmt.Sprintf("locals:%v", value)
+		}
+
+		return byIP(ctx)
+	}
+}
+
+// KeyByRoute returns a key extractor that counts requests by the route pattern,
+// so that all clients share the limit of the route.
+func KeyByRoute() func(ctx wayes.Ctx) string {
+	return func(ctx wayes.Ctx) string {
+		return "route:" + ctx.Pattern()
+	}
+}
+
+// seconds formats the duration as a number of whole seconds rounded up.
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
+
+// bucket represents a structure for the state of a token bucket.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// tokenBucketStore represents a structure that implements the [RateLimitStore] interface
+// with the token bucket algorithm.
+type tokenBucketStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	lastGC  time.Time
+	now     func() time.Time
+}
+
+// NewTokenBucketStore creates a new in-memory [RateLimitStore] with the token bucket algorithm.
+func NewTokenBucketStore() RateLimitStore {
+	return &tokenBucketStore{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// Take consumes one token for the key and returns the state of the bucket.
+func (s *tokenBucketStore) Take(key string, limit int, window time.Duration) (RateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.gc(now, window)
+
+	rate := float64(limit) / window.Seconds()
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit), last: now}
+		s.buckets[key] = b
+	}
+
+	b.tokens = math.Min(float64(limit), b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+
+	result := RateLimitResult{Limit: limit}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration((1 - b.tokens) / rate * float64(time.Second))
+	}
+
+	result.Remaining = int(b.tokens)
+	result.Reset = time.Duration((float64(limit) - b.tokens) / rate * float64(time.Second))
+
+	return result, nil
+}
+
+// gc removes the buckets that are refilled completely, the caller must hold the lock.
+func (s *tokenBucketStore) gc(now time.Time, window time.Duration) {
+	if now.Sub(s.lastGC) < gcInterval {
+		return
+	}
+	s.lastGC = now
+
+	for key, b := range s.buckets {
+		if now.Sub(b.last) >= window {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+// counter represents a structure for the state of a sliding window.
+type counter struct {
+	start    time.Time
+	previous int
+	current  int
+}
+
+// slidingWindowStore represents a structure that implements the [RateLimitStore] interface
+// with the sliding window algorithm.
+type slidingWindowStore struct {
+	mu       sync.Mutex
+	counters map[string]*counter
+	lastGC   time.Time
+	now      func() time.Time
+}
+
+// NewSlidingWindowStore creates a new in-memory [RateLimitStore] with the sliding window algorithm.
+func NewSlidingWindowStore() RateLimitStore {
+	return &slidingWindowStore{
+		counters: make(map[string]*counter),
+		now:      time.Now,
+	}
+}
+
+// Take counts one request for the key and returns the state of the window.
+// The number of requests is estimated from the current window and
+// the weighted number of requests of the previous window.
+func (s *slidingWindowStore) Take(key string, limit int, window time.Duration) (RateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.gc(now, window)
+
+	c, ok := s.counters[key]
+	if !ok {
+		c = &counter{start: now.Truncate(window)}
+		s.counters[key] = c
+	}
+
+	switch elapsed := now.Sub(c.start); {
+	case elapsed >= 2*window:
+		c.start = now.Truncate(window)
+		c.previous, c.current = 0, 0
+	case elapsed >= window:
+		c.start = c.start.Add(window)
+		c.previous, c.current = c.current, 0
+	}
+
+	elapsed := now.Sub(c.start)
+	weight := 1 - float64(elapsed)/float64(window)
+	estimated := int(float64(c.previous)*weight) + c.current
+
+	result := RateLimitResult{
+		Limit: limit,
+		Reset: window - elapsed,
+	}
+
+	if estimated < limit {
+		c.current++
+		estimated++
+		result.Allowed = true
+	} else {
+		result.RetryAfter = window - elapsed
+		if c.current < limit && c.previous > 0 {
+			// The estimation decreases as the previous window slides out.
+			free := float64(limit-c.current-1) / float64(c.previous)
+			result.RetryAfter = time.Duration((1-free)*float64(window)) - elapsed
+		}
+	}
+
+	result.Remaining = max(limit-estimated, 0)
+
+	return result, nil
+}
+
+// gc removes the counters without requests in the last two windows, the caller must hold the lock.
+func (s *slidingWindowStore) gc(now time.Time, window time.Duration) {
+	if now.Sub(s.lastGC) < gcInterval {
+		return
+	}
+	s.lastGC = now
+
+	for key, c := range s.counters {
+		if now.Sub(c.start) >= 2*window {
+			delete(s.counters, key)
+		}
+	}
+}
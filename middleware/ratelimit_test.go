@@ -0,0 +1,138 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/eliofery/wayes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRateLimit tests the limiting of the number of requests.
+func TestRateLimit(t *testing.T) {
+	cases := []struct {
+		name      string
+		algorithm RateLimitAlgorithm
+	}{
+		{
+			name:      "Token bucket",
+			algorithm: TokenBucket,
+		},
+		{
+			name:      "Sliding window",
+			algorithm: SlidingWindow,
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			rt := wayes.New()
+			rt.Use(RateLimit(RateLimitConfig{
+				Max:       2,
+				Window:    time.Hour,
+				Algorithm: test.algorithm,
+				KeyFunc:   KeyByHeader("X-API-Key"),
+			}))
+			rt.Get("/test", func(ctx wayes.Ctx) error {
+				return ctx.Write("Test rate limit response")
+			})
+
+			send := func(key string) *httptest.ResponseRecorder {
+				req, err := http.NewRequest(http.MethodGet, "/test", nil)
+				require.NoError(t, err)
+				req.Header.Set("X-API-Key", key)
+
+				rr := httptest.NewRecorder()
+				rt.Mux().ServeHTTP(rr, req)
+
+				return rr
+			}
+
+			for _, remaining := range []string{"1", "0"} {
+				rr := send("foo")
+				assert.Equal(t, http.StatusOK, rr.Code)
+				assert.Equal(t, "2", rr.Header().Get("RateLimit-Limit"))
+				assert.Equal(t, remaining, rr.Header().Get("RateLimit-Remaining"))
+			}
+
+			rr := send("foo")
+			assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+			assert.Equal(t, "0", rr.Header().Get("RateLimit-Remaining"))
+			assert.NotEmpty(t, rr.Header().Get("Retry-After"))
+
+			rr = send("bar")
+			assert.Equal(t, http.StatusOK, rr.Code)
+		})
+	}
+}
+
+// TestTokenBucketStore tests the refilling of tokens and removal of expired keys.
+func TestTokenBucketStore(t *testing.T) {
+	now := time.Now()
+	store := NewTokenBucketStore().(*tokenBucketStore)
+	store.now = func() time.Time { return now }
+
+	for range 4 {
+		result, err := store.Take("foo", 4, time.Minute)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+	}
+
+	result, err := store.Take("foo", 4, time.Minute)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 15*time.Second, result.RetryAfter)
+	assert.Equal(t, time.Minute, result.Reset)
+
+	now = now.Add(15 * time.Second)
+	result, err = store.Take("foo", 4, time.Minute)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+
+	now = now.Add(2 * gcInterval)
+	_, err = store.Take("bar", 4, time.Minute)
+	require.NoError(t, err)
+	assert.NotContains(t, store.buckets, "foo")
+	assert.Contains(t, store.buckets, "bar")
+}
+
+// TestSlidingWindowStore tests the weighting of the previous window and removal of expired keys.
+func TestSlidingWindowStore(t *testing.T) {
+	now := time.Now().Truncate(time.Minute)
+	store := NewSlidingWindowStore().(*slidingWindowStore)
+	store.now = func() time.Time { return now }
+
+	for range 4 {
+		result, err := store.Take("foo", 4, time.Minute)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+	}
+
+	result, err := store.Take("foo", 4, time.Minute)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, time.Minute, result.RetryAfter)
+
+	// A half of the previous window is taken into account.
+	now = now.Add(90 * time.Second)
+	for range 2 {
+		result, err = store.Take("foo", 4, time.Minute)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+	}
+
+	result, err = store.Take("foo", 4, time.Minute)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+	assert.Equal(t, 15*time.Second, result.RetryAfter)
+
+	now = now.Add(2 * gcInterval)
+	_, err = store.Take("bar", 4, time.Minute)
+	require.NoError(t, err)
+	assert.NotContains(t, store.counters, "foo")
+}
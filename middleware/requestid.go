@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/eliofery/wayes"
+)
+
+// maxRequestIDLength is the maximum length of an incoming request identifier that is accepted as is.
+const maxRequestIDLength = 128
+
+// RequestIDConfig represents a structure for the [RequestID] middleware configuration.
+type RequestIDConfig struct {
+	// Header is the name of the header that carries the request identifier.
+	// Defaults to [wayes.HeaderRequestID].
+	Header string
+
+	// Generator returns a new request identifier when the request does not contain one.
+	// Defaults to a random UUID version 4.
+	Generator func() string
+}
+
+// RequestID creates a middleware that propagates a request identifier.
+// It reads the identifier from the incoming request header or generates a new one,
+// sets it on the response and stores it in the request context.
+// The identifier can be retrieved with [wayes.RequestIDFrom].
+func RequestID(config ...RequestIDConfig) wayes.Handler {
+	cfg := RequestIDConfig{}
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+
+	if cfg.Header == "" {
+		cfg.Header = wayes.HeaderRequestID
+	}
+
+	if cfg.Generator == nil {
+		cfg.Generator = uuid
+	}
+
+	return func(ctx wayes.Ctx) error {
+		id := ctx.Request().Header.Get(cfg.Header)
+		if !validRequestID(id) {
+			id = cfg.Generator()
+		}
+
+		ctx.Set(cfg.Header, id)
+		ctx.Locals(wayes.RequestIDKey, id)
+
+		return ctx.Next()
+	}
+}
+
+// validRequestID reports whether the incoming request identifier can be reused.
+// Only non-empty printable ASCII identifiers of a reasonable length are accepted.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+
+	return true
+}
+
+// uuid generates a random UUID version 4.
+func uuid() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return ""
+	}
+
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	var buf [36]byte
+	hex.Encode(buf[0:8], b[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], b[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], b[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], b[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], b[10:])
+
+	return string(buf[:])
+}
@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/eliofery/wayes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRequestID tests the propagation of the request identifier.
+func TestRequestID(t *testing.T) {
+	cases := []struct {
+		name         string
+		config       []RequestIDConfig
+		header       string
+		requestID    string
+		exceptedID   string
+		exceptedUUID bool
+	}{
+		{
+			name:         "Generated identifier",
+			header:       wayes.HeaderRequestID,
+			exceptedUUID: true,
+		},
+		{
+			name:       "Incoming identifier",
+			header:     wayes.HeaderRequestID,
+			requestID:  "incoming-id",
+			exceptedID: "incoming-id",
+		},
+		{
+			name:         "Invalid incoming identifier",
+			header:       wayes.HeaderRequestID,
+			requestID:    "bad id",
+			exceptedUUID: true,
+		},
+		{
+			name:       "Custom header",
+			config:     []RequestIDConfig{{Header: "X-Correlation-ID"}},
+			header:     "X-Correlation-ID",
+			requestID:  "correlation-id",
+			exceptedID: "correlation-id",
+		},
+		{
+			name:       "Custom generator",
+			config:     []RequestIDConfig{{Generator: func() string { return "generated-id" }}},
+			header:     wayes.HeaderRequestID,
+			exceptedID: "generated-id",
+		},
+	}
+
+	uuidPattern := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			rt := wayes.New()
+			rt.Use(RequestID(test.config...))
+			rt.Get("/test", func(ctx wayes.Ctx) error {
+				return ctx.Write(wayes.RequestIDFrom(ctx.Request().Context()))
+			})
+
+			req, err := http.NewRequest(http.MethodGet, "/test", nil)
+			require.NoError(t, err)
+			if test.requestID != "" {
+				req.Header.Set(test.header, test.requestID)
+			}
+
+			rr := httptest.NewRecorder()
+			rt.Mux().ServeHTTP(rr, req)
+
+			id := rr.Header().Get(test.header)
+			if test.exceptedUUID {
+				assert.Regexp(t, uuidPattern, id)
+			} else {
+				assert.Equal(t, test.exceptedID, id)
+			}
+			assert.Equal(t, id, rr.Body.String())
+		})
+	}
+}
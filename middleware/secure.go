@@ -0,0 +1,174 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eliofery/wayes"
+)
+
+// NoncePlaceholder is replaced with the per-request nonce in the Content-Security-Policy of the [Secure] middleware.
+const NoncePlaceholder = "{nonce}"
+
+// SecureConfig represents a structure for the [Secure] middleware configuration.
+// Empty fields disable the corresponding headers.
+type SecureConfig struct {
+	// HSTSMaxAge is the max-age of the Strict-Transport-Security header, which is sent over HTTPS only.
+	// The scheme is resolved by [wayes.Ctx.Scheme], so HTTPS terminated by a trusted proxy is detected.
+	HSTSMaxAge time.Duration
+
+	// HSTSIncludeSubdomains applies the Strict-Transport-Security header to the subdomains.
+	HSTSIncludeSubdomains bool
+
+	// HSTSPreload allows the domain to be included in the HSTS preload lists of browsers.
+	HSTSPreload bool
+
+	// ContentTypeNosniff sets the X-Content-Type-Options header to nosniff.
+	ContentTypeNosniff bool
+
+	// FrameOptions is the value of the X-Frame-Options header.
+	FrameOptions string
+
+	// ReferrerPolicy is the value of the Referrer-Policy header.
+	ReferrerPolicy string
+
+	// PermissionsPolicy is the value of the Permissions-Policy header.
+	PermissionsPolicy string
+
+	// CrossOriginOpenerPolicy is the value of the Cross-Origin-Opener-Policy header.
+	CrossOriginOpenerPolicy string
+
+	// CrossOriginEmbedderPolicy is the value of the Cross-Origin-Embedder-Policy header.
+	CrossOriginEmbedderPolicy string
+
+	// CrossOriginResourcePolicy is the value of the Cross-Origin-Resource-Policy header.
+	CrossOriginResourcePolicy string
+
+	// ContentSecurityPolicy is the value of the Content-Security-Policy header.
+	// Every [NoncePlaceholder] is replaced with a random nonce generated for the request.
+	ContentSecurityPolicy string
+
+	// CSPReportOnly sends the policy in the Content-Security-Policy-Report-Only header instead.
+	CSPReportOnly bool
+
+	// ContextKey is the key under which the nonce is stored in [wayes.Ctx.Locals].
+	// Defaults to [wayes.CSPNonceKey].
+	ContextKey any
+}
+
+// DefaultSecureConfig is the configuration used by the [Secure] middleware when no configuration is provided.
+// Copy and modify it to override single headers.
+var DefaultSecureConfig = SecureConfig{
+	HSTSMaxAge:                365 * 24 * time.Hour,
+	HSTSIncludeSubdomains:     true,
+	ContentTypeNosniff:        true,
+	FrameOptions:              "DENY",
+	ReferrerPolicy:            "no-referrer",
+	PermissionsPolicy:         "camera=(), microphone=(), geolocation=()",
+	CrossOriginOpenerPolicy:   "same-origin",
+	CrossOriginResourcePolicy: "same-origin",
+	ContentSecurityPolicy: "default-src 'self'; base-uri 'self'; object-src 'none'; frame-ancestors 'none'; " +
+		"script-src 'self' 'nonce-" + NoncePlaceholder + "'; style-src 'self' 'nonce-" + NoncePlaceholder + "'",
+}
+
+// Secure creates a middleware that sets security headers such as Strict-Transport-Security,
+// X-Content-Type-Options, X-Frame-Options and Content-Security-Policy.
+// Every header managed by the middleware is set or removed, so the middleware registered for
+// a route group overrides the one registered for the router.
+// If the policy contains [NoncePlaceholder], the nonce is stored in [wayes.Ctx.Locals] for templates.
+func Secure(config ...SecureConfig) wayes.Handler {
+	cfg := DefaultSecureConfig
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+
+	if cfg.ContextKey == nil {
+		cfg.ContextKey = wayes.CSPNonceKey
+	}
+
+	var hsts string
+	if cfg.HSTSMaxAge > 0 {
+		hsts = "max-age=" + strconv.FormatInt(int64(cfg.HSTSMaxAge/time.Second), 10)
+		if cfg.HSTSIncludeSubdomains {
+			hsts += "; includeSubDomains"
+		}
+		if cfg.HSTSPreload {
+			hsts += "; preload"
+		}
+	}
+
+	var nosniff string
+	if cfg.ContentTypeNosniff {
+		nosniff = "nosniff"
+	}
+
+	cspHeader, otherCSPHeader := "Content-Security-Policy", "Content-Security-Policy-Report-Only"
+	if cfg.CSPReportOnly {
+		cspHeader, otherCSPHeader = otherCSPHeader, cspHeader
+	}
+
+	headers := []struct {
+		name  string
+		value string
+	}{
+		{"X-Content-Type-Options", nosniff},
+		{"X-Frame-Options", cfg.FrameOptions},
+		{"Referrer-Policy", cfg.ReferrerPolicy},
+		{"Permissions-Policy", cfg.PermissionsPolicy},
+		{"Cross-Origin-Opener-Policy", cfg.CrossOriginOpenerPolicy},
+		{"Cross-Origin-Embedder-Policy", cfg.CrossOriginEmbedderPolicy},
+		{"Cross-Origin-Resource-Policy", cfg.CrossOriginResourcePolicy},
+	}
+
+	return func(ctx wayes.Ctx) error {
+		header := ctx.Response().Header()
+
+		for _, h := range headers {
+			setOrDelete(header, h.name, h.value)
+		}
+
+		if ctx.Scheme() == "https" {
+			setOrDelete(header, "Strict-Transport-Security", hsts)
+		}
+
+		policy := cfg.ContentSecurityPolicy
+		if strings.Contains(policy, NoncePlaceholder) {
+			nonce, err := newNonce()
+			if err != nil {
+				return err
+			}
+
+			policy = strings.ReplaceAll(policy, NoncePlaceholder, nonce)
+			ctx.Locals(cfg.ContextKey, nonce)
+		}
+
+		header.Del(otherCSPHeader)
+		setOrDelete(header, cspHeader, policy)
+
+		return ctx.Next()
+	}
+}
+
+// setOrDelete sets the header to the value or removes it if the value is empty.
+func setOrDelete(header http.Header, name, value string) {
+	if value == "" {
+		header.Del(name)
+		return
+	}
+
+	header.Set(name, value)
+}
+
+// newNonce returns a random nonce for the Content-Security-Policy.
+func newNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(b), nil
+}
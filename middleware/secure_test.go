@@ -0,0 +1,116 @@
+package middleware
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/eliofery/wayes"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestSecure tests the security headers set by the middleware.
+func TestSecure(t *testing.T) {
+	rt := wayes.New()
+	rt.Use(Secure())
+	rt.Get("/test", func(ctx wayes.Ctx) error {
+		return ctx.Write(ctx.Locals(wayes.CSPNonceKey).(string))
+	})
+
+	embed := rt.Group("/embed")
+	{
+		cfg := DefaultSecureConfig
+		cfg.FrameOptions = ""
+		cfg.ContentSecurityPolicy = "frame-ancestors https://example.com"
+		cfg.CSPReportOnly = true
+		embed.Use(Secure(cfg))
+
+		embed.Get("/widget", func(ctx wayes.Ctx) error {
+			return ctx.Write("Test secure response")
+		})
+	}
+
+	cases := []struct {
+		name            string
+		path            string
+		https           bool
+		exceptedHeaders map[string]string
+	}{
+		{
+			name: "Default headers",
+			path: "/test",
+			exceptedHeaders: map[string]string{
+				"Strict-Transport-Security":           "",
+				"X-Content-Type-Options":              "nosniff",
+				"X-Frame-Options":                     "DENY",
+				"Referrer-Policy":                     "no-referrer",
+				"Permissions-Policy":                  "camera=(), microphone=(), geolocation=()",
+				"Cross-Origin-Opener-Policy":          "same-origin",
+				"Cross-Origin-Embedder-Policy":        "",
+				"Cross-Origin-Resource-Policy":        "same-origin",
+				"Content-Security-Policy-Report-Only": "",
+			},
+		},
+		{
+			name:  "HTTPS request",
+			path:  "/test",
+			https: true,
+			exceptedHeaders: map[string]string{
+				"Strict-Transport-Security": "max-age=31536000; includeSubDomains",
+			},
+		},
+		{
+			name: "Group override",
+			path: "/embed/widget",
+			exceptedHeaders: map[string]string{
+				"X-Frame-Options":                     "",
+				"X-Content-Type-Options":              "nosniff",
+				"Content-Security-Policy":             "",
+				"Content-Security-Policy-Report-Only": "frame-ancestors https://example.com",
+			},
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, test.path, nil)
+			if test.https {
+				req.TLS = &tls.ConnectionState{}
+			}
+
+			rr := httptest.NewRecorder()
+			rt.Mux().ServeHTTP(rr, req)
+
+			assert.Equal(t, http.StatusOK, rr.Code)
+			for key, value := range test.exceptedHeaders {
+				assert.Equal(t, value, rr.Header().Get(key), key)
+			}
+		})
+	}
+}
+
+// TestSecure_nonce tests the generation of the Content-Security-Policy nonce for every request.
+func TestSecure_nonce(t *testing.T) {
+	rt := wayes.New()
+	rt.Use(Secure())
+	rt.Get("/test", func(ctx wayes.Ctx) error {
+		return ctx.Write(ctx.Locals(wayes.CSPNonceKey).(string))
+	})
+
+	nonces := make(map[string]bool)
+	for range 3 {
+		rr := httptest.NewRecorder()
+		rt.Mux().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/test", nil))
+
+		nonce := rr.Body.String()
+		assert.NotEmpty(t, nonce)
+		assert.False(t, nonces[nonce], "nonce must be unique")
+		nonces[nonce] = true
+
+		csp := rr.Header().Get("Content-Security-Policy")
+		assert.Equal(t, 2, strings.Count(csp, "'nonce-"+nonce+"'"))
+		assert.NotContains(t, csp, NoncePlaceholder)
+	}
+}
@@ -0,0 +1,266 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/eliofery/wayes"
+)
+
+// TimeoutConfig represents a structure for the [Timeout] middleware configuration.
+type TimeoutConfig struct {
+	// StatusCode is the status code returned when the deadline passes.
+	// Defaults to 503 Service Unavailable, 504 Gateway Timeout is a common alternative.
+	StatusCode int
+
+	// Message is the error message returned when the deadline passes.
+	// Defaults to the status text of StatusCode.
+	Message string
+}
+
+// Timeout creates a middleware that limits the time of the request handling.
+// The next handlers run with a request context that is cancelled after the timeout.
+// Their response is buffered and sent only if it is completed in time,
+// otherwise the router error handler receives a [wayes.Error] with the configured status code
+// and late writes of the handlers fail with [http.ErrHandlerTimeout].
+//
+// Flushing or hijacking the response commits it, so streaming and WebSocket handlers work under
+// the middleware. A committed response can no longer be replaced, the deadline only cancels
+// the request context and the middleware waits for the handlers to stop.
+// Requests cancelled by the client are not reported to the error handler.
+func Timeout(timeout time.Duration, config ...TimeoutConfig) wayes.Handler {
+	cfg := TimeoutConfig{}
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+
+	if cfg.StatusCode == 0 {
+		cfg.StatusCode = http.StatusServiceUnavailable
+	}
+
+	return func(ctx wayes.Ctx) error {
+		timeoutCtx, cancel := context.WithTimeout(ctx.Request().Context(), timeout)
+		defer cancel()
+
+		tw := &timeoutWriter{
+			w:      ctx.Response(),
+			header: ctx.Response().Header().Clone(),
+		}
+
+		worker := ctx.Copy()
+		worker.SetRequest(ctx.Request().WithContext(timeoutCtx))
+		worker.SetResponse(tw)
+
+		done := make(chan error, 1)
+		panicked := make(chan any, 1)
+		go func() {
+			defer func() {
+				if p := recover(); p != nil {
+					panicked <- p
+				}
+			}()
+
+			done <- worker.Next()
+		}()
+
+		select {
+		case p := <-panicked:
+			panic(p)
+		case err := <-done:
+			tw.flush()
+			return tw.result(ctx, err)
+		case <-timeoutCtx.Done():
+			if tw.timeout() {
+				select {
+				case p := <-panicked:
+					panic(p)
+				case err := <-done:
+					return tw.result(ctx, err)
+				}
+			}
+
+			if errors.Is(timeoutCtx.Err(), context.DeadlineExceeded) {
+				return wayes.NewError(cfg.StatusCode, cfg.Message)
+			}
+
+			return nil
+		}
+	}
+}
+
+// timeoutWriter represents a structure that buffers the response until the handler completes.
+type timeoutWriter struct {
+	w      http.ResponseWriter
+	header http.Header
+	buf    bytes.Buffer
+
+	mu          sync.Mutex
+	code        int
+	wroteHeader bool
+	timedOut    bool
+	committed   bool
+	hijacked    bool
+}
+
+// Header returns the buffered header map.
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+// Write writes the data to the buffer.
+func (tw *timeoutWriter) Write(p []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+
+	if !tw.wroteHeader {
+		tw.writeHeaderLocked(http.StatusOK)
+	}
+
+	if tw.committed {
+		return tw.w.Write(p)
+	}
+
+	return tw.buf.Write(p)
+}
+
+// WriteHeader records the status code of the response.
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.timedOut || tw.wroteHeader || tw.committed {
+		return
+	}
+
+	tw.writeHeaderLocked(code)
+}
+
+// writeHeaderLocked records the status code, the caller must hold the lock.
+func (tw *timeoutWriter) writeHeaderLocked(code int) {
+	tw.wroteHeader = true
+	tw.code = code
+}
+
+// Flush commits the buffered response and flushes it to the client.
+// Later writes go directly to the underlying writer.
+func (tw *timeoutWriter) Flush() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.timedOut {
+		return
+	}
+
+	if !tw.wroteHeader {
+		tw.writeHeaderLocked(http.StatusOK)
+	}
+
+	tw.commitLocked()
+	_ = http.NewResponseController(tw.w).Flush()
+}
+
+// Hijack takes over the connection of the underlying writer, the buffered response is discarded.
+func (tw *timeoutWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.timedOut {
+		return nil, nil, http.ErrHandlerTimeout
+	}
+
+	conn, rw, err := http.NewResponseController(tw.w).Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tw.buf.Reset()
+	tw.committed = true
+	tw.hijacked = true
+
+	return conn, rw, nil
+}
+
+// Unwrap returns the underlying writer, used by [http.ResponseController].
+func (tw *timeoutWriter) Unwrap() http.ResponseWriter {
+	return tw.w
+}
+
+// flush sends the buffered response to the underlying writer.
+func (tw *timeoutWriter) flush() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if !tw.committed {
+		tw.sendLocked()
+	}
+}
+
+// commitLocked sends the buffered response and switches the writer to the direct writes,
+// the caller must hold the lock.
+func (tw *timeoutWriter) commitLocked() {
+	if !tw.committed {
+		tw.sendLocked()
+		tw.committed = true
+	}
+}
+
+// sendLocked sends the buffered header and data to the underlying writer, the caller must hold the lock.
+func (tw *timeoutWriter) sendLocked() {
+	dst := tw.w.Header()
+	for key, values := range tw.header {
+		dst[key] = values
+	}
+
+	if tw.wroteHeader {
+		tw.w.WriteHeader(tw.code)
+	}
+
+	if tw.buf.Len() > 0 {
+		_, _ = tw.w.Write(tw.buf.Bytes())
+		tw.buf.Reset()
+	}
+}
+
+// timeout marks the response as timed out, so later writes of the handler are rejected.
+// It reports whether the response was already committed, in which case it is left as is.
+func (tw *timeoutWriter) timeout() bool {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.committed {
+		return true
+	}
+
+	tw.timedOut = true
+
+	return false
+}
+
+// result returns the error of the handlers for the error handler.
+// Errors of hijacked connections, cancelled requests and streams cut by the deadline are dropped,
+// since no response can be sent for them.
+func (tw *timeoutWriter) result(ctx wayes.Ctx, err error) error {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	switch {
+	case err == nil, tw.hijacked:
+		return nil
+	case ctx.Request().Context().Err() != nil && errors.Is(err, context.Canceled):
+		return nil
+	case tw.committed && (errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)):
+		return nil
+	}
+
+	return err
+}
@@ -0,0 +1,134 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/eliofery/wayes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestTimeout tests the limiting of the request handling time.
+func TestTimeout(t *testing.T) {
+	lateWrite := make(chan error, 1)
+
+	cases := []struct {
+		name         string
+		config       []TimeoutConfig
+		handler      wayes.Handler
+		exceptedCode int
+		exceptedBody string
+	}{
+		{
+			name: "Completed in time",
+			handler: func(ctx wayes.Ctx) error {
+				ctx.Set("X-Test", "true")
+				return ctx.Status(http.StatusCreated).Write("Test timeout response")
+			},
+			exceptedCode: http.StatusCreated,
+			exceptedBody: "Test timeout response",
+		},
+		{
+			name: "Deadline exceeded",
+			handler: func(ctx wayes.Ctx) error {
+				<-ctx.Request().Context().Done()
+				time.Sleep(10 * time.Millisecond)
+
+				_, err := ctx.Response().Write([]byte("late response"))
+				lateWrite <- err
+
+				return err
+			},
+			exceptedCode: http.StatusServiceUnavailable,
+			exceptedBody: "Service Unavailable\n",
+		},
+		{
+			name:   "Custom status code",
+			config: []TimeoutConfig{{StatusCode: http.StatusGatewayTimeout, Message: "too slow"}},
+			handler: func(ctx wayes.Ctx) error {
+				<-ctx.Request().Context().Done()
+				return ctx.Request().Context().Err()
+			},
+			exceptedCode: http.StatusGatewayTimeout,
+			exceptedBody: "too slow\n",
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			rt := wayes.New()
+			rt.Use(Timeout(20*time.Millisecond, test.config...))
+			rt.Get("/test", test.handler)
+
+			req, err := http.NewRequest(http.MethodGet, "/test", nil)
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			rt.Mux().ServeHTTP(rr, req)
+
+			assert.Equal(t, test.exceptedCode, rr.Code)
+			assert.Equal(t, test.exceptedBody, rr.Body.String())
+		})
+	}
+
+	assert.ErrorIs(t, <-lateWrite, http.ErrHandlerTimeout)
+}
+
+// TestTimeout_stream tests that a flushed response is streamed and cut by the deadline without an error response.
+func TestTimeout_stream(t *testing.T) {
+	var handled error
+
+	rt := wayes.New()
+	rt.SetErrorHandler(func(ctx wayes.Ctx, err error) { handled = err })
+	rt.Use(Timeout(20 * time.Millisecond))
+	rt.Get("/test", func(ctx wayes.Ctx) error {
+		return ctx.StreamJSON(func(enc *wayes.StreamEncoder) error {
+			for i := 0; ; i++ {
+				if err := enc.Encode(i); err != nil {
+					return err
+				}
+				time.Sleep(time.Millisecond)
+			}
+		}, wayes.StreamJSONConfig{FlushInterval: -1})
+	})
+
+	req, err := http.NewRequest(http.MethodGet, "/test", nil)
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	rt.Mux().ServeHTTP(rr, req)
+
+	assert.NoError(t, handled)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.True(t, rr.Flushed)
+	assert.Contains(t, rr.Body.String(), "0\n1\n")
+}
+
+// TestTimeout_cancelled tests that a request cancelled by the client is not reported to the error handler.
+func TestTimeout_cancelled(t *testing.T) {
+	var handled error
+
+	rt := wayes.New()
+	rt.SetErrorHandler(func(ctx wayes.Ctx, err error) { handled = err })
+	rt.Use(Timeout(time.Second))
+	rt.Get("/test", func(ctx wayes.Ctx) error {
+		<-ctx.Request().Context().Done()
+		return ctx.Request().Context().Err()
+	})
+
+	reqCtx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, "/test", nil)
+	require.NoError(t, err)
+
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	rr := httptest.NewRecorder()
+	rt.Mux().ServeHTTP(rr, req)
+
+	assert.NoError(t, handled)
+	assert.Empty(t, rr.Body.String())
+}
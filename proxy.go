@@ -0,0 +1,293 @@
+package wayes
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+)
+
+// forwarded represents a structure for an element of the Forwarded header (RFC 7239).
+type forwarded struct {
+	forAddr string
+	proto   string
+	host    string
+}
+
+// parseForwarded parses the values of the Forwarded header into elements in the order of the hops.
+func parseForwarded(values []string) []forwarded {
+	var elements []forwarded
+	for _, value := range values {
+		for _, part := range splitQuoted(value, ',') {
+			var element forwarded
+			for _, pair := range splitQuoted(part, ';') {
+				key, val, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if !ok {
+					continue
+				}
+
+				val = strings.Trim(strings.TrimSpace(val), `"`)
+				switch strings.ToLower(strings.TrimSpace(key)) {
+				case "for":
+					element.forAddr = val
+				case "proto":
+					element.proto = strings.ToLower(val)
+				case "host":
+					element.host = val
+				}
+			}
+			elements = append(elements, element)
+		}
+	}
+
+	return elements
+}
+
+// splitQuoted splits the value by the separator outside of quoted strings.
+func splitQuoted(value string, sep byte) []string {
+	var parts []string
+
+	quoted, start := false, 0
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '"':
+			quoted = !quoted
+		case sep:
+			if !quoted {
+				parts = append(parts, value[start:i])
+				start = i + 1
+			}
+		}
+	}
+
+	return append(parts, value[start:])
+}
+
+// parseAddr parses an IP address optionally enclosed in brackets and followed by a port.
+func parseAddr(value string) (netip.Addr, bool) {
+	value = strings.TrimSpace(value)
+	if host, _, err := net.SplitHostPort(value); err == nil {
+		value = host
+	}
+
+	addr, err := netip.ParseAddr(strings.Trim(value, "[]"))
+	if err != nil {
+		return netip.Addr{}, false
+	}
+
+	return addr.Unmap(), true
+}
+
+// ParsePrefixes parses CIDR ranges and single IP addresses, the addresses become single-address ranges.
+// IPv4-mapped IPv6 addresses are converted to IPv4.
+func ParsePrefixes(values []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(values))
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+
+		if !strings.Contains(value, "/") {
+			addr, err := netip.ParseAddr(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid IP address %q: %w", value, err)
+			}
+			addr = addr.Unmap()
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q: %w", value, err)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+
+	return prefixes, nil
+}
+
+// trusted reports whether the address belongs to a trusted proxy.
+func (s *settings) trusted(addr netip.Addr) bool {
+	for _, prefix := range s.trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// remoteAddr returns the address of the peer that sent the request.
+func (c *ctx) remoteAddr() (netip.Addr, bool) {
+	return parseAddr(c.request.RemoteAddr)
+}
+
+// fromTrustedProxy reports whether the request was sent by a trusted proxy.
+func (c *ctx) fromTrustedProxy() bool {
+	remote, ok := c.remoteAddr()
+
+	return ok && c.settings.trusted(remote)
+}
+
+// forwardedFor returns the client addresses from the Forwarded or X-Forwarded-For headers in the order of the hops.
+func (c *ctx) forwardedFor() []string {
+	if values := c.request.Header.Values("Forwarded"); len(values) > 0 {
+		var chain []string
+		for _, element := range parseForwarded(values) {
+			chain = append(chain, element.forAddr)
+		}
+
+		return chain
+	}
+
+	var chain []string
+	for _, value := range c.request.Header.Values("X-Forwarded-For") {
+		for _, addr := range strings.Split(value, ",") {
+			chain = append(chain, strings.TrimSpace(addr))
+		}
+	}
+
+	return chain
+}
+
+// IP returns the address of the client.
+// If the request is sent by a trusted proxy, the forwarding headers are walked from the nearest hop
+// and the first address that is not a trusted proxy is returned.
+func (c *ctx) IP() string {
+	remote, ok := c.remoteAddr()
+	if !ok {
+		return c.request.RemoteAddr
+	}
+
+	if !c.settings.trusted(remote) {
+		return remote.String()
+	}
+
+	chain := c.forwardedFor()
+	if len(chain) == 0 {
+		if addr, ok := parseAddr(c.request.Header.Get("X-Real-IP")); ok {
+			return addr.String()
+		}
+
+		return remote.String()
+	}
+
+	client := remote
+	for i := len(chain) - 1; i >= 0; i-- {
+		addr, ok := parseAddr(chain[i])
+		if !ok {
+			break
+		}
+
+		client = addr
+		if !c.settings.trusted(addr) {
+			break
+		}
+	}
+
+	return client.String()
+}
+
+// IPs returns the addresses of the client and the proxies from the forwarding headers followed by
+// the address of the peer. The forwarding headers are used only if the request is sent by a trusted proxy.
+func (c *ctx) IPs() []string {
+	remote, ok := c.remoteAddr()
+	if !ok {
+		return []string{c.request.RemoteAddr}
+	}
+
+	if !c.settings.trusted(remote) {
+		return []string{remote.String()}
+	}
+
+	var ips []string
+	for _, value := range c.forwardedFor() {
+		if addr, ok := parseAddr(value); ok {
+			ips = append(ips, addr.String())
+		}
+	}
+
+	return append(ips, remote.String())
+}
+
+// trustedHops returns the number of the nearest hops of the chain that are trusted proxies.
+// The hop that precedes them is the client, the first hop is the client if all hops are trusted.
+func (c *ctx) trustedHops(chain []string) int {
+	hops := 0
+	for i := len(chain) - 1; i > 0; i-- {
+		addr, ok := parseAddr(chain[i])
+		if !ok || !c.settings.trusted(addr) {
+			break
+		}
+		hops++
+	}
+
+	return hops
+}
+
+// forwardedValue returns the value recorded by the trusted proxy that received the request from the client.
+// The elements of the Forwarded header or the values of the X-Forwarded header are aligned with the hops
+// from the nearest one, so values supplied by the client are skipped.
+func (c *ctx) forwardedValue(header string, field func(element forwarded) string) string {
+	hops := c.trustedHops(c.forwardedFor())
+
+	if values := c.request.Header.Values("Forwarded"); len(values) > 0 {
+		elements := parseForwarded(values)
+		return field(elements[len(elements)-1-hops])
+	}
+
+	var list []string
+	for _, value := range c.request.Header.Values(header) {
+		for _, item := range strings.Split(value, ",") {
+			list = append(list, strings.TrimSpace(item))
+		}
+	}
+
+	if len(list) == 0 {
+		return ""
+	}
+
+	return list[max(len(list)-1-hops, 0)]
+}
+
+// Scheme returns the scheme of the request, "http" or "https".
+// The Forwarded and X-Forwarded-Proto headers are used only if the request is sent by a trusted proxy,
+// the value recorded by the proxy that received the request from the client is taken.
+func (c *ctx) Scheme() string {
+	if c.fromTrustedProxy() {
+		proto := c.forwardedValue("X-Forwarded-Proto", func(element forwarded) string { return element.proto })
+		if proto = strings.ToLower(proto); proto == "http" || proto == "https" {
+			return proto
+		}
+	}
+
+	if c.request.TLS != nil {
+		return "https"
+	}
+
+	return "http"
+}
+
+// Hostname returns the host of the request without the port.
+// The Forwarded and X-Forwarded-Host headers are used only if the request is sent by a trusted proxy,
+// the value recorded by the proxy that received the request from the client is taken.
+func (c *ctx) Hostname() string {
+	host := c.request.Host
+
+	if c.fromTrustedProxy() {
+		forwardedHost := c.forwardedValue("X-Forwarded-Host", func(element forwarded) string { return element.host })
+		if forwardedHost != "" {
+			host = forwardedHost
+		}
+	}
+
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		return strings.Trim(hostname, "[]")
+	}
+
+	return strings.Trim(host, "[]")
+}
+
+// Protocol returns the protocol version of the request, for example "HTTP/1.1" or "HTTP/2.0".
+func (c *ctx) Protocol() string {
+	return c.request.Proto
+}
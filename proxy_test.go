@@ -0,0 +1,225 @@
+package wayes
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCtxIP tests the resolution of the client address with trusted proxies.
+func TestCtxIP(t *testing.T) {
+	cases := []struct {
+		name        string
+		remoteAddr  string
+		headers     map[string]string
+		exceptedIP  string
+		exceptedIPs []string
+	}{
+		{
+			name:        "Direct request",
+			remoteAddr:  "203.0.113.7:1234",
+			exceptedIP:  "203.0.113.7",
+			exceptedIPs: []string{"203.0.113.7"},
+		},
+		{
+			name:        "Untrusted proxy",
+			remoteAddr:  "203.0.113.7:1234",
+			headers:     map[string]string{"X-Forwarded-For": "198.51.100.1"},
+			exceptedIP:  "203.0.113.7",
+			exceptedIPs: []string{"203.0.113.7"},
+		},
+		{
+			name:        "X-Forwarded-For",
+			remoteAddr:  "10.0.0.1:1234",
+			headers:     map[string]string{"X-Forwarded-For": "198.51.100.9, 198.51.100.1, 10.0.0.2"},
+			exceptedIP:  "198.51.100.1",
+			exceptedIPs: []string{"198.51.100.9", "198.51.100.1", "10.0.0.2", "10.0.0.1"},
+		},
+		{
+			name:        "Only trusted hops",
+			remoteAddr:  "10.0.0.1:1234",
+			headers:     map[string]string{"X-Forwarded-For": "10.0.0.3, 10.0.0.2"},
+			exceptedIP:  "10.0.0.3",
+			exceptedIPs: []string{"10.0.0.3", "10.0.0.2", "10.0.0.1"},
+		},
+		{
+			name:        "Invalid hop",
+			remoteAddr:  "10.0.0.1:1234",
+			headers:     map[string]string{"X-Forwarded-For": "198.51.100.9, garbage, 10.0.0.2"},
+			exceptedIP:  "10.0.0.2",
+			exceptedIPs: []string{"198.51.100.9", "10.0.0.2", "10.0.0.1"},
+		},
+		{
+			name:       "Forwarded",
+			remoteAddr: "[2001:db8::1]:443",
+			headers: map[string]string{
+				"Forwarded":       `for=198.51.100.9;proto=https, for="[2001:db8:cafe::17]:4711"`,
+				"X-Forwarded-For": "192.0.2.1",
+			},
+			exceptedIP:  "2001:db8:cafe::17",
+			exceptedIPs: []string{"198.51.100.9", "2001:db8:cafe::17", "2001:db8::1"},
+		},
+		{
+			name:        "X-Real-IP",
+			remoteAddr:  "10.0.0.1:1234",
+			headers:     map[string]string{"X-Real-IP": "198.51.100.9"},
+			exceptedIP:  "198.51.100.9",
+			exceptedIPs: []string{"10.0.0.1"},
+		},
+	}
+
+	var ip string
+	var ips []string
+
+	rt := New()
+	require.NoError(t, rt.SetTrustedProxies("10.0.0.0/8", "2001:db8::1"))
+	rt.Get("/test", func(ctx Ctx) error {
+		ip, ips = ctx.IP(), ctx.IPs()
+		return ctx.SendStatus(http.StatusOK)
+	})
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/test", nil)
+			req.RemoteAddr = test.remoteAddr
+			for key, value := range test.headers {
+				req.Header.Set(key, value)
+			}
+			rt.Mux().ServeHTTP(httptest.NewRecorder(), req)
+
+			assert.Equal(t, test.exceptedIP, ip)
+			assert.Equal(t, test.exceptedIPs, ips)
+		})
+	}
+}
+
+// TestCtxScheme tests the resolution of the scheme and the host with trusted proxies.
+func TestCtxScheme(t *testing.T) {
+	cases := []struct {
+		name             string
+		remoteAddr       string
+		tls              bool
+		headers          map[string]string
+		exceptedScheme   string
+		exceptedHostname string
+	}{
+		{
+			name:             "Plain request",
+			remoteAddr:       "203.0.113.7:1234",
+			exceptedScheme:   "http",
+			exceptedHostname: "example.com",
+		},
+		{
+			name:             "TLS request",
+			remoteAddr:       "203.0.113.7:1234",
+			tls:              true,
+			exceptedScheme:   "https",
+			exceptedHostname: "example.com",
+		},
+		{
+			name:             "Untrusted proxy",
+			remoteAddr:       "203.0.113.7:1234",
+			headers:          map[string]string{"X-Forwarded-Proto": "https", "X-Forwarded-Host": "evil.com"},
+			exceptedScheme:   "http",
+			exceptedHostname: "example.com",
+		},
+		{
+			name:             "X-Forwarded headers",
+			remoteAddr:       "10.0.0.1:1234",
+			headers:          map[string]string{"X-Forwarded-Proto": "https", "X-Forwarded-Host": "api.example.com:8443"},
+			exceptedScheme:   "https",
+			exceptedHostname: "api.example.com",
+		},
+		{
+			name:       "X-Forwarded headers of trusted hops",
+			remoteAddr: "10.0.0.1:1234",
+			headers: map[string]string{
+				"X-Forwarded-For":   "198.51.100.9, 10.0.0.2",
+				"X-Forwarded-Proto": "https, http",
+				"X-Forwarded-Host":  "api.example.com, internal.example.com",
+			},
+			exceptedScheme:   "https",
+			exceptedHostname: "api.example.com",
+		},
+		{
+			name:       "X-Forwarded headers supplied by the client",
+			remoteAddr: "10.0.0.1:1234",
+			headers: map[string]string{
+				"X-Forwarded-For":   "198.51.100.9",
+				"X-Forwarded-Proto": "http, https",
+				"X-Forwarded-Host":  "evil.com, api.example.com",
+			},
+			exceptedScheme:   "https",
+			exceptedHostname: "api.example.com",
+		},
+		{
+			name:             "Forwarded",
+			remoteAddr:       "10.0.0.1:1234",
+			headers:          map[string]string{"Forwarded": `for=198.51.100.9;proto=https;host="[::1]:8443"`},
+			exceptedScheme:   "https",
+			exceptedHostname: "::1",
+		},
+		{
+			name:       "Forwarded supplied by the client",
+			remoteAddr: "10.0.0.1:1234",
+			headers: map[string]string{
+				"Forwarded": `for=192.0.2.1;proto=http;host=evil.com, for=198.51.100.9;proto=https;host=api.example.com`,
+			},
+			exceptedScheme:   "https",
+			exceptedHostname: "api.example.com",
+		},
+	}
+
+	var scheme, hostname, protocol string
+
+	rt := New()
+	require.NoError(t, rt.SetTrustedProxies("10.0.0.0/8"))
+	rt.Get("/test", func(ctx Ctx) error {
+		scheme, hostname, protocol = ctx.Scheme(), ctx.Hostname(), ctx.Protocol()
+		return ctx.SendStatus(http.StatusOK)
+	})
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "http://example.com:8080/test", nil)
+			req.RemoteAddr = test.remoteAddr
+			if test.tls {
+				req.TLS = &tls.ConnectionState{}
+			}
+			for key, value := range test.headers {
+				req.Header.Set(key, value)
+			}
+			rt.Mux().ServeHTTP(httptest.NewRecorder(), req)
+
+			assert.Equal(t, test.exceptedScheme, scheme)
+			assert.Equal(t, test.exceptedHostname, hostname)
+			assert.Equal(t, "HTTP/1.1", protocol)
+		})
+	}
+}
+
+// TestWayesSetTrustedProxies_error tests the rejection of invalid trusted proxies.
+func TestWayesSetTrustedProxies_error(t *testing.T) {
+	rt := New()
+
+	assert.Error(t, rt.SetTrustedProxies("10.0.0.0/33"))
+	assert.Error(t, rt.SetTrustedProxies("proxy.local"))
+	assert.NoError(t, rt.SetTrustedProxies("::ffff:10.0.0.1", "fd00::/8"))
+}
+
+// TestParsePrefixes tests the parsing of CIDR ranges and single IP addresses.
+func TestParsePrefixes(t *testing.T) {
+	prefixes, err := ParsePrefixes([]string{" 10.1.2.3/8", "::ffff:192.0.2.1", "2001:db8::1"})
+	require.NoError(t, err)
+
+	assert.Equal(t, []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("192.0.2.1/32"),
+		netip.MustParsePrefix("2001:db8::1/128"),
+	}, prefixes)
+}
@@ -0,0 +1,378 @@
+package wayes
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// errRouteParams represents an error indicating that the route parameters are not key-value pairs.
+var errRouteParams = errors.New("wayes: route parameters must be key-value pairs")
+
+// RedirectMode represents a set of normalizations of request paths that are answered with a redirect.
+type RedirectMode int
+
+// Normalizations of request paths.
+const (
+	// RedirectTrailingSlash redirects a path with the trailing slash to the route without it.
+	// The opposite redirect is always done by [http.ServeMux].
+	RedirectTrailingSlash RedirectMode = 1 << iota
+
+	// RedirectCase redirects a path to the route that matches the path in lower case.
+	RedirectCase
+)
+
+// fallbackPattern is the pattern of the handler that answers the requests matching no route.
+const fallbackPattern = "/"
+
+// probeMethods is the list of methods checked to answer requests matching a route with another method.
+var probeMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+	http.MethodOptions,
+}
+
+// Name represents a route option that names the route, so its URL can be built with [Ctx.RouteURL].
+// Names are shared with all route groups and must be unique.
+type Name string
+
+// apply sets the name of the route.
+func (n Name) apply(e *endpoint) {
+	e.name = string(n)
+}
+
+// Redirect registers a rule that redirects requests of the path to the target with the status code.
+// The rule handles the GET method, and for 307 Temporary Redirect and 308 Permanent Redirect also
+// the methods with a body. The query of the request is kept unless the target has its own.
+// Root-relative targets are prefixed with the prefix of the group, the same way as route names.
+func (rt *wayes) Redirect(from, to string, code int) {
+	if code < http.StatusMultipleChoices || code > http.StatusPermanentRedirect {
+		panic(fmt.Sprintf("wayes: invalid redirect status %d", code))
+	}
+
+	wildcards := make(map[string]bool)
+	_, _ = buildPath(from, func(name string) (string, bool) {
+		wildcards[name] = true
+		return "", true
+	})
+
+	if _, err := buildPath(to, func(name string) (string, bool) { return "", wildcards[name] }); err != nil {
+		panic(fmt.Sprintf("wayes: redirect target %q: %v", to, err))
+	}
+
+	if strings.HasPrefix(to, "/") && !strings.HasPrefix(to, "//") {
+		to = rt.prefix + to
+	}
+
+	methods := []string{http.MethodGet}
+	if code == http.StatusTemporaryRedirect || code == http.StatusPermanentRedirect {
+		methods = append(methods, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete)
+	}
+
+	for _, method := range methods {
+		rt.handle(method, from, func(ctx Ctx) error {
+			r := ctx.Request()
+
+			location, err := buildPath(to, func(name string) (string, bool) {
+				return r.PathValue(name), true
+			})
+			if err != nil {
+				return err
+			}
+
+			if r.URL.RawQuery != "" && !strings.Contains(location, "?") {
+				location += "?" + r.URL.RawQuery
+			}
+
+			return ctx.Redirect(location, code)
+		})
+	}
+}
+
+// SetRedirectMode enables redirects of requests that match no route to the normalized path of a route.
+// GET and HEAD requests are redirected with 301 Moved Permanently, other methods with 308 Permanent Redirect.
+func (rt *wayes) SetRedirectMode(mode RedirectMode) {
+	rt.settings.redirectMode = mode
+
+	if mode != 0 {
+		rt.registerFallback()
+	}
+}
+
+// registerFallback registers the handler of requests matching no route in the wayes and its groups.
+func (rt *wayes) registerFallback() {
+	rt.handleFallback()
+
+	for _, group := range rt.groups {
+		group.registerFallback()
+	}
+}
+
+// handleFallback registers the handler of requests matching no route in the wayes once,
+// it is shared by the redirect mode and the combined routers.
+func (rt *wayes) handleFallback() {
+	if !rt.fallback {
+		rt.fallback = true
+		rt.mux.HandleFunc(fallbackPattern, rt.serveFallback)
+	}
+}
+
+// serveFallback passes the request to the combined router with a matching route.
+// Otherwise, the request is normalized if a redirect mode is set, or passed to the first combined router.
+func (rt *wayes) serveFallback(w http.ResponseWriter, r *http.Request) {
+	for _, router := range rt.combined {
+		if _, pattern := router.Handler(r); pattern != "" {
+			router.ServeHTTP(w, r)
+			return
+		}
+	}
+
+	if rt.settings.redirectMode == 0 && len(rt.combined) > 0 {
+		rt.combined[0].ServeHTTP(w, r)
+		return
+	}
+
+	rt.normalize(w, r)
+}
+
+// normalize redirects the request to the normalized path if it matches a route.
+// Otherwise, it answers like [http.ServeMux] with 405 Method Not Allowed or 404 Not Found.
+func (rt *wayes) normalize(w http.ResponseWriter, r *http.Request) {
+	for _, candidate := range normalizedPaths(r.URL.Path, rt.settings.redirectMode) {
+		if !rt.matches(r, r.Method, candidate) {
+			continue
+		}
+
+		code := http.StatusMovedPermanently
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			code = http.StatusPermanentRedirect
+		}
+
+		location := url.URL{Path: rt.prefix + candidate, RawQuery: r.URL.RawQuery}
+		http.Redirect(w, r, location.String(), code)
+
+		return
+	}
+
+	var allow []string
+	for _, method := range probeMethods {
+		if rt.matches(r, method, r.URL.Path) {
+			allow = append(allow, method)
+		}
+	}
+
+	if len(allow) > 0 {
+		w.Header().Set("Allow", strings.Join(allow, ", "))
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	http.NotFound(w, r)
+}
+
+// matches reports whether a route other than the fallback matches the method and the path.
+func (rt *wayes) matches(r *http.Request, method, path string) bool {
+	probe := &http.Request{Method: method, Host: r.Host, URL: &url.URL{Path: path}}
+	_, pattern := rt.mux.Handler(probe)
+
+	return pattern != "" && pattern != fallbackPattern
+}
+
+// normalizedPaths returns the paths the request path is redirected to, in the order of preference.
+func normalizedPaths(path string, mode RedirectMode) []string {
+	var paths []string
+	add := func(candidate string) {
+		if candidate != path && !slices.Contains(paths, candidate) {
+			paths = append(paths, candidate)
+		}
+	}
+
+	if mode&RedirectTrailingSlash != 0 {
+		add(toggleSlash(path))
+	}
+
+	if mode&RedirectCase != 0 {
+		lower := strings.ToLower(path)
+		add(lower)
+		if mode&RedirectTrailingSlash != 0 {
+			add(toggleSlash(lower))
+		}
+	}
+
+	return paths
+}
+
+// toggleSlash removes the trailing slash of the path or adds it if there is none.
+func toggleSlash(path string) string {
+	if path == "/" {
+		return path
+	}
+
+	if strings.HasSuffix(path, "/") {
+		return strings.TrimSuffix(path, "/")
+	}
+
+	return path + "/"
+}
+
+// buildPath replaces the wildcards of the route path with the escaped parameter values.
+// The values of the {name...} wildcards keep their slashes.
+func buildPath(path string, param func(name string) (string, bool)) (string, error) {
+	var b strings.Builder
+
+	for {
+		start := strings.IndexByte(path, '{')
+		if start < 0 {
+			break
+		}
+
+		end := strings.IndexByte(path[start:], '}')
+		if end < 0 {
+			break
+		}
+
+		b.WriteString(path[:start])
+		wildcard := path[start+1 : start+end]
+		path = path[start+end+1:]
+
+		if wildcard == "$" {
+			continue
+		}
+
+		name, remainder := strings.CutSuffix(wildcard, "...")
+		value, ok := param(name)
+		if !ok {
+			return "", fmt.Errorf("missing parameter %q", name)
+		}
+
+		if remainder {
+			segments := strings.Split(value, "/")
+			for i, segment := range segments {
+				segments[i] = url.PathEscape(segment)
+			}
+			value = strings.Join(segments, "/")
+		} else {
+			value = url.PathEscape(value)
+		}
+
+		b.WriteString(value)
+	}
+	b.WriteString(path)
+
+	return b.String(), nil
+}
+
+// Redirect redirects the request to the location with the status code, 302 Found by default.
+// The location must be trusted, use [Ctx.RedirectLocal] for locations supplied by the user.
+func (c *ctx) Redirect(location string, status ...int) error {
+	code := http.StatusFound
+	if len(status) > 0 {
+		code = status[0]
+	}
+
+	http.Redirect(c.response, c.request, location, code)
+
+	return nil
+}
+
+// RedirectLocal redirects the request to the target supplied by the user, for example the "next" query parameter,
+// if it points to the host of the request. Otherwise, it redirects to the fallback, preventing open redirects.
+func (c *ctx) RedirectLocal(target, fallback string, status ...int) error {
+	if !c.localURL(target) {
+		target = fallback
+	}
+
+	return c.Redirect(target, status...)
+}
+
+// RedirectBack redirects the request to the page from the Referer header if it points to the host of the request.
+// Otherwise, it redirects to the fallback.
+func (c *ctx) RedirectBack(fallback string, status ...int) error {
+	return c.RedirectLocal(c.request.Header.Get("Referer"), fallback, status...)
+}
+
+// RedirectToRoute redirects the request to the named route with 302 Found.
+// The parameters are key-value pairs, the ones that are not wildcards of the route are added to the query.
+func (c *ctx) RedirectToRoute(name string, params ...string) error {
+	location, err := c.RouteURL(name, params...)
+	if err != nil {
+		return err
+	}
+
+	return c.Redirect(location)
+}
+
+// RouteURL builds the URL of the named route, replacing its wildcards with the parameters.
+// The parameters are key-value pairs, the ones that are not wildcards of the route are added to the query.
+func (c *ctx) RouteURL(name string, params ...string) (string, error) {
+	path, ok := c.settings.names[name]
+	if !ok {
+		return "", fmt.Errorf("wayes: route %q not found", name)
+	}
+
+	if len(params)%2 != 0 {
+		return "", errRouteParams
+	}
+
+	values := make(map[string]string, len(params)/2)
+	for i := 0; i < len(params); i += 2 {
+		values[params[i]] = params[i+1]
+	}
+
+	used := make(map[string]bool, len(values))
+	location, err := buildPath(path, func(name string) (string, bool) {
+		value, ok := values[name]
+		used[name] = true
+
+		return value, ok
+	})
+	if err != nil {
+		return "", fmt.Errorf("wayes: route %q: %w", name, err)
+	}
+
+	query := url.Values{}
+	for i := 0; i < len(params); i += 2 {
+		if !used[params[i]] {
+			query.Add(params[i], params[i+1])
+		}
+	}
+
+	if len(query) > 0 {
+		location += "?" + query.Encode()
+	}
+
+	return location, nil
+}
+
+// localURL reports whether the target is a relative URL or an absolute URL with the host of the request.
+// Protocol-relative URLs, backslashes and control characters, which browsers may resolve to another host,
+// are rejected.
+func (c *ctx) localURL(target string) bool {
+	if target == "" || strings.HasPrefix(target, "//") || strings.ContainsRune(target, '\\') {
+		return false
+	}
+
+	for i := 0; i < len(target); i++ {
+		if target[i] < 0x20 || target[i] == 0x7f {
+			return false
+		}
+	}
+
+	u, err := url.Parse(target)
+	if err != nil {
+		return false
+	}
+
+	if u.Scheme == "" && u.Host == "" {
+		return true
+	}
+
+	return (u.Scheme == "http" || u.Scheme == "https") && strings.EqualFold(u.Hostname(), c.Hostname())
+}
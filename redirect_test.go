@@ -0,0 +1,461 @@
+package wayes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCtxRedirect tests the redirects to trusted, user-supplied and referring locations.
+func TestCtxRedirect(t *testing.T) {
+	cases := []struct {
+		name             string
+		path             string
+		headers          map[string]string
+		exceptedCode     int
+		exceptedLocation string
+	}{
+		{
+			name:             "Default status",
+			path:             "/redirect",
+			exceptedCode:     http.StatusFound,
+			exceptedLocation: "/home",
+		},
+		{
+			name:             "Custom status",
+			path:             "/redirect?status=303",
+			exceptedCode:     http.StatusSeeOther,
+			exceptedLocation: "/home",
+		},
+		{
+			name:             "Local path",
+			path:             "/login?next=/account%3Ftab%3D1",
+			exceptedCode:     http.StatusSeeOther,
+			exceptedLocation: "/account?tab=1",
+		},
+		{
+			name:             "Local absolute URL",
+			path:             "/login?next=https://example.com/account",
+			exceptedCode:     http.StatusSeeOther,
+			exceptedLocation: "https://example.com/account",
+		},
+		{
+			name:             "External URL",
+			path:             "/login?next=https://evil.com/account",
+			exceptedCode:     http.StatusSeeOther,
+			exceptedLocation: "/",
+		},
+		{
+			name:             "Protocol-relative URL",
+			path:             "/login?next=//evil.com",
+			exceptedCode:     http.StatusSeeOther,
+			exceptedLocation: "/",
+		},
+		{
+			name:             "Backslash",
+			path:             "/login?next=/%5Cevil.com",
+			exceptedCode:     http.StatusSeeOther,
+			exceptedLocation: "/",
+		},
+		{
+			name:             "Control character",
+			path:             "/login?next=/%09/evil.com",
+			exceptedCode:     http.StatusSeeOther,
+			exceptedLocation: "/",
+		},
+		{
+			name:             "Script URL",
+			path:             "/login?next=javascript:alert(1)",
+			exceptedCode:     http.StatusSeeOther,
+			exceptedLocation: "/",
+		},
+		{
+			name:             "Back to referer",
+			path:             "/back",
+			headers:          map[string]string{"Referer": "https://example.com/cart"},
+			exceptedCode:     http.StatusFound,
+			exceptedLocation: "https://example.com/cart",
+		},
+		{
+			name:             "Back to external referer",
+			path:             "/back",
+			headers:          map[string]string{"Referer": "https://evil.com/cart"},
+			exceptedCode:     http.StatusFound,
+			exceptedLocation: "/",
+		},
+		{
+			name:             "Back without referer",
+			path:             "/back",
+			exceptedCode:     http.StatusFound,
+			exceptedLocation: "/",
+		},
+	}
+
+	rt := New()
+	rt.Get("/redirect", func(ctx Ctx) error {
+		if ctx.Request().URL.Query().Get("status") == "303" {
+			return ctx.Redirect("/home", http.StatusSeeOther)
+		}
+
+		return ctx.Redirect("/home")
+	})
+	rt.Get("/login", func(ctx Ctx) error {
+		return ctx.RedirectLocal(ctx.Request().URL.Query().Get("next"), "/", http.StatusSeeOther)
+	})
+	rt.Get("/back", func(ctx Ctx) error {
+		return ctx.RedirectBack("/")
+	})
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "https://example.com"+test.path, nil)
+			for key, value := range test.headers {
+				req.Header.Set(key, value)
+			}
+
+			rr := httptest.NewRecorder()
+			rt.Mux().ServeHTTP(rr, req)
+
+			assert.Equal(t, test.exceptedCode, rr.Code)
+			assert.Equal(t, test.exceptedLocation, rr.Header().Get("Location"))
+		})
+	}
+}
+
+// TestCtxRouteURL tests the building of URLs of named routes.
+func TestCtxRouteURL(t *testing.T) {
+	cases := []struct {
+		name          string
+		route         string
+		params        []string
+		exceptedURL   string
+		exceptedError string
+	}{
+		{
+			name:        "Without parameters",
+			route:       "home",
+			exceptedURL: "/",
+		},
+		{
+			name:        "Wildcard",
+			route:       "user",
+			params:      []string{"id", "john doe"},
+			exceptedURL: "/api/users/john%20doe",
+		},
+		{
+			name:        "Remaining wildcard",
+			route:       "file",
+			params:      []string{"path", "docs/read me.txt"},
+			exceptedURL: "/files/docs/read%20me.txt",
+		},
+		{
+			name:        "Query parameters",
+			route:       "user",
+			params:      []string{"id", "42", "tab", "posts", "page", "2"},
+			exceptedURL: "/api/users/42?page=2&tab=posts",
+		},
+		{
+			name:          "Missing parameter",
+			route:         "user",
+			exceptedError: `wayes: route "user": missing parameter "id"`,
+		},
+		{
+			name:          "Odd parameters",
+			route:         "user",
+			params:        []string{"id"},
+			exceptedError: errRouteParams.Error(),
+		},
+		{
+			name:          "Unknown route",
+			route:         "missing",
+			exceptedError: `wayes: route "missing" not found`,
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			var (
+				url string
+				err error
+			)
+
+			rt := New()
+			rt.Get("/{$}", func(ctx Ctx) error { return nil }, Name("home"))
+			rt.Get("/files/{path...}", func(ctx Ctx) error { return nil }, Name("file"))
+			rt.Group("/api").Get("/users/{id}", func(ctx Ctx) error { return nil }, Name("user"))
+			rt.Get("/test", func(ctx Ctx) error {
+				url, err = ctx.RouteURL(test.route, test.params...)
+				return nil
+			})
+			rt.Mux().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/test", nil))
+
+			if test.exceptedError != "" {
+				assert.EqualError(t, err, test.exceptedError)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.exceptedURL, url)
+		})
+	}
+}
+
+// TestCtxRedirectToRoute tests the redirect to a named route and the uniqueness of route names.
+func TestCtxRedirectToRoute(t *testing.T) {
+	rt := New()
+	rt.Get("/users/{id}", func(ctx Ctx) error { return nil }, Name("user"))
+	rt.Post("/users", func(ctx Ctx) error {
+		return ctx.RedirectToRoute("user", "id", "42")
+	})
+
+	rr := httptest.NewRecorder()
+	rt.Mux().ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/users", nil))
+
+	assert.Equal(t, http.StatusFound, rr.Code)
+	assert.Equal(t, "/users/42", rr.Header().Get("Location"))
+	assert.Equal(t, "user", rt.Routes()[0].Name)
+
+	assert.PanicsWithValue(t, `wayes: route name "user" is already registered`, func() {
+		rt.Get("/profiles/{id}", func(ctx Ctx) error { return nil }, Name("user"))
+	})
+}
+
+// TestWayesRedirect tests the redirect rules of the router.
+func TestWayesRedirect(t *testing.T) {
+	cases := []struct {
+		name             string
+		method           string
+		path             string
+		exceptedCode     int
+		exceptedLocation string
+	}{
+		{
+			name:             "Static rule",
+			method:           http.MethodGet,
+			path:             "/old",
+			exceptedCode:     http.StatusMovedPermanently,
+			exceptedLocation: "/new",
+		},
+		{
+			name:             "Rule with wildcards",
+			method:           http.MethodGet,
+			path:             "/old/users/john%20doe?tab=posts",
+			exceptedCode:     http.StatusMovedPermanently,
+			exceptedLocation: "/users/john%20doe?tab=posts",
+		},
+		{
+			name:             "Method preserved",
+			method:           http.MethodPost,
+			path:             "/v1/orders",
+			exceptedCode:     http.StatusPermanentRedirect,
+			exceptedLocation: "https://api.example.com/v2/orders",
+		},
+		{
+			name:             "Group rule",
+			method:           http.MethodGet,
+			path:             "/api/old/1",
+			exceptedCode:     http.StatusMovedPermanently,
+			exceptedLocation: "/api/new/1",
+		},
+		{
+			name:             "Group rule with absolute URL",
+			method:           http.MethodGet,
+			path:             "/api/docs",
+			exceptedCode:     http.StatusFound,
+			exceptedLocation: "https://docs.example.com/",
+		},
+		{
+			name:         "Method not redirected",
+			method:       http.MethodPost,
+			path:         "/old",
+			exceptedCode: http.StatusMethodNotAllowed,
+		},
+	}
+
+	rt := New()
+	rt.Redirect("/old", "/new", http.StatusMovedPermanently)
+	rt.Redirect("/old/users/{id}", "/users/{id}", http.StatusMovedPermanently)
+	rt.Redirect("/v1/{path...}", "https://api.example.com/v2/{path...}", http.StatusPermanentRedirect)
+
+	api := rt.Group("/api")
+	api.Redirect("/old/{id}", "/new/{id}", http.StatusMovedPermanently)
+	api.Redirect("/docs", "https://docs.example.com/", http.StatusFound)
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			rt.Mux().ServeHTTP(rr, httptest.NewRequest(test.method, test.path, nil))
+
+			assert.Equal(t, test.exceptedCode, rr.Code)
+			assert.Equal(t, test.exceptedLocation, rr.Header().Get("Location"))
+		})
+	}
+
+	assert.PanicsWithValue(t, `wayes: invalid redirect status 200`, func() {
+		rt.Redirect("/from", "/to", http.StatusOK)
+	})
+	assert.PanicsWithValue(t, `wayes: redirect target "/to/{id}": missing parameter "id"`, func() {
+		rt.Redirect("/from", "/to/{id}", http.StatusFound)
+	})
+}
+
+// TestWayesSetRedirectMode tests the redirects of requests to the normalized paths of routes.
+func TestWayesSetRedirectMode(t *testing.T) {
+	cases := []struct {
+		name             string
+		mode             RedirectMode
+		method           string
+		path             string
+		exceptedCode     int
+		exceptedLocation string
+		exceptedAllow    string
+	}{
+		{
+			name:         "Disabled",
+			method:       http.MethodGet,
+			path:         "/users/",
+			exceptedCode: http.StatusNotFound,
+		},
+		{
+			name:             "Remove trailing slash",
+			mode:             RedirectTrailingSlash,
+			method:           http.MethodGet,
+			path:             "/users/?page=2",
+			exceptedCode:     http.StatusMovedPermanently,
+			exceptedLocation: "/users?page=2",
+		},
+		{
+			name:             "Method preserved",
+			mode:             RedirectTrailingSlash,
+			method:           http.MethodPost,
+			path:             "/posts/",
+			exceptedCode:     http.StatusPermanentRedirect,
+			exceptedLocation: "/posts",
+		},
+		{
+			name:         "Case not normalized",
+			mode:         RedirectTrailingSlash,
+			method:       http.MethodGet,
+			path:         "/Users",
+			exceptedCode: http.StatusNotFound,
+		},
+		{
+			name:             "Lower case",
+			mode:             RedirectCase,
+			method:           http.MethodGet,
+			path:             "/Users",
+			exceptedCode:     http.StatusMovedPermanently,
+			exceptedLocation: "/users",
+		},
+		{
+			name:             "Lower case and trailing slash",
+			mode:             RedirectCase | RedirectTrailingSlash,
+			method:           http.MethodGet,
+			path:             "/USERS/",
+			exceptedCode:     http.StatusMovedPermanently,
+			exceptedLocation: "/users",
+		},
+		{
+			name:             "Group route",
+			mode:             RedirectTrailingSlash,
+			method:           http.MethodGet,
+			path:             "/api/items/",
+			exceptedCode:     http.StatusMovedPermanently,
+			exceptedLocation: "/api/items",
+		},
+		{
+			name:          "Method not allowed",
+			mode:          RedirectTrailingSlash,
+			method:        http.MethodDelete,
+			path:          "/users",
+			exceptedCode:  http.StatusMethodNotAllowed,
+			exceptedAllow: "GET, HEAD, OPTIONS",
+		},
+		{
+			name:         "Not found",
+			mode:         RedirectCase | RedirectTrailingSlash,
+			method:       http.MethodGet,
+			path:         "/missing/",
+			exceptedCode: http.StatusNotFound,
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			rt := New()
+			rt.Get("/users", func(ctx Ctx) error { return nil })
+			rt.Post("/posts", func(ctx Ctx) error { return nil })
+			api := rt.Group("/api")
+			rt.SetRedirectMode(test.mode)
+			api.Get("/items", func(ctx Ctx) error { return nil })
+
+			rr := httptest.NewRecorder()
+			rt.Mux().ServeHTTP(rr, httptest.NewRequest(test.method, test.path, nil))
+
+			assert.Equal(t, test.exceptedCode, rr.Code)
+			assert.Equal(t, test.exceptedLocation, rr.Header().Get("Location"))
+			assert.Equal(t, test.exceptedAllow, rr.Header().Get("Allow"))
+		})
+	}
+}
+
+// TestWayesSetRedirectMode_combine tests the redirect mode of the router combined with another router.
+func TestWayesSetRedirectMode_combine(t *testing.T) {
+	cases := []struct {
+		name             string
+		path             string
+		exceptedCode     int
+		exceptedLocation string
+	}{
+		{
+			name:         "Own route",
+			path:         "/users",
+			exceptedCode: http.StatusOK,
+		},
+		{
+			name:         "Combined route",
+			path:         "/v2/items",
+			exceptedCode: http.StatusOK,
+		},
+		{
+			name:             "Normalized path",
+			path:             "/Users/",
+			exceptedCode:     http.StatusMovedPermanently,
+			exceptedLocation: "/users",
+		},
+		{
+			name:         "Not found",
+			path:         "/missing",
+			exceptedCode: http.StatusNotFound,
+		},
+	}
+
+	for _, combineFirst := range []bool{true, false} {
+		rt := New()
+		rt.Get("/users", func(ctx Ctx) error { return nil })
+
+		rt2 := New()
+		rt2.Get("/v2/items", func(ctx Ctx) error { return nil })
+
+		if combineFirst {
+			rt.Combine(rt2.Mux())
+			rt.SetRedirectMode(RedirectCase | RedirectTrailingSlash)
+		} else {
+			rt.SetRedirectMode(RedirectCase | RedirectTrailingSlash)
+			rt.Combine(rt2.Mux())
+		}
+
+		for _, test := range cases {
+			t.Run(test.name, func(t *testing.T) {
+				rr := httptest.NewRecorder()
+				rt.Mux().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, test.path, nil))
+
+				assert.Equal(t, test.exceptedCode, rr.Code)
+				assert.Equal(t, test.exceptedLocation, rr.Header().Get("Location"))
+			})
+		}
+	}
+}
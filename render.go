@@ -0,0 +1,221 @@
+package wayes
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"maps"
+	"strings"
+)
+
+// ErrNoRenderer is returned by [Ctx.Render] when the router has no renderer.
+var ErrNoRenderer = errors.New("wayes: renderer is not set")
+
+// viewLocals is the list of values of [Ctx.Locals] injected into the data of templates.
+var viewLocals = []struct {
+	key  localsKey
+	name string
+}{
+	{CSRFTokenKey, "CSRFToken"},
+	{CSPNonceKey, "CSPNonce"},
+}
+
+// Renderer is an interface that defines a method for rendering templates.
+type Renderer interface {
+	// Render renders the template with the data into the writer.
+	// The layouts are applied in order, the first layout wraps the template and the last one is the outermost.
+	Render(w io.Writer, name string, data any, layouts ...string) error
+}
+
+// HTMLConfig represents a structure for the [NewHTMLRenderer] configuration.
+type HTMLConfig struct {
+	// Extension is the extension of the template files. Defaults to ".html".
+	Extension string
+
+	// Reload parses the templates on every render, so changes are picked up without a restart.
+	// It is intended for development.
+	Reload bool
+
+	// Funcs are the functions available in the templates in addition to embed.
+	Funcs template.FuncMap
+}
+
+// htmlRenderer represents a structure that implements the [Renderer] interface with [html/template].
+type htmlRenderer struct {
+	fsys      fs.FS
+	config    HTMLConfig
+	marker    string
+	templates *template.Template
+}
+
+// NewHTMLRenderer creates a new [Renderer] that loads the templates from the file system.
+// Every template is named by its path without the extension, for example "pages/home", and can be
+// included into other templates as a partial with {{ template "partials/nav" . }}.
+// Layouts include the content they wrap with {{ embed }}.
+func NewHTMLRenderer(fsys fs.FS, config ...HTMLConfig) (Renderer, error) {
+	cfg := HTMLConfig{}
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+
+	if cfg.Extension == "" {
+		cfg.Extension = ".html"
+	}
+
+	var nonce [16]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return nil, err
+	}
+
+	r := &htmlRenderer{
+		fsys:   fsys,
+		config: cfg,
+		marker: "<!--wayes:embed:" + hex.EncodeToString(nonce[:]) + "-->",
+	}
+
+	templates, err := r.parse()
+	if err != nil {
+		return nil, err
+	}
+	r.templates = templates
+
+	return r, nil
+}
+
+// parse parses the template files of the file system into a single set.
+func (r *htmlRenderer) parse() (*template.Template, error) {
+	funcs := template.FuncMap{
+		"embed": func() template.HTML {
+			return template.HTML(r.marker)
+		},
+	}
+	maps.Copy(funcs, r.config.Funcs)
+
+	templates := template.New("").Funcs(funcs)
+
+	err := fs.WalkDir(r.fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(name, r.config.Extension) {
+			return err
+		}
+
+		src, err := fs.ReadFile(r.fsys, name)
+		if err != nil {
+			return err
+		}
+
+		_, err = templates.New(strings.TrimSuffix(name, r.config.Extension)).Parse(string(src))
+
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("wayes: parse templates: %w", err)
+	}
+
+	return templates, nil
+}
+
+// lookup returns the template set, parsing it again in the reload mode.
+func (r *htmlRenderer) lookup() (*template.Template, error) {
+	if r.config.Reload {
+		return r.parse()
+	}
+
+	return r.templates, nil
+}
+
+// Render renders the template and wraps it with the layouts.
+// The output is buffered, so nothing is written when the rendering fails.
+func (r *htmlRenderer) Render(w io.Writer, name string, data any, layouts ...string) error {
+	templates, err := r.lookup()
+	if err != nil {
+		return err
+	}
+
+	content, err := execute(templates, name, data)
+	if err != nil {
+		return err
+	}
+
+	for _, layout := range layouts {
+		out, err := execute(templates, layout, data)
+		if err != nil {
+			return err
+		}
+
+		content = bytes.Replace(out, []byte(r.marker), content, 1)
+	}
+
+	_, err = w.Write(content)
+
+	return err
+}
+
+// execute executes the template of the set by the name.
+func execute(templates *template.Template, name string, data any) ([]byte, error) {
+	tmpl := templates.Lookup(name)
+	if tmpl == nil {
+		return nil, fmt.Errorf("wayes: template %q not found", name)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Render renders the template with the renderer of the router and sends it as an HTML response.
+// When the data is a [Map] or nil, the CSRF token and the CSP nonce stored in [Ctx.Locals] are
+// injected as CSRFToken and CSPNonce unless the data already contains them.
+func (c *ctx) Render(name string, data any, layouts ...string) error {
+	if c.settings.renderer == nil {
+		return ErrNoRenderer
+	}
+
+	var buf bytes.Buffer
+	if err := c.settings.renderer.Render(&buf, name, c.viewData(data), layouts...); err != nil {
+		return err
+	}
+
+	c.ContentType("text/html; charset=utf-8")
+
+	return c.send(buf.Bytes())
+}
+
+// viewData returns a copy of the map data with the values of [Ctx.Locals] used by the templates.
+// Other types of data are returned as is.
+func (c *ctx) viewData(data any) any {
+	var view Map
+
+	switch value := data.(type) {
+	case nil:
+	case Map:
+		view = maps.Clone(value)
+	case map[string]any:
+		view = maps.Clone(value)
+	default:
+		return data
+	}
+
+	if view == nil {
+		view = Map{}
+	}
+
+	for _, local := range viewLocals {
+		if _, ok := view[local.name]; ok {
+			continue
+		}
+
+		if value := c.Locals(local.key); value != nil {
+			view[local.name] = value
+		}
+	}
+
+	return view
+}
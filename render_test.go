@@ -0,0 +1,182 @@
+package wayes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCtxRender tests the rendering of templates with partials, layouts and values of the context.
+func TestCtxRender(t *testing.T) {
+	fsys := fstest.MapFS{
+		"layouts/main.html":    {Data: []byte(`<html><script nonce="{{ .CSPNonce }}"></script>{{ embed }}</html>`)},
+		"layouts/section.html": {Data: []byte(`<section>{{ embed }}</section>`)},
+		"partials/nav.html":    {Data: []byte(`<nav>{{ .Title }}</nav>`)},
+		"pages/home.html":      {Data: []byte(`{{ template "partials/nav" . }}<input value="{{ .CSRFToken }}">`)},
+		"pages/upper.html":     {Data: []byte(`{{ upper .Title }}`)},
+		"pages/broken.html":    {Data: []byte(`{{ .Title.Missing }}`)},
+		"README.md":            {Data: []byte(`{{ not a template`)},
+	}
+
+	renderer, err := NewHTMLRenderer(fsys, HTMLConfig{
+		Funcs: map[string]any{"upper": strings.ToUpper},
+	})
+	require.NoError(t, err)
+
+	cases := []struct {
+		name          string
+		template      string
+		data          any
+		layouts       []string
+		exceptedCode  int
+		exceptedBody  string
+		exceptedError bool
+	}{
+		{
+			name:         "Template with partial",
+			template:     "pages/home",
+			data:         Map{"Title": "Home"},
+			exceptedCode: http.StatusOK,
+			exceptedBody: `<nav>Home</nav><input value="token">`,
+		},
+		{
+			name:         "Escaped data",
+			template:     "partials/nav",
+			data:         map[string]any{"Title": "<b>Home</b>"},
+			exceptedCode: http.StatusOK,
+			exceptedBody: `<nav>&lt;b&gt;Home&lt;/b&gt;</nav>`,
+		},
+		{
+			name:         "Data overrides locals",
+			template:     "pages/home",
+			data:         Map{"Title": "Home", "CSRFToken": "custom"},
+			exceptedCode: http.StatusOK,
+			exceptedBody: `<nav>Home</nav><input value="custom">`,
+		},
+		{
+			name:         "Layout",
+			template:     "partials/nav",
+			data:         Map{"Title": "Home"},
+			layouts:      []string{"layouts/main"},
+			exceptedCode: http.StatusOK,
+			exceptedBody: `<html><script nonce="nonce"></script><nav>Home</nav></html>`,
+		},
+		{
+			name:         "Nested layouts",
+			template:     "partials/nav",
+			data:         Map{"Title": "Home"},
+			layouts:      []string{"layouts/section", "layouts/main"},
+			exceptedCode: http.StatusOK,
+			exceptedBody: `<html><script nonce="nonce"></script><section><nav>Home</nav></section></html>`,
+		},
+		{
+			name:         "Struct data",
+			template:     "pages/upper",
+			data:         struct{ Title string }{"home"},
+			exceptedCode: http.StatusOK,
+			exceptedBody: `HOME`,
+		},
+		{
+			name:          "Missing template",
+			template:      "pages/missing",
+			exceptedError: true,
+		},
+		{
+			name:          "Missing layout",
+			template:      "partials/nav",
+			layouts:       []string{"layouts/missing"},
+			exceptedError: true,
+		},
+		{
+			name:          "Execution error",
+			template:      "pages/broken",
+			data:          Map{"Title": "Home"},
+			exceptedError: true,
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			var err error
+
+			rt := New()
+			rt.SetRenderer(renderer)
+			rt.Use(func(ctx Ctx) error {
+				ctx.Locals(CSRFTokenKey, "token")
+				ctx.Locals(CSPNonceKey, "nonce")
+				return ctx.Next()
+			})
+			rt.Get("/test", func(ctx Ctx) error {
+				err = ctx.Render(test.template, test.data, test.layouts...)
+				return err
+			})
+
+			rr := httptest.NewRecorder()
+			rt.Mux().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/test", nil))
+
+			if test.exceptedError {
+				assert.Error(t, err)
+				assert.Equal(t, http.StatusInternalServerError, rr.Code)
+				return
+			}
+			require.NoError(t, err)
+
+			assert.Equal(t, test.exceptedCode, rr.Code)
+			assert.Equal(t, "text/html; charset=utf-8", rr.Header().Get("Content-Type"))
+			assert.Equal(t, test.exceptedBody, rr.Body.String())
+		})
+	}
+}
+
+// TestCtxRender_errorNoRenderer tests that rendering fails when the router has no renderer.
+func TestCtxRender_errorNoRenderer(t *testing.T) {
+	c := NewCtx(nil, httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.ErrorIs(t, c.Render("pages/home", nil), ErrNoRenderer)
+}
+
+// TestNewHTMLRenderer_reload tests that the templates are parsed again on every render in the reload mode.
+func TestNewHTMLRenderer_reload(t *testing.T) {
+	cases := []struct {
+		name         string
+		reload       bool
+		exceptedBody string
+	}{
+		{
+			name:         "Cached",
+			exceptedBody: "v1",
+		},
+		{
+			name:         "Reload",
+			reload:       true,
+			exceptedBody: "v2",
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			fsys := fstest.MapFS{"index.html": {Data: []byte("v1")}}
+
+			renderer, err := NewHTMLRenderer(fsys, HTMLConfig{Reload: test.reload})
+			require.NoError(t, err)
+
+			fsys["index.html"] = &fstest.MapFile{Data: []byte("v2")}
+
+			var buf strings.Builder
+			require.NoError(t, renderer.Render(&buf, "index", nil))
+			assert.Equal(t, test.exceptedBody, buf.String())
+		})
+	}
+}
+
+// TestNewHTMLRenderer_errorParse tests that invalid templates are reported when the renderer is created.
+func TestNewHTMLRenderer_errorParse(t *testing.T) {
+	_, err := NewHTMLRenderer(fstest.MapFS{"index.html": {Data: []byte("{{ .Title ")}})
+
+	assert.ErrorContains(t, err, "wayes: parse templates")
+}
@@ -0,0 +1,27 @@
+package wayes
+
+import "context"
+
+// HeaderRequestID is the default header used to propagate the request identifier.
+const HeaderRequestID = "X-Request-ID"
+
+// RequestIDKey is the key under which the request identifier is stored in [Ctx.Locals].
+const RequestIDKey localsKey = "requestID"
+
+// RequestIDFrom returns the request identifier stored in the provided context.
+// It returns an empty string if the context does not contain a request identifier.
+func RequestIDFrom(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+
+	id, _ := ctx.Value(RequestIDKey).(string)
+
+	return id
+}
+
+// WithRequestID returns a copy of the provided context that carries the request identifier.
+// It is useful for propagating the identifier to outgoing clients and background jobs.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, RequestIDKey, id)
+}
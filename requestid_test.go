@@ -0,0 +1,14 @@
+package wayes
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRequestIDFrom tests the retrieval of the request identifier from the context.
+func TestRequestIDFrom(t *testing.T) {
+	assert.Equal(t, "", RequestIDFrom(context.Background()))
+	assert.Equal(t, "test-id", RequestIDFrom(WithRequestID(context.Background(), "test-id")))
+}
@@ -0,0 +1,27 @@
+/*
+Package session provides session management for the [github.com/eliofery/wayes] router.
+
+The [Middleware] loads the session of the request from the store and saves it before the response is sent.
+The session identifier is kept in a cookie encrypted with the cookie keys of the router,
+so the keys must be configured with SetCookieKeys.
+
+	router := wayes.New()
+	router.SetCookieKeys([]byte(os.Getenv("COOKIE_KEY")))
+
+	router.Use(session.Middleware(session.Config{
+		Store: session.NewMemoryStore(),
+	}))
+
+	router.Post("/login", func(ctx wayes.Ctx) error {
+		sess := session.From(ctx)
+
+		// Protect against session fixation.
+		if err := sess.Regenerate(); err != nil {
+			return err
+		}
+		sess.Set("user", user.ID)
+
+		return ctx.SendStatus(http.StatusNoContent)
+	})
+*/
+package session
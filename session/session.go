@@ -0,0 +1,371 @@
+package session
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"maps"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/eliofery/wayes"
+)
+
+// sessionKey is the key under which the session is stored in [wayes.Ctx.Locals].
+type sessionKey struct{}
+
+// Config represents a structure for the [Middleware] configuration.
+type Config struct {
+	// Store holds the session data. Defaults to an in-memory store.
+	Store Store
+
+	// CookieName is the name of the session cookie. Defaults to "session".
+	CookieName string
+
+	// CookiePath is the path of the session cookie. Defaults to "/".
+	CookiePath string
+
+	// CookieDomain is the domain of the session cookie.
+	CookieDomain string
+
+	// CookieSecure restricts the session cookie to HTTPS requests.
+	CookieSecure bool
+
+	// CookieSameSite is the SameSite attribute of the session cookie. Defaults to Lax.
+	CookieSameSite http.SameSite
+
+	// IdleTimeout is the time after which an inactive session expires. Defaults to 30 minutes.
+	IdleTimeout time.Duration
+
+	// AbsoluteTimeout is the time after which a session expires regardless of activity. Defaults to 24 hours.
+	AbsoluteTimeout time.Duration
+
+	// Rolling saves the session on every request, so the idle timeout is renewed even if the session is not modified.
+	Rolling bool
+}
+
+// Session represents a structure for the session of a request.
+type Session struct {
+	mu          sync.Mutex
+	data        *Data
+	isNew       bool
+	modified    bool
+	destroyed   bool
+	regenerated []string
+}
+
+// From returns the session of the request stored by the [Middleware].
+// It returns nil if the middleware is not registered for the route.
+func From(ctx wayes.Ctx) *Session {
+	sess, _ := ctx.Locals(sessionKey{}).(*Session)
+
+	return sess
+}
+
+// ID returns the identifier of the session.
+func (s *Session) ID() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.data.ID
+}
+
+// IsNew reports whether the session was created by the current request.
+func (s *Session) IsNew() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.isNew
+}
+
+// Get returns the value stored by the key, nil if there is none.
+func (s *Session) Get(key string) any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.data.Values[key]
+}
+
+// Set stores the value by the key.
+func (s *Session) Set(key string, value any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data.Values[key] = value
+	s.modified = true
+}
+
+// Delete removes the value stored by the key.
+func (s *Session) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.data.Values, key)
+	s.modified = true
+}
+
+// Regenerate replaces the identifier of the session keeping its values and removes the old session from the store.
+// It must be called when the privilege level changes, for example after login, to prevent session fixation.
+func (s *Session) Regenerate() error {
+	id, err := newID()
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.isNew {
+		s.regenerated = append(s.regenerated, s.data.ID)
+	}
+
+	s.data.ID = id
+	s.data.Created = time.Now()
+	s.modified = true
+
+	return nil
+}
+
+// Destroy removes all values of the session, deletes it from the store and clears the session cookie.
+// Values set after Destroy are not saved.
+func (s *Session) Destroy() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data.Values = make(map[string]any)
+	s.destroyed = true
+
+	return nil
+}
+
+// Middleware creates a middleware that loads the session of the request and stores it in [wayes.Ctx.Locals],
+// so it can be retrieved with [From]. Expired sessions and sessions with unknown identifiers are replaced
+// with new ones. The session is saved when the response header is written.
+func Middleware(config ...Config) wayes.Handler {
+	cfg := Config{}
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+
+	if cfg.Store == nil {
+		cfg.Store = NewMemoryStore()
+	}
+
+	if cfg.CookieName == "" {
+		cfg.CookieName = "session"
+	}
+
+	if cfg.CookiePath == "" {
+		cfg.CookiePath = "/"
+	}
+
+	if cfg.CookieSameSite == 0 {
+		cfg.CookieSameSite = http.SameSiteLaxMode
+	}
+
+	if cfg.IdleTimeout <= 0 {
+		cfg.IdleTimeout = 30 * time.Minute
+	}
+
+	if cfg.AbsoluteTimeout <= 0 {
+		cfg.AbsoluteTimeout = 24 * time.Hour
+	}
+
+	return func(ctx wayes.Ctx) error {
+		sess, err := cfg.load(ctx)
+		if err != nil {
+			return err
+		}
+		ctx.Locals(sessionKey{}, sess)
+
+		original := ctx.Response()
+		sw := &sessionWriter{ResponseWriter: original}
+		sw.commit = func() {
+			sw.err = cfg.save(ctx, original, sess)
+		}
+
+		ctx.SetResponse(sw)
+		err = ctx.Next()
+		ctx.SetResponse(original)
+
+		sw.save()
+		if err != nil {
+			return err
+		}
+
+		return sw.err
+	}
+}
+
+// load returns the session of the request or a new session.
+func (cfg Config) load(ctx wayes.Ctx) (*Session, error) {
+	token, err := ctx.EncryptedCookie(cfg.CookieName)
+	if errors.Is(err, wayes.ErrCookieKeys) {
+		return nil, err
+	}
+
+	if err == nil {
+		data, err := cfg.Store.Load(token)
+		if err != nil {
+			return nil, err
+		}
+
+		if data != nil && !cfg.expired(data, time.Now()) {
+			if data.Values == nil {
+				data.Values = make(map[string]any)
+			}
+
+			return &Session{data: data}, nil
+		}
+
+		if data != nil {
+			if err := cfg.Store.Delete(data.ID); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	return &Session{
+		data: &Data{
+			ID:       id,
+			Values:   make(map[string]any),
+			Created:  now,
+			Accessed: now,
+		},
+		isNew: true,
+	}, nil
+}
+
+// expired reports whether the session is expired by the idle or the absolute timeout.
+func (cfg Config) expired(data *Data, now time.Time) bool {
+	return now.Sub(data.Accessed) > cfg.IdleTimeout || now.Sub(data.Created) > cfg.AbsoluteTimeout
+}
+
+// save stores the session and sets the session cookie on the response writer.
+func (cfg Config) save(ctx wayes.Ctx, w http.ResponseWriter, sess *Session) error {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+
+	for _, id := range sess.regenerated {
+		if err := cfg.Store.Delete(id); err != nil {
+			return err
+		}
+	}
+
+	// The cookie API of the context writes to the current response writer.
+	current := ctx.Response()
+	ctx.SetResponse(w)
+	defer ctx.SetResponse(current)
+
+	if sess.destroyed {
+		if !sess.isNew || len(sess.regenerated) > 0 {
+			if err := cfg.Store.Delete(sess.data.ID); err != nil {
+				return err
+			}
+		}
+
+		if !sess.isNew {
+			ctx.SetCookie(cfg.cookie("", -1, time.Unix(0, 0)))
+		}
+
+		return nil
+	}
+
+	if !sess.modified && !cfg.Rolling {
+		return nil
+	}
+
+	if sess.isNew && len(sess.data.Values) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	sess.data.Accessed = now
+
+	expires := sess.data.Created.Add(cfg.AbsoluteTimeout)
+	ttl := min(cfg.IdleTimeout, expires.Sub(now))
+
+	data := *sess.data
+	data.Values = maps.Clone(sess.data.Values)
+
+	token, err := cfg.Store.Save(&data, ttl)
+	if err != nil {
+		return err
+	}
+
+	return ctx.SetEncryptedCookie(cfg.cookie(token, int(expires.Sub(now).Seconds()), expires))
+}
+
+// cookie returns the session cookie with the value.
+func (cfg Config) cookie(value string, maxAge int, expires time.Time) *http.Cookie {
+	return &http.Cookie{
+		Name:     cfg.CookieName,
+		Value:    value,
+		Path:     cfg.CookiePath,
+		Domain:   cfg.CookieDomain,
+		Expires:  expires,
+		MaxAge:   maxAge,
+		Secure:   cfg.CookieSecure,
+		HttpOnly: true,
+		SameSite: cfg.CookieSameSite,
+	}
+}
+
+// newID returns a new random session identifier.
+func newID() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// sessionWriter represents a structure that saves the session before the response header is written.
+type sessionWriter struct {
+	http.ResponseWriter
+
+	commit func()
+	saved  bool
+	err    error
+}
+
+// save saves the session once.
+func (sw *sessionWriter) save() {
+	if !sw.saved {
+		sw.saved = true
+		sw.commit()
+	}
+}
+
+// WriteHeader saves the session and writes the status code.
+func (sw *sessionWriter) WriteHeader(code int) {
+	sw.save()
+	sw.ResponseWriter.WriteHeader(code)
+}
+
+// Write saves the session and writes the data.
+func (sw *sessionWriter) Write(p []byte) (int, error) {
+	sw.save()
+
+	return sw.ResponseWriter.Write(p)
+}
+
+// Flush saves the session and flushes the response.
+func (sw *sessionWriter) Flush() {
+	sw.save()
+	_ = http.NewResponseController(sw.ResponseWriter).Flush()
+}
+
+// Unwrap returns the underlying writer for [http.ResponseController].
+func (sw *sessionWriter) Unwrap() http.ResponseWriter {
+	return sw.ResponseWriter
+}
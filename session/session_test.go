@@ -0,0 +1,215 @@
+package session
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/eliofery/wayes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newRouter creates a router with the session middleware and handlers for the session operations.
+func newRouter(cfg Config) wayes.Wayes {
+	rt := wayes.New()
+	rt.SetCookieKeys([]byte("secret"))
+	rt.Use(Middleware(cfg))
+
+	rt.Get("/get", func(ctx wayes.Ctx) error {
+		value, _ := From(ctx).Get("user").(string)
+		return ctx.Write(value)
+	})
+	rt.Get("/id", func(ctx wayes.Ctx) error {
+		return ctx.Write(From(ctx).ID())
+	})
+	rt.Post("/set", func(ctx wayes.Ctx) error {
+		From(ctx).Set("user", "alice")
+		return ctx.Write("ok")
+	})
+	rt.Post("/login", func(ctx wayes.Ctx) error {
+		sess := From(ctx)
+		if err := sess.Regenerate(); err != nil {
+			return err
+		}
+		sess.Set("user", "admin")
+
+		return ctx.Write(sess.ID())
+	})
+	rt.Post("/logout", func(ctx wayes.Ctx) error {
+		if err := From(ctx).Destroy(); err != nil {
+			return err
+		}
+
+		return ctx.SendStatus(http.StatusNoContent)
+	})
+
+	return rt
+}
+
+// request sends the request with the cookies and returns the response.
+func request(rt wayes.Wayes, method, path string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+
+	rr := httptest.NewRecorder()
+	rt.Mux().ServeHTTP(rr, req)
+
+	return rr
+}
+
+// sessionCookie returns the session cookie set by the response, nil if there is none.
+func sessionCookie(rr *httptest.ResponseRecorder) *http.Cookie {
+	for _, cookie := range rr.Result().Cookies() {
+		if cookie.Name == "session" {
+			return cookie
+		}
+	}
+
+	return nil
+}
+
+// TestMiddleware tests the loading and saving of sessions with the stores.
+func TestMiddleware(t *testing.T) {
+	stores := map[string]Store{
+		"Memory store": NewMemoryStore(),
+		"Cookie store": NewCookieStore(),
+	}
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			rt := newRouter(Config{Store: store})
+
+			rr := request(rt, http.MethodGet, "/get")
+			assert.Equal(t, http.StatusOK, rr.Code)
+			assert.Nil(t, sessionCookie(rr), "empty new session must not be saved")
+
+			rr = request(rt, http.MethodPost, "/set")
+			cookie := sessionCookie(rr)
+			require.NotNil(t, cookie)
+			assert.True(t, cookie.HttpOnly)
+			assert.Equal(t, http.SameSiteLaxMode, cookie.SameSite)
+
+			rr = request(rt, http.MethodGet, "/get", cookie)
+			assert.Equal(t, "alice", rr.Body.String())
+			assert.Nil(t, sessionCookie(rr), "unmodified session must not be saved")
+
+			tampered := *cookie
+			tampered.Value = "A" + cookie.Value[1:]
+			if cookie.Value[0] == 'A' {
+				tampered.Value = "B" + cookie.Value[1:]
+			}
+
+			rr = request(rt, http.MethodGet, "/get", &tampered)
+			assert.Empty(t, rr.Body.String())
+		})
+	}
+}
+
+// TestSession_Regenerate tests that the regenerated session keeps values and invalidates the old identifier.
+func TestSession_Regenerate(t *testing.T) {
+	rt := newRouter(Config{})
+
+	cookie := sessionCookie(request(rt, http.MethodPost, "/set"))
+	require.NotNil(t, cookie)
+	oldID := request(rt, http.MethodGet, "/id", cookie).Body.String()
+
+	rr := request(rt, http.MethodPost, "/login", cookie)
+	newCookie := sessionCookie(rr)
+	require.NotNil(t, newCookie)
+	assert.NotEqual(t, oldID, rr.Body.String())
+
+	assert.Equal(t, "admin", request(rt, http.MethodGet, "/get", newCookie).Body.String())
+	assert.Empty(t, request(rt, http.MethodGet, "/get", cookie).Body.String(), "old session must be removed")
+}
+
+// TestSession_Destroy tests that the destroyed session is removed with its cookie.
+func TestSession_Destroy(t *testing.T) {
+	rt := newRouter(Config{})
+
+	cookie := sessionCookie(request(rt, http.MethodPost, "/set"))
+	require.NotNil(t, cookie)
+
+	rr := request(rt, http.MethodPost, "/logout", cookie)
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+
+	cleared := sessionCookie(rr)
+	require.NotNil(t, cleared)
+	assert.Equal(t, -1, cleared.MaxAge)
+
+	assert.Empty(t, request(rt, http.MethodGet, "/get", cookie).Body.String())
+}
+
+// TestMiddleware_expiration tests the idle and absolute expiration and the rolling renewal of sessions.
+func TestMiddleware_expiration(t *testing.T) {
+	cases := []struct {
+		name          string
+		rolling       bool
+		accessed      time.Duration
+		created       time.Duration
+		exceptedValue string
+		exceptedSaved bool
+	}{
+		{
+			name:          "Active session",
+			accessed:      -time.Minute,
+			created:       -time.Hour,
+			exceptedValue: "alice",
+		},
+		{
+			name:          "Rolling renewal",
+			rolling:       true,
+			accessed:      -time.Minute,
+			created:       -time.Hour,
+			exceptedValue: "alice",
+			exceptedSaved: true,
+		},
+		{
+			name:     "Idle timeout",
+			accessed: -time.Hour,
+			created:  -time.Hour,
+		},
+		{
+			name:     "Absolute timeout",
+			rolling:  true,
+			accessed: -time.Minute,
+			created:  -48 * time.Hour,
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			store := NewMemoryStore().(*memoryStore)
+			rt := newRouter(Config{Store: store, Rolling: test.rolling})
+
+			cookie := sessionCookie(request(rt, http.MethodPost, "/set"))
+			require.NotNil(t, cookie)
+
+			for _, entry := range store.sessions {
+				entry.data.Accessed = time.Now().Add(test.accessed)
+				entry.data.Created = time.Now().Add(test.created)
+			}
+
+			rr := request(rt, http.MethodGet, "/get", cookie)
+			assert.Equal(t, test.exceptedValue, rr.Body.String())
+			assert.Equal(t, test.exceptedSaved, sessionCookie(rr) != nil)
+		})
+	}
+}
+
+// TestMiddleware_errorCookieKeys tests that the middleware requires the cookie keys of the router.
+func TestMiddleware_errorCookieKeys(t *testing.T) {
+	rt := wayes.New()
+	rt.Use(Middleware())
+	rt.Get("/test", func(ctx wayes.Ctx) error {
+		return ctx.Write("Test session")
+	})
+
+	rr := request(rt, http.MethodGet, "/test")
+
+	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	assert.Equal(t, wayes.ErrCookieKeys.Error()+"\n", rr.Body.String())
+}
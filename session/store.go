@@ -0,0 +1,154 @@
+package session
+
+import (
+	"encoding/json"
+	"maps"
+	"sync"
+	"time"
+)
+
+// gcInterval is the interval between removals of expired sessions from the in-memory store.
+const gcInterval = time.Minute
+
+// Data represents a structure for the data of a session kept in a [Store].
+type Data struct {
+	// ID is the identifier of the session.
+	ID string `json:"id"`
+
+	// Values holds the values of the session.
+	Values map[string]any `json:"values"`
+
+	// Created is the time the session was created or regenerated.
+	Created time.Time `json:"created"`
+
+	// Accessed is the time the session was saved last.
+	Accessed time.Time `json:"accessed"`
+}
+
+// Store is an interface that defines methods for keeping the session data.
+// Implementations must be safe for concurrent use.
+type Store interface {
+	// Load returns the session data by the token from the session cookie, nil if there is none.
+	Load(token string) (*Data, error)
+
+	// Save stores the session data for the duration of ttl and returns the token for the session cookie.
+	Save(data *Data, ttl time.Duration) (string, error)
+
+	// Delete removes the session data by the session identifier.
+	Delete(id string) error
+}
+
+// memoryEntry represents a structure for a session kept in the in-memory store.
+type memoryEntry struct {
+	data    *Data
+	expires time.Time
+}
+
+// memoryStore represents a structure that implements the [Store] interface keeping sessions in memory.
+type memoryStore struct {
+	mu       sync.Mutex
+	sessions map[string]*memoryEntry
+	lastGC   time.Time
+	now      func() time.Time
+}
+
+// NewMemoryStore creates a new server-side [Store] that keeps sessions in memory.
+// The session cookie contains only the session identifier.
+func NewMemoryStore() Store {
+	return &memoryStore{
+		sessions: make(map[string]*memoryEntry),
+		now:      time.Now,
+	}
+}
+
+// Load returns a copy of the session data by the session identifier.
+func (s *memoryStore) Load(token string) (*Data, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.sessions[token]
+	if !ok || !s.now().Before(entry.expires) {
+		return nil, nil
+	}
+
+	data := *entry.data
+	data.Values = maps.Clone(entry.data.Values)
+
+	return &data, nil
+}
+
+// Save stores the session data and returns the session identifier as the token.
+func (s *memoryStore) Save(data *Data, ttl time.Duration) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.gc(now)
+
+	s.sessions[data.ID] = &memoryEntry{
+		data:    data,
+		expires: now.Add(ttl),
+	}
+
+	return data.ID, nil
+}
+
+// Delete removes the session data by the session identifier.
+func (s *memoryStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.sessions, id)
+
+	return nil
+}
+
+// gc removes the expired sessions, the caller must hold the lock.
+func (s *memoryStore) gc(now time.Time) {
+	if now.Sub(s.lastGC) < gcInterval {
+		return
+	}
+	s.lastGC = now
+
+	for id, entry := range s.sessions {
+		if !now.Before(entry.expires) {
+			delete(s.sessions, id)
+		}
+	}
+}
+
+// cookieStore represents a structure that implements the [Store] interface keeping sessions in the cookie.
+type cookieStore struct{}
+
+// NewCookieStore creates a new client-side [Store] that keeps the whole session in the encrypted session cookie.
+// The values are encoded as JSON, so they are limited by the cookie size and numbers are decoded as float64.
+// Sessions cannot be revoked on the server, rely on short timeouts.
+func NewCookieStore() Store {
+	return cookieStore{}
+}
+
+// Load decodes the session data from the token.
+// Tokens that cannot be decoded are ignored, so a new session is created.
+func (cookieStore) Load(token string) (*Data, error) {
+	var data Data
+	if err := json.Unmarshal([]byte(token), &data); err != nil {
+		return nil, nil
+	}
+
+	return &data, nil
+}
+
+// Save encodes the session data into the token.
+func (cookieStore) Save(data *Data, _ time.Duration) (string, error) {
+	token, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+
+	return string(token), nil
+}
+
+// Delete does nothing, the session is removed together with the cookie.
+func (cookieStore) Delete(string) error {
+	return nil
+}
@@ -0,0 +1,58 @@
+package session
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMemoryStore tests the expiration and removal of sessions in the in-memory store.
+func TestMemoryStore(t *testing.T) {
+	now := time.Now()
+	store := NewMemoryStore().(*memoryStore)
+	store.now = func() time.Time { return now }
+
+	token, err := store.Save(&Data{ID: "id", Values: map[string]any{"user": "alice"}}, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, "id", token)
+
+	data, err := store.Load(token)
+	require.NoError(t, err)
+	require.NotNil(t, data)
+	assert.Equal(t, "alice", data.Values["user"])
+
+	data.Values["user"] = "bob"
+	data, _ = store.Load(token)
+	assert.Equal(t, "alice", data.Values["user"], "loaded data must be a copy")
+
+	now = now.Add(time.Minute)
+	data, _ = store.Load(token)
+	assert.Nil(t, data, "expired session must not be returned")
+
+	_, _ = store.Save(&Data{ID: "other"}, time.Minute)
+	assert.Len(t, store.sessions, 1, "expired session must be garbage-collected")
+
+	require.NoError(t, store.Delete("other"))
+	assert.Empty(t, store.sessions)
+}
+
+// TestCookieStore tests the encoding of sessions into the cookie token.
+func TestCookieStore(t *testing.T) {
+	store := NewCookieStore()
+
+	created := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	token, err := store.Save(&Data{ID: "id", Values: map[string]any{"count": 1}, Created: created}, time.Minute)
+	require.NoError(t, err)
+
+	data, err := store.Load(token)
+	require.NoError(t, err)
+	assert.Equal(t, "id", data.ID)
+	assert.Equal(t, float64(1), data.Values["count"])
+	assert.True(t, created.Equal(data.Created))
+
+	data, err = store.Load("invalid")
+	assert.NoError(t, err)
+	assert.Nil(t, data)
+}
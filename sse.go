@@ -0,0 +1,198 @@
+package wayes
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SSEConfig represents a structure for the [Ctx.SSE] configuration.
+type SSEConfig struct {
+	// Heartbeat is the interval between comments sent to keep the connection open. Defaults to 15 seconds,
+	// a negative value disables heartbeats.
+	Heartbeat time.Duration
+}
+
+// Event represents a structure for a server-sent event.
+type Event struct {
+	// ID is the identifier of the event, which the client sends back in the Last-Event-ID header on reconnect.
+	ID string
+
+	// Name is the type of the event, the client dispatches events without a name as "message".
+	Name string
+
+	// Data is the payload of the event, multi-line data is sent in several data fields.
+	Data string
+
+	// Retry is the reconnection time of the client.
+	Retry time.Duration
+}
+
+// EventStream represents a structure for the stream of server-sent events of a request.
+// It is safe for concurrent use.
+type EventStream struct {
+	mu          sync.Mutex
+	w           http.ResponseWriter
+	controller  *http.ResponseController
+	ctx         context.Context
+	lastEventID string
+}
+
+// SSE streams server-sent events to the client until the handler returns or the request context is cancelled.
+// The response is flushed after every event and heartbeat comments keep the connection open through proxies.
+// The disconnection of the client is not reported as an error.
+func (c *ctx) SSE(handler func(stream *EventStream) error, config ...SSEConfig) error {
+	cfg := SSEConfig{}
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+
+	if cfg.Heartbeat == 0 {
+		cfg.Heartbeat = 15 * time.Second
+	}
+
+	header := c.response.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("X-Accel-Buffering", "no")
+	header.Del("Content-Length")
+	if c.request.ProtoMajor == 1 {
+		header.Set("Connection", "keep-alive")
+	}
+
+	stream := &EventStream{
+		w:           c.response,
+		controller:  http.NewResponseController(c.response),
+		ctx:         c.request.Context(),
+		lastEventID: c.request.Header.Get("Last-Event-ID"),
+	}
+
+	c.response.WriteHeader(http.StatusOK)
+	c.committed.Store(true)
+	if err := stream.controller.Flush(); err != nil {
+		return err
+	}
+
+	if cfg.Heartbeat > 0 {
+		var wg sync.WaitGroup
+		done := make(chan struct{})
+		defer func() {
+			close(done)
+			wg.Wait()
+		}()
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			stream.heartbeat(cfg.Heartbeat, done)
+		}()
+	}
+
+	err := handler(stream)
+	if errors.Is(err, context.Canceled) && stream.ctx.Err() != nil {
+		return nil
+	}
+
+	return err
+}
+
+// heartbeat sends comments with the interval until done is closed or the request context is cancelled.
+func (s *EventStream) heartbeat(interval time.Duration, done <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Comment("heartbeat"); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// LastEventID returns the value of the Last-Event-ID request header sent by the client on reconnect.
+func (s *EventStream) LastEventID() string {
+	return s.lastEventID
+}
+
+// Context returns the context of the request, which is cancelled when the client disconnects.
+func (s *EventStream) Context() context.Context {
+	return s.ctx
+}
+
+// Done returns a channel that is closed when the client disconnects.
+func (s *EventStream) Done() <-chan struct{} {
+	return s.ctx.Done()
+}
+
+// Send sends the event and flushes it to the client.
+// It returns the error of the request context if the client has disconnected.
+func (s *EventStream) Send(event Event) error {
+	var b strings.Builder
+
+	if event.ID != "" {
+		b.WriteString("id: " + sanitizeField(event.ID) + "\n")
+	}
+
+	if event.Name != "" {
+		b.WriteString("event: " + sanitizeField(event.Name) + "\n")
+	}
+
+	if event.Retry > 0 {
+		b.WriteString("retry: " + strconv.FormatInt(event.Retry.Milliseconds(), 10) + "\n")
+	}
+
+	data := strings.ReplaceAll(strings.ReplaceAll(event.Data, "\r\n", "\n"), "\r", "\n")
+	for _, line := range strings.Split(data, "\n") {
+		b.WriteString("data: " + line + "\n")
+	}
+	b.WriteString("\n")
+
+	return s.write(b.String())
+}
+
+// Data sends an event with the data only.
+func (s *EventStream) Data(data string) error {
+	return s.Send(Event{Data: data})
+}
+
+// Comment sends a comment, which is ignored by the client.
+func (s *EventStream) Comment(text string) error {
+	var b strings.Builder
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r", ""), "\n") {
+		b.WriteString(": " + line + "\n")
+	}
+	b.WriteString("\n")
+
+	return s.write(b.String())
+}
+
+// write writes the message and flushes it to the client.
+func (s *EventStream) write(message string) error {
+	if err := s.ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.w.Write([]byte(message)); err != nil {
+		return err
+	}
+
+	return s.controller.Flush()
+}
+
+// sanitizeField removes line breaks that would terminate the field and null characters.
+func sanitizeField(value string) string {
+	return strings.NewReplacer("\r", "", "\n", "", "\x00", "").Replace(value)
+}
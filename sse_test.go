@@ -0,0 +1,104 @@
+package wayes
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCtxSSE tests the encoding of server-sent events.
+func TestCtxSSE(t *testing.T) {
+	rt := New()
+	rt.Get("/events", func(ctx Ctx) error {
+		return ctx.SSE(func(stream *EventStream) error {
+			if err := stream.Send(Event{ID: "1", Name: "update", Data: "line 1\nline 2\r\nline 3", Retry: 3 * time.Second}); err != nil {
+				return err
+			}
+
+			if err := stream.Comment("keep"); err != nil {
+				return err
+			}
+
+			return stream.Data(stream.LastEventID())
+		}, SSEConfig{Heartbeat: -1})
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/events", nil)
+	req.Header.Set("Last-Event-ID", "41")
+
+	rr := httptest.NewRecorder()
+	rt.Mux().ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "text/event-stream", rr.Header().Get("Content-Type"))
+	assert.Equal(t, "no-cache", rr.Header().Get("Cache-Control"))
+	assert.Equal(t, "no", rr.Header().Get("X-Accel-Buffering"))
+	assert.True(t, rr.Flushed)
+	assert.Equal(t, "id: 1\nevent: update\nretry: 3000\ndata: line 1\ndata: line 2\ndata: line 3\n\n"+
+		": keep\n\n"+
+		"data: 41\n\n", rr.Body.String())
+}
+
+// TestCtxSSE_heartbeat tests the heartbeats and the stopping of the stream when the client disconnects.
+func TestCtxSSE_heartbeat(t *testing.T) {
+	stopped := make(chan error, 1)
+
+	rt := New()
+	rt.Get("/events", func(ctx Ctx) error {
+		err := ctx.SSE(func(stream *EventStream) error {
+			if err := stream.Data("hello"); err != nil {
+				return err
+			}
+
+			<-stream.Done()
+
+			return stream.Data("late")
+		}, SSEConfig{Heartbeat: 10 * time.Millisecond})
+
+		stopped <- err
+
+		return err
+	})
+
+	server := httptest.NewServer(rt.Mux())
+	defer server.Close()
+
+	reqCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, server.URL+"/events", nil)
+	require.NoError(t, err)
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	reader := bufio.NewReader(resp.Body)
+	var lines []string
+	for len(lines) < 4 {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	assert.Equal(t, "data: hello", lines[0])
+	assert.Equal(t, []string{": heartbeat", ": heartbeat", ": heartbeat"}, lines[1:])
+
+	cancel()
+
+	select {
+	case err := <-stopped:
+		assert.NoError(t, err, "disconnection must not be reported as an error")
+	case <-time.After(time.Second):
+		t.Fatal("stream is not stopped after the client disconnected")
+	}
+}
@@ -0,0 +1,191 @@
+package wayes
+
+import (
+	"fmt"
+	"html"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StaticConfig represents a structure for the [Wayes.Static] configuration.
+type StaticConfig struct {
+	// Index is the name of the file served for directories. Defaults to "index.html".
+	Index string
+
+	// Browse enables the listing of directories without the index file.
+	Browse bool
+
+	// MaxAge sets the Cache-Control header with the public and max-age directives for the files.
+	MaxAge time.Duration
+
+	// Precompressed serves the ".br" and ".gz" siblings of the files to clients that accept the encodings.
+	Precompressed bool
+
+	// SPA serves the index file of the root directory for unknown paths, so a single-page application
+	// can handle the routing on the client side.
+	SPA bool
+}
+
+// precompressedEncodings is the list of encodings of the precompressed files in the order of preference.
+var precompressedEncodings = []struct {
+	encoding  string
+	extension string
+}{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+// Static registers a handler that serves the files of the file system under the prefix.
+// The files are served with [http.ServeContent], so conditional and byte-range requests are supported.
+// Use [fs.Sub] to serve a subdirectory of an [embed.FS].
+func (rt *wayes) Static(prefix string, fsys fs.FS, config ...StaticConfig) {
+	cfg := StaticConfig{}
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+
+	if cfg.Index == "" {
+		cfg.Index = "index.html"
+	}
+
+	rt.Get(strings.TrimSuffix(prefix, "/")+"/{path...}", func(ctx Ctx) error {
+		return cfg.serve(ctx, fsys)
+	})
+}
+
+// serve serves the file or the directory matching the path of the request.
+func (cfg StaticConfig) serve(ctx Ctx, fsys fs.FS) error {
+	name := path.Clean("/" + ctx.Request().PathValue("path"))[1:]
+	if name == "" {
+		name = "."
+	}
+
+	info, err := fs.Stat(fsys, name)
+	if err != nil {
+		if cfg.SPA {
+			return cfg.serveFile(ctx, fsys, cfg.Index, false)
+		}
+
+		return NewError(http.StatusNotFound)
+	}
+
+	if !info.IsDir() {
+		return cfg.serveFile(ctx, fsys, name, true)
+	}
+
+	urlPath := ctx.Request().URL.Path
+	if !strings.HasSuffix(urlPath, "/") {
+		ctx.Set("Location", path.Base(urlPath)+"/")
+		return ctx.SendStatus(http.StatusMovedPermanently)
+	}
+
+	index := path.Join(name, cfg.Index)
+	if _, err := fs.Stat(fsys, index); err == nil {
+		return cfg.serveFile(ctx, fsys, index, true)
+	}
+
+	if cfg.Browse {
+		return browse(ctx, fsys, name)
+	}
+
+	return NewError(http.StatusNotFound)
+}
+
+// serveFile serves the file or its precompressed sibling.
+func (cfg StaticConfig) serveFile(ctx Ctx, fsys fs.FS, name string, cache bool) error {
+	if cache && cfg.MaxAge > 0 {
+		ctx.CacheControl(CacheControl{Public: true, MaxAge: cfg.MaxAge})
+	}
+
+	file := name
+	if cfg.Precompressed {
+		ctx.Vary("Accept-Encoding")
+
+		accept := ctx.Request().Header.Get("Accept-Encoding")
+		for _, pre := range precompressedEncodings {
+			if !acceptsEncoding(accept, pre.encoding) {
+				continue
+			}
+
+			if info, err := fs.Stat(fsys, name+pre.extension); err == nil && !info.IsDir() {
+				file = name + pre.extension
+				ctx.Set("Content-Encoding", pre.encoding)
+
+				contentType := mime.TypeByExtension(path.Ext(name))
+				if contentType == "" {
+					contentType = "application/octet-stream"
+				}
+				ctx.ContentType(contentType)
+
+				break
+			}
+		}
+	}
+
+	f, err := fsys.Open(file)
+	if err != nil {
+		return NewError(http.StatusNotFound)
+	}
+	defer f.Close()
+
+	return serveContent(ctx, f, name)
+}
+
+// browse serves the listing of the directory as an HTML page.
+// The listing is sent with [http.ServeContent] like the files, so conditional and HEAD requests are supported.
+func browse(ctx Ctx, fsys fs.FS, name string) error {
+	info, err := fs.Stat(fsys, name)
+	if err != nil {
+		return err
+	}
+
+	entries, err := fs.ReadDir(fsys, name)
+	if err != nil {
+		return err
+	}
+
+	var listing strings.Builder
+	listing.WriteString("<!doctype html>\n<meta name=\"viewport\" content=\"width=device-width\">\n<pre>\n")
+	for _, entry := range entries {
+		entryName := entry.Name()
+		if entry.IsDir() {
+			entryName += "/"
+		}
+
+		link := url.URL{Path: entryName}
+		fmt.Fprintf(&listing, "<a href=\"%s\">%s</a>\n", html.EscapeString(link.String()), html.EscapeString(entryName))
+	}
+	listing.WriteString("</pre>\n")
+
+	ctx.ContentType("text/html; charset=utf-8")
+	http.ServeContent(ctx.Response(), ctx.Request(), "", info.ModTime(), strings.NewReader(listing.String()))
+
+	return nil
+}
+
+// acceptsEncoding reports whether the Accept-Encoding header accepts the encoding with a non-zero quality.
+func acceptsEncoding(accept, encoding string) bool {
+	for _, part := range strings.Split(accept, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if !strings.EqualFold(strings.TrimSpace(name), encoding) {
+			continue
+		}
+
+		q, ok := strings.CutPrefix(strings.TrimSpace(params), "q=")
+		if !ok {
+			return true
+		}
+
+		quality, err := strconv.ParseFloat(q, 64)
+
+		return err == nil && quality > 0
+	}
+
+	return false
+}
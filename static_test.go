@@ -0,0 +1,182 @@
+package wayes
+
+import (
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestWayesStatic tests the serving of files and directories.
+func TestWayesStatic(t *testing.T) {
+	modTime := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	fsys := fstest.MapFS{
+		"docs":               {Mode: fs.ModeDir, ModTime: modTime},
+		"index.html":         {Data: []byte("<h1>Home</h1>")},
+		"css/app.css":        {Data: []byte("body{}")},
+		"css/app.css.gz":     {Data: []byte("gzip body")},
+		"css/app.css.br":     {Data: []byte("brotli body")},
+		"docs/a&b.txt":       {Data: []byte("text")},
+		"docs/guide/1.txt":   {Data: []byte("guide")},
+		"empty/.placeholder": {Data: []byte("")},
+	}
+
+	cases := []struct {
+		name            string
+		path            string
+		headers         map[string]string
+		exceptedCode    int
+		exceptedBody    string
+		exceptedHeaders map[string]string
+	}{
+		{
+			name:         "Index file",
+			path:         "/static/",
+			exceptedCode: http.StatusOK,
+			exceptedBody: "<h1>Home</h1>",
+			exceptedHeaders: map[string]string{
+				"Content-Type":  "text/html; charset=utf-8",
+				"Cache-Control": "public, max-age=3600",
+			},
+		},
+		{
+			name:         "File",
+			path:         "/static/css/app.css",
+			exceptedCode: http.StatusOK,
+			exceptedBody: "body{}",
+			exceptedHeaders: map[string]string{
+				"Content-Type":     "text/css; charset=utf-8",
+				"Content-Encoding": "",
+				"Vary":             "Accept-Encoding",
+			},
+		},
+		{
+			name:         "Precompressed brotli",
+			path:         "/static/css/app.css",
+			headers:      map[string]string{"Accept-Encoding": "gzip, br"},
+			exceptedCode: http.StatusOK,
+			exceptedBody: "brotli body",
+			exceptedHeaders: map[string]string{
+				"Content-Type":     "text/css; charset=utf-8",
+				"Content-Encoding": "br",
+			},
+		},
+		{
+			name:         "Precompressed gzip",
+			path:         "/static/css/app.css",
+			headers:      map[string]string{"Accept-Encoding": "gzip, br;q=0"},
+			exceptedCode: http.StatusOK,
+			exceptedBody: "gzip body",
+			exceptedHeaders: map[string]string{
+				"Content-Encoding": "gzip",
+			},
+		},
+		{
+			name:         "Byte range",
+			path:         "/static/css/app.css",
+			headers:      map[string]string{"Range": "bytes=0-3"},
+			exceptedCode: http.StatusPartialContent,
+			exceptedBody: "body",
+			exceptedHeaders: map[string]string{
+				"Content-Range": "bytes 0-3/6",
+			},
+		},
+		{
+			name:         "Directory listing",
+			path:         "/static/docs/",
+			exceptedCode: http.StatusOK,
+			exceptedBody: "<!doctype html>\n<meta name=\"viewport\" content=\"width=device-width\">\n<pre>\n" +
+				"<a href=\"a&amp;b.txt\">a&amp;b.txt</a>\n<a href=\"guide/\">guide/</a>\n</pre>\n",
+			exceptedHeaders: map[string]string{
+				"Content-Type":  "text/html; charset=utf-8",
+				"Last-Modified": modTime.Format(http.TimeFormat),
+			},
+		},
+		{
+			name:         "Directory listing not modified",
+			path:         "/static/docs/",
+			headers:      map[string]string{"If-Modified-Since": modTime.Format(http.TimeFormat)},
+			exceptedCode: http.StatusNotModified,
+		},
+		{
+			name:         "Directory redirect",
+			path:         "/static/docs",
+			exceptedCode: http.StatusMovedPermanently,
+			exceptedBody: "Moved Permanently",
+			exceptedHeaders: map[string]string{
+				"Location": "docs/",
+			},
+		},
+		{
+			name:         "SPA fallback",
+			path:         "/static/users/42",
+			exceptedCode: http.StatusOK,
+			exceptedBody: "<h1>Home</h1>",
+			exceptedHeaders: map[string]string{
+				"Cache-Control": "",
+			},
+		},
+		{
+			name:         "Path traversal",
+			path:         "/static/..%2f..%2fstatic_test.go",
+			exceptedCode: http.StatusOK,
+			exceptedBody: "<h1>Home</h1>",
+		},
+	}
+
+	rt := New()
+	rt.Use(func(ctx Ctx) error {
+		ctx.Set("X-Middleware", "true")
+		return ctx.Next()
+	})
+	rt.Static("/static", fsys, StaticConfig{
+		Browse:        true,
+		MaxAge:        time.Hour,
+		Precompressed: true,
+		SPA:           true,
+	})
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, test.path, nil)
+			for key, value := range test.headers {
+				req.Header.Set(key, value)
+			}
+
+			rr := httptest.NewRecorder()
+			rt.Mux().ServeHTTP(rr, req)
+
+			assert.Equal(t, test.exceptedCode, rr.Code)
+			assert.Equal(t, test.exceptedBody, rr.Body.String())
+			for key, value := range test.exceptedHeaders {
+				assert.Equal(t, value, rr.Header().Get(key), key)
+			}
+			assert.Equal(t, "true", rr.Header().Get("X-Middleware"))
+		})
+	}
+}
+
+// TestWayesStatic_notFound tests the responses for unknown paths and directories without index files.
+func TestWayesStatic_notFound(t *testing.T) {
+	rt := New()
+	rt.Static("/", fstest.MapFS{"docs/readme.txt": {Data: []byte("readme")}})
+
+	for _, path := range []string{"/missing.txt", "/docs/"} {
+		rr := httptest.NewRecorder()
+		rt.Mux().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+
+		assert.Equal(t, http.StatusNotFound, rr.Code, path)
+	}
+
+	rr := httptest.NewRecorder()
+	rt.Mux().ServeHTTP(rr, httptest.NewRequest(http.MethodHead, "/docs/readme.txt", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Empty(t, rr.Body.String())
+	assert.Equal(t, "6", rr.Header().Get("Content-Length"))
+}
@@ -0,0 +1,180 @@
+package wayes
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// StreamJSONConfig represents a structure for the [Ctx.StreamJSON] configuration.
+type StreamJSONConfig struct {
+	// Array streams the values as the elements of a JSON array instead of newline-delimited JSON.
+	Array bool
+
+	// FlushInterval is the minimum interval between flushes of the encoded values to the client.
+	// Defaults to 1 second, a negative value flushes every value.
+	FlushInterval time.Duration
+}
+
+// StreamEncoder represents a structure that encodes the values of a JSON stream.
+type StreamEncoder struct {
+	w        *bufio.Writer
+	buf      bytes.Buffer
+	ctx      context.Context
+	array    bool
+	count    int
+	interval time.Duration
+	flushed  time.Time
+}
+
+// flushWriter represents a writer that flushes every write to the client and stops when the request is cancelled.
+type flushWriter struct {
+	w          http.ResponseWriter
+	controller *http.ResponseController
+	ctx        context.Context
+}
+
+// Write writes the data to the response and flushes it.
+func (fw flushWriter) Write(p []byte) (int, error) {
+	if err := fw.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	n, err := fw.w.Write(p)
+	if err != nil {
+		return n, err
+	}
+
+	if err = fw.controller.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return n, err
+	}
+
+	return n, nil
+}
+
+// stream writes the header of the streamed response and returns the buffered writer of its body.
+func (c *ctx) stream(contentType string) *bufio.Writer {
+	c.ContentType(contentType)
+	c.response.Header().Del("Content-Length")
+	c.response.WriteHeader(c.status)
+	c.committed.Store(true)
+
+	return bufio.NewWriterSize(flushWriter{
+		w:          c.response,
+		controller: http.NewResponseController(c.response),
+		ctx:        c.request.Context(),
+	}, streamBufferSize)
+}
+
+// StreamJSON streams the values encoded by the handler as newline-delimited JSON or as a JSON array.
+// The values are buffered and flushed to the client periodically and when the handler returns.
+// The status is sent before the handler runs, so an error returned by the handler ends the stream:
+// the buffered values are flushed and the array is closed. Cancellation of the request by the client
+// is not reported as an error.
+func (c *ctx) StreamJSON(handler func(enc *StreamEncoder) error, config ...StreamJSONConfig) error {
+	cfg := StreamJSONConfig{}
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+
+	if cfg.FlushInterval == 0 {
+		cfg.FlushInterval = time.Second
+	}
+
+	contentType := "application/x-ndjson"
+	if cfg.Array {
+		contentType = "application/json"
+	}
+
+	enc := &StreamEncoder{
+		w:        c.stream(contentType),
+		ctx:      c.request.Context(),
+		array:    cfg.Array,
+		interval: cfg.FlushInterval,
+		flushed:  time.Now(),
+	}
+
+	if enc.array {
+		_ = enc.w.WriteByte('[')
+	}
+
+	err := handler(enc)
+
+	if enc.array {
+		_, _ = enc.w.WriteString("]\n")
+	}
+
+	if flushErr := enc.Flush(); err == nil {
+		err = flushErr
+	}
+
+	if errors.Is(err, context.Canceled) && enc.ctx.Err() != nil {
+		return nil
+	}
+
+	return err
+}
+
+// Encode writes the value to the stream, flushing the buffered values if the flush interval has elapsed.
+// It returns the error of the request context when the client disconnects.
+func (e *StreamEncoder) Encode(value any) error {
+	if err := e.ctx.Err(); err != nil {
+		return err
+	}
+
+	e.buf.Reset()
+	if err := encode(&e.buf, value, ""); err != nil {
+		return err
+	}
+
+	data := e.buf.Bytes()
+	if e.array {
+		data = bytes.TrimSuffix(data, []byte("\n"))
+		if e.count > 0 {
+			_ = e.w.WriteByte(',')
+		}
+	}
+	_, _ = e.w.Write(data)
+	e.count++
+
+	if time.Since(e.flushed) >= e.interval {
+		return e.Flush()
+	}
+
+	return nil
+}
+
+// Count returns the number of the encoded values.
+func (e *StreamEncoder) Count() int {
+	return e.count
+}
+
+// Flush sends the buffered values to the client.
+func (e *StreamEncoder) Flush() error {
+	e.flushed = time.Now()
+
+	return e.w.Flush()
+}
+
+// StreamWriter streams the data written by the handler to the buffered writer.
+// The data is sent to the client when the buffer is full, when [bufio.Writer.Flush] is called and when the handler
+// returns, also with an error. Writes fail with the error of the request context when the client disconnects.
+// The Content-Type defaults to application/octet-stream.
+func (c *ctx) StreamWriter(handler func(w *bufio.Writer) error) error {
+	contentType := c.response.Header().Get("Content-Type")
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	w := c.stream(contentType)
+
+	err := handler(w)
+	if flushErr := w.Flush(); err == nil {
+		err = flushErr
+	}
+
+	return err
+}
@@ -0,0 +1,277 @@
+package wayes
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCtxStreamJSON tests the streaming of values as newline-delimited JSON and as a JSON array.
+func TestCtxStreamJSON(t *testing.T) {
+	type item struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+
+	cases := []struct {
+		name         string
+		items        []item
+		config       StreamJSONConfig
+		exceptedType string
+		exceptedBody string
+	}{
+		{
+			name:         "Newline-delimited JSON",
+			items:        []item{{1, "alice"}, {2, "bob"}},
+			exceptedType: "application/x-ndjson",
+			exceptedBody: "{\"id\":1,\"name\":\"alice\"}\n{\"id\":2,\"name\":\"bob\"}\n",
+		},
+		{
+			name:         "JSON array",
+			items:        []item{{1, "alice"}, {2, "bob"}},
+			config:       StreamJSONConfig{Array: true, FlushInterval: -1},
+			exceptedType: "application/json",
+			exceptedBody: "[{\"id\":1,\"name\":\"alice\"},{\"id\":2,\"name\":\"bob\"}]\n",
+		},
+		{
+			name:         "Empty JSON array",
+			config:       StreamJSONConfig{Array: true},
+			exceptedType: "application/json",
+			exceptedBody: "[]\n",
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			c := NewCtx(nil, rr, httptest.NewRequest(http.MethodGet, "/", nil))
+			c.Set("Content-Length", "100")
+
+			err := c.StreamJSON(func(enc *StreamEncoder) error {
+				for _, value := range test.items {
+					if err := enc.Encode(value); err != nil {
+						return err
+					}
+				}
+
+				assert.Equal(t, len(test.items), enc.Count())
+				return nil
+			}, test.config)
+			require.NoError(t, err)
+
+			assert.Equal(t, http.StatusOK, rr.Code)
+			assert.Equal(t, test.exceptedType, rr.Header().Get("Content-Type"))
+			assert.Empty(t, rr.Header().Get("Content-Length"))
+			assert.Equal(t, test.exceptedBody, rr.Body.String())
+			assert.True(t, rr.Flushed)
+		})
+	}
+}
+
+// TestCtxStreamJSON_flush tests that the values are flushed to the client when the flush interval elapses.
+func TestCtxStreamJSON_flush(t *testing.T) {
+	rr := httptest.NewRecorder()
+	c := NewCtx(nil, rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	err := c.StreamJSON(func(enc *StreamEncoder) error {
+		if err := enc.Encode("first"); err != nil {
+			return err
+		}
+		assert.Equal(t, "\"first\"\n", rr.Body.String())
+
+		return nil
+	}, StreamJSONConfig{FlushInterval: -1})
+	require.NoError(t, err)
+
+	rr = httptest.NewRecorder()
+	c = NewCtx(nil, rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	err = c.StreamJSON(func(enc *StreamEncoder) error {
+		if err := enc.Encode("first"); err != nil {
+			return err
+		}
+		assert.Empty(t, rr.Body.String())
+
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "\"first\"\n", rr.Body.String())
+}
+
+// TestCtxStreamJSON_error tests that the stream is flushed and the array is closed when the handler fails.
+func TestCtxStreamJSON_error(t *testing.T) {
+	rr := httptest.NewRecorder()
+	c := NewCtx(nil, rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	handlerErr := errors.New("query failed")
+	err := c.StreamJSON(func(enc *StreamEncoder) error {
+		for i := 0; i < 2; i++ {
+			if err := enc.Encode(i); err != nil {
+				return err
+			}
+		}
+
+		return handlerErr
+	}, StreamJSONConfig{Array: true})
+
+	assert.ErrorIs(t, err, handlerErr)
+	assert.Equal(t, "[0,1]\n", rr.Body.String())
+}
+
+// TestCtxStreamJSON_errorCancelled tests that the encoding stops without an error when the client disconnects.
+func TestCtxStreamJSON_errorCancelled(t *testing.T) {
+	reqCtx, cancel := context.WithCancel(context.Background())
+
+	rr := httptest.NewRecorder()
+	c := NewCtx(nil, rr, httptest.NewRequest(http.MethodGet, "/", nil).WithContext(reqCtx))
+
+	err := c.StreamJSON(func(enc *StreamEncoder) error {
+		for i := 0; ; i++ {
+			if i == 3 {
+				cancel()
+			}
+
+			if err := enc.Encode(i); err != nil {
+				return err
+			}
+		}
+	}, StreamJSONConfig{FlushInterval: -1})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "0\n1\n2\n", rr.Body.String())
+}
+
+// TestCtxStreamWriter tests the streaming of the data written to the buffered writer.
+func TestCtxStreamWriter(t *testing.T) {
+	body := strings.Repeat("line\n", 10000)
+
+	rr := httptest.NewRecorder()
+	c := NewCtx(nil, rr, httptest.NewRequest(http.MethodGet, "/", nil))
+	c.ContentType("text/csv")
+
+	err := c.StreamWriter(func(w *bufio.Writer) error {
+		_, err := w.WriteString(body)
+		return err
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "text/csv", rr.Header().Get("Content-Type"))
+	assert.Equal(t, body, rr.Body.String())
+	assert.True(t, rr.Flushed)
+
+	rr = httptest.NewRecorder()
+	c = NewCtx(nil, rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	handlerErr := errors.New("query failed")
+	err = c.StreamWriter(func(w *bufio.Writer) error {
+		return handlerErr
+	})
+	assert.ErrorIs(t, err, handlerErr)
+	assert.Equal(t, "application/octet-stream", rr.Header().Get("Content-Type"))
+}
+
+// TestCtxStreamWriter_errorCancelled tests that the writes fail when the request context is cancelled.
+func TestCtxStreamWriter_errorCancelled(t *testing.T) {
+	reqCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	rr := httptest.NewRecorder()
+	c := NewCtx(nil, rr, httptest.NewRequest(http.MethodGet, "/", nil).WithContext(reqCtx))
+
+	err := c.StreamWriter(func(w *bufio.Writer) error {
+		_, err := w.WriteString("data")
+		return err
+	})
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, rr.Body.String())
+}
+
+// TestCtxCommitted tests that errors of streamed responses reach the error handler without corrupting the stream.
+func TestCtxCommitted(t *testing.T) {
+	handlerErr := errors.New("query failed")
+
+	cases := []struct {
+		name         string
+		handler      Handler
+		exceptedBody string
+	}{
+		{
+			name: "JSON array",
+			handler: func(ctx Ctx) error {
+				return ctx.StreamJSON(func(enc *StreamEncoder) error {
+					for i := 1; i <= 2; i++ {
+						if err := enc.Encode(i); err != nil {
+							return err
+						}
+					}
+
+					return handlerErr
+				}, StreamJSONConfig{Array: true})
+			},
+			exceptedBody: "[1,2]\n",
+		},
+		{
+			name: "Buffered writer",
+			handler: func(ctx Ctx) error {
+				return ctx.StreamWriter(func(w *bufio.Writer) error {
+					_, _ = w.WriteString("id,name\n")
+					return handlerErr
+				})
+			},
+			exceptedBody: "id,name\n",
+		},
+		{
+			name: "Reader",
+			handler: func(ctx Ctx) error {
+				return ctx.Stream(io.MultiReader(strings.NewReader("chunk"), iotest.ErrReader(handlerErr)), "text/plain")
+			},
+			exceptedBody: "chunk",
+		},
+		{
+			name: "Server-sent events",
+			handler: func(ctx Ctx) error {
+				return ctx.SSE(func(stream *EventStream) error {
+					if err := stream.Data("update"); err != nil {
+						return err
+					}
+
+					return handlerErr
+				}, SSEConfig{Heartbeat: -1})
+			},
+			exceptedBody: "data: update\n\n",
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			rt := New()
+			rt.Get("/stream", test.handler)
+
+			rr := httptest.NewRecorder()
+			rt.Mux().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/stream", nil))
+
+			assert.Equal(t, http.StatusOK, rr.Code)
+			assert.Equal(t, test.exceptedBody, rr.Body.String())
+
+			var handled error
+			rt.SetErrorHandler(func(ctx Ctx, err error) {
+				assert.True(t, ctx.Committed())
+				handled = err
+			})
+			rt.Mux().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/stream", nil))
+
+			assert.ErrorIs(t, handled, handlerErr)
+		})
+	}
+}
@@ -0,0 +1,385 @@
+package wayes
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+var (
+	// errNotMultipart represents an error indicating that the request body is not a multipart form.
+	errNotMultipart = errors.New("not a multipart form")
+
+	// errFileTooLarge represents an error indicating that an uploaded file exceeds the size limit.
+	errFileTooLarge = errors.New("file too large")
+
+	// errFileType represents an error indicating that the type of an uploaded file is not allowed.
+	errFileType = errors.New("file type not allowed")
+)
+
+// sniffLength is the number of bytes used to detect the content type of uploaded files.
+const sniffLength = 512
+
+// MultipartConfig represents a structure for the configuration of multipart uploads.
+type MultipartConfig struct {
+	// MaxMemory is the number of bytes of the form kept in memory, the rest is stored in temporary files.
+	// Defaults to 32 MB.
+	MaxMemory int64
+
+	// MaxFileSize is the maximum size of an uploaded file. Zero means no limit.
+	MaxFileSize int64
+
+	// MaxTotalSize is the maximum size of the request body. Defaults to 64 MB.
+	MaxTotalSize int64
+
+	// AllowedTypes is a list of content types of uploaded files detected by content sniffing,
+	// for example "image/png" or "image/*". An empty list allows all types.
+	AllowedTypes []string
+}
+
+// withDefaults returns a copy of the configuration with the default values.
+func (cfg MultipartConfig) withDefaults() MultipartConfig {
+	if cfg.MaxMemory <= 0 {
+		cfg.MaxMemory = 32 << 20
+	}
+
+	if cfg.MaxTotalSize <= 0 {
+		cfg.MaxTotalSize = 64 << 20
+	}
+
+	return cfg
+}
+
+// allowed reports whether the content type is in the list of the allowed types.
+func (cfg MultipartConfig) allowed(contentType string) bool {
+	if len(cfg.AllowedTypes) == 0 {
+		return true
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	for _, allowed := range cfg.AllowedTypes {
+		if prefix, ok := strings.CutSuffix(allowed, "/*"); ok {
+			if strings.HasPrefix(mediaType, prefix+"/") {
+				return true
+			}
+			continue
+		}
+
+		if strings.EqualFold(mediaType, allowed) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// uploads represents a structure for the multipart form shared between the copies of a context,
+// so the temporary files are removed at the end of the request.
+type uploads struct {
+	form *multipart.Form
+	err  error
+}
+
+// UploadPart represents a structure for a part of a multipart form read by [Ctx.StreamMultipart].
+// Reading a file part fails with an [Error] 413 Request Entity Too Large if it exceeds the size limit.
+type UploadPart struct {
+	// FormName is the name of the form field.
+	FormName string
+
+	// FileName is the name of the uploaded file, empty for other fields.
+	FileName string
+
+	// ContentType is the content type of the uploaded file detected by content sniffing.
+	ContentType string
+
+	reader io.Reader
+}
+
+// Read reads the content of the part.
+func (p *UploadPart) Read(b []byte) (int, error) {
+	return p.reader.Read(b)
+}
+
+// limitedReader represents a structure for a reader that fails when the limit is exceeded.
+type limitedReader struct {
+	reader    io.Reader
+	remaining int64
+}
+
+// Read reads from the underlying reader and fails with an [Error] 413 Request Entity Too Large
+// when the limit is exceeded.
+func (r *limitedReader) Read(b []byte) (int, error) {
+	if r.remaining < 0 {
+		return 0, NewError(http.StatusRequestEntityTooLarge, errFileTooLarge.Error())
+	}
+
+	if int64(len(b)) > r.remaining+1 {
+		b = b[:r.remaining+1]
+	}
+
+	n, err := r.reader.Read(b)
+	r.remaining -= int64(n)
+	if r.remaining < 0 {
+		return n + int(r.remaining), NewError(http.StatusRequestEntityTooLarge, errFileTooLarge.Error())
+	}
+
+	return n, err
+}
+
+// multipartReader returns the reader of the multipart request body limited by the total size.
+func (c *ctx) multipartReader() (*multipart.Reader, error) {
+	mediaType, _, _ := mime.ParseMediaType(c.request.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		return nil, NewError(http.StatusUnsupportedMediaType, errNotMultipart.Error())
+	}
+
+	c.request.Body = http.MaxBytesReader(c.response, c.request.Body, c.settings.multipart.MaxTotalSize)
+
+	reader, err := c.request.MultipartReader()
+	if err != nil {
+		return nil, uploadError(err)
+	}
+
+	return reader, nil
+}
+
+// uploadError maps the errors of reading multipart bodies to an [Error].
+func uploadError(err error) error {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return NewError(http.StatusRequestEntityTooLarge, errBodyTooLarge.Error())
+	}
+
+	var e *Error
+	if errors.As(err, &e) {
+		return err
+	}
+
+	return NewError(http.StatusBadRequest, err.Error())
+}
+
+// MultipartForm parses the multipart form of the request.
+// Files exceeding the memory limit are stored in temporary files, which are removed at the end of the request.
+// The size and the content type of the files are checked according to the multipart configuration of the router,
+// the form already parsed by [http.Request.ParseMultipartForm] is checked as well.
+func (c *ctx) MultipartForm() (*multipart.Form, error) {
+	if c.uploads.form == nil && c.uploads.err == nil {
+		c.uploads.form, c.uploads.err = c.parseMultipartForm()
+	}
+
+	if c.uploads.err != nil {
+		return nil, c.uploads.err
+	}
+
+	return c.uploads.form, nil
+}
+
+// parseMultipartForm reads the multipart form and checks its files.
+// The form already parsed by [http.Request.ParseMultipartForm] bypassed the total size limit, so its size is checked.
+// The form is returned along with the error of the check, so its temporary files are removed.
+func (c *ctx) parseMultipartForm() (*multipart.Form, error) {
+	cfg := c.settings.multipart
+
+	form := c.request.MultipartForm
+	if form == nil {
+		reader, err := c.multipartReader()
+		if err != nil {
+			return nil, err
+		}
+
+		form, err = reader.ReadForm(cfg.MaxMemory)
+		if err != nil {
+			return nil, uploadError(err)
+		}
+	} else if formSize(form) > cfg.MaxTotalSize {
+		return form, NewError(http.StatusRequestEntityTooLarge, errBodyTooLarge.Error())
+	}
+
+	for _, files := range form.File {
+		for _, fh := range files {
+			if cfg.MaxFileSize > 0 && fh.Size > cfg.MaxFileSize {
+				return form, NewError(http.StatusRequestEntityTooLarge, errFileTooLarge.Error())
+			}
+
+			contentType, err := sniffFile(fh)
+			if err != nil {
+				return form, err
+			}
+
+			if !cfg.allowed(contentType) {
+				return form, NewError(http.StatusUnsupportedMediaType, errFileType.Error())
+			}
+		}
+	}
+
+	c.setForm(form)
+
+	return form, nil
+}
+
+// formSize returns the size of the values and the files of the multipart form.
+func formSize(form *multipart.Form) int64 {
+	var size int64
+	for key, values := range form.Value {
+		for _, value := range values {
+			size += int64(len(key) + len(value))
+		}
+	}
+
+	for _, files := range form.File {
+		for _, fh := range files {
+			size += fh.Size
+		}
+	}
+
+	return size
+}
+
+// setForm stores the multipart form in the request, so [http.Request.FormValue] returns its values.
+func (c *ctx) setForm(form *multipart.Form) {
+	c.request.MultipartForm = form
+	c.request.PostForm = make(url.Values, len(form.Value))
+	c.request.Form = make(url.Values, len(form.Value))
+
+	for key, values := range form.Value {
+		c.request.PostForm[key] = values
+		c.request.Form[key] = append(c.request.Form[key], values...)
+	}
+
+	for key, values := range c.request.URL.Query() {
+		c.request.Form[key] = append(c.request.Form[key], values...)
+	}
+}
+
+// FormFile returns the first file uploaded with the form field name.
+// It returns [http.ErrMissingFile] if there is no such file.
+func (c *ctx) FormFile(name string) (*multipart.FileHeader, error) {
+	form, err := c.MultipartForm()
+	if err != nil {
+		return nil, err
+	}
+
+	files := form.File[name]
+	if len(files) == 0 {
+		return nil, http.ErrMissingFile
+	}
+
+	return files[0], nil
+}
+
+// SaveFile saves the uploaded file to the destination path.
+func (c *ctx) SaveFile(fh *multipart.FileHeader, dst string) error {
+	src, err := fh.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, src); err != nil {
+		_ = out.Close()
+		return err
+	}
+
+	return out.Close()
+}
+
+// StreamMultipart reads the multipart form part by part without storing it, which suits huge uploads.
+// The size and the content type of the files are checked according to the multipart configuration of the router.
+// Errors of the handler are returned unchanged, except exceeding the size limit of the request body.
+func (c *ctx) StreamMultipart(handler func(part *UploadPart) error) error {
+	reader, err := c.multipartReader()
+	if err != nil {
+		return err
+	}
+
+	cfg := c.settings.multipart
+
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		if err != nil {
+			return uploadError(err)
+		}
+
+		upload := &UploadPart{
+			FormName: part.FormName(),
+			FileName: part.FileName(),
+			reader:   part,
+		}
+
+		if upload.FileName != "" {
+			buffered := bufio.NewReaderSize(part, sniffLength)
+			head, err := buffered.Peek(sniffLength)
+			if err != nil && !errors.Is(err, io.EOF) {
+				return uploadError(err)
+			}
+
+			upload.ContentType = http.DetectContentType(head)
+			if !cfg.allowed(upload.ContentType) {
+				return NewError(http.StatusUnsupportedMediaType, errFileType.Error())
+			}
+
+			upload.reader = buffered
+			if cfg.MaxFileSize > 0 {
+				upload.reader = &limitedReader{reader: buffered, remaining: cfg.MaxFileSize}
+			}
+		}
+
+		err = handler(upload)
+		_ = part.Close()
+
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return uploadError(err)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// sniffFile detects the content type of the uploaded file by its content.
+func sniffFile(fh *multipart.FileHeader) (string, error) {
+	f, err := fh.Open()
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	head := make([]byte, sniffLength)
+	n, err := io.ReadFull(f, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+
+	return http.DetectContentType(head[:n]), nil
+}
+
+// removeUploads removes the temporary files of the multipart form,
+// including the form parsed by [http.Request.ParseMultipartForm] outside of the context.
+func (c *ctx) removeUploads() {
+	if form := c.request.MultipartForm; form != nil && form != c.uploads.form {
+		_ = form.RemoveAll()
+	}
+
+	if c.uploads.form != nil {
+		_ = c.uploads.form.RemoveAll()
+		c.uploads.form = nil
+	}
+	c.uploads.err = nil
+}
@@ -0,0 +1,394 @@
+package wayes
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pngHeader is the signature of PNG files used to test content sniffing.
+var pngHeader = []byte("\x89PNG\r\n\x1a\n")
+
+// multipartBody creates a multipart body with the fields and the files.
+func multipartBody(t *testing.T, fields map[string]string, files map[string][]byte) (io.Reader, string) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	for name, value := range fields {
+		require.NoError(t, writer.WriteField(name, value))
+	}
+
+	for name, content := range files {
+		part, err := writer.CreateFormFile(name, name+".bin")
+		require.NoError(t, err)
+		_, err = part.Write(content)
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, writer.Close())
+
+	return &body, writer.FormDataContentType()
+}
+
+// TestCtxFormFile tests the parsing of multipart forms with the size and type limits.
+func TestCtxFormFile(t *testing.T) {
+	image := append(append([]byte{}, pngHeader...), bytes.Repeat([]byte{0}, 100)...)
+
+	cases := []struct {
+		name         string
+		fields       map[string]string
+		files        map[string][]byte
+		contentType  string
+		exceptedCode int
+		exceptedBody string
+	}{
+		{
+			name:         "Uploaded file",
+			fields:       map[string]string{"title": "avatar"},
+			files:        map[string][]byte{"avatar": image},
+			exceptedCode: http.StatusOK,
+			exceptedBody: "avatar avatar.bin 108",
+		},
+		{
+			name:         "Missing file",
+			fields:       map[string]string{"title": "avatar"},
+			exceptedCode: http.StatusInternalServerError,
+			exceptedBody: http.ErrMissingFile.Error() + "\n",
+		},
+		{
+			name:         "File too large",
+			files:        map[string][]byte{"avatar": append(image, make([]byte, 1024)...)},
+			exceptedCode: http.StatusRequestEntityTooLarge,
+			exceptedBody: "file too large\n",
+		},
+		{
+			name:         "File type not allowed",
+			files:        map[string][]byte{"avatar": []byte("<html><script>alert(1)</script></html>")},
+			exceptedCode: http.StatusUnsupportedMediaType,
+			exceptedBody: "file type not allowed\n",
+		},
+		{
+			name:         "Body too large",
+			files:        map[string][]byte{"avatar": image, "other": make([]byte, 4096)},
+			exceptedCode: http.StatusRequestEntityTooLarge,
+			exceptedBody: "body too large\n",
+		},
+		{
+			name:         "Not multipart",
+			contentType:  "application/json",
+			exceptedCode: http.StatusUnsupportedMediaType,
+			exceptedBody: "not a multipart form\n",
+		},
+		{
+			name:         "Missing boundary",
+			contentType:  "multipart/form-data",
+			exceptedCode: http.StatusBadRequest,
+			exceptedBody: http.ErrMissingBoundary.Error() + "\n",
+		},
+	}
+
+	rt := New()
+	rt.SetMultipart(MultipartConfig{
+		MaxFileSize:  1024,
+		MaxTotalSize: 2048,
+		AllowedTypes: []string{"image/*", "application/pdf"},
+	})
+	rt.Post("/upload", func(ctx Ctx) error {
+		fh, err := ctx.FormFile("avatar")
+		if err != nil {
+			return err
+		}
+
+		return ctx.Write(strings.Join([]string{ctx.Request().FormValue("title"), fh.Filename, strconv.FormatInt(fh.Size, 10)}, " "))
+	})
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			body, contentType := multipartBody(t, test.fields, test.files)
+			if test.contentType != "" {
+				contentType = test.contentType
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/upload", body)
+			req.Header.Set("Content-Type", contentType)
+
+			rr := httptest.NewRecorder()
+			rt.Mux().ServeHTTP(rr, req)
+
+			assert.Equal(t, test.exceptedCode, rr.Code)
+			assert.Equal(t, test.exceptedBody, rr.Body.String())
+		})
+	}
+}
+
+// TestCtxFormFile_errorCached tests that the rejected form is rejected again on the next call.
+func TestCtxFormFile_errorCached(t *testing.T) {
+	var errs []error
+
+	rt := New()
+	rt.SetMultipart(MultipartConfig{AllowedTypes: []string{"image/*"}})
+	rt.Post("/upload", func(ctx Ctx) error {
+		for i := 0; i < 2; i++ {
+			_, err := ctx.FormFile("avatar")
+			errs = append(errs, err)
+		}
+
+		return nil
+	})
+
+	body, contentType := multipartBody(t, nil, map[string][]byte{"avatar": []byte("<html></html>")})
+	req := httptest.NewRequest(http.MethodPost, "/upload", body)
+	req.Header.Set("Content-Type", contentType)
+	rt.Mux().ServeHTTP(httptest.NewRecorder(), req)
+
+	require.Len(t, errs, 2)
+	for _, err := range errs {
+		assert.EqualError(t, err, errFileType.Error())
+	}
+}
+
+// TestCtxFormFile_parsed tests the files of the form already parsed by a middleware, for example by CSRF.
+func TestCtxFormFile_parsed(t *testing.T) {
+	rt := New()
+	rt.SetMultipart(MultipartConfig{MaxTotalSize: 2048, AllowedTypes: []string{"image/*"}})
+	rt.Use(func(ctx Ctx) error {
+		if ctx.Request().PostFormValue("_csrf") != "token" {
+			return NewError(http.StatusForbidden)
+		}
+
+		return ctx.Next()
+	})
+	rt.Post("/upload", func(ctx Ctx) error {
+		fh, err := ctx.FormFile("avatar")
+		if err != nil {
+			return err
+		}
+
+		return ctx.Write(fh.Filename)
+	})
+
+	cases := []struct {
+		name         string
+		content      []byte
+		exceptedCode int
+		exceptedBody string
+	}{
+		{
+			name:         "Allowed file",
+			content:      pngHeader,
+			exceptedCode: http.StatusOK,
+			exceptedBody: "avatar.bin",
+		},
+		{
+			name:         "File type not allowed",
+			content:      []byte("<html></html>"),
+			exceptedCode: http.StatusUnsupportedMediaType,
+			exceptedBody: "file type not allowed\n",
+		},
+		{
+			name:         "Body too large",
+			content:      append(append([]byte{}, pngHeader...), make([]byte, 4096)...),
+			exceptedCode: http.StatusRequestEntityTooLarge,
+			exceptedBody: "body too large\n",
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			body, contentType := multipartBody(t, map[string]string{"_csrf": "token"}, map[string][]byte{"avatar": test.content})
+			req := httptest.NewRequest(http.MethodPost, "/upload", body)
+			req.Header.Set("Content-Type", contentType)
+
+			rr := httptest.NewRecorder()
+			rt.Mux().ServeHTTP(rr, req)
+
+			assert.Equal(t, test.exceptedCode, rr.Code)
+			assert.Equal(t, test.exceptedBody, rr.Body.String())
+		})
+	}
+}
+
+// TestCtxFormFile_parsedRemoved tests that the temporary files of the form parsed by a middleware
+// are removed when the middleware rejects the request.
+func TestCtxFormFile_parsedRemoved(t *testing.T) {
+	var fh *multipart.FileHeader
+
+	rt := New()
+	rt.Use(func(ctx Ctx) error {
+		if err := ctx.Request().ParseMultipartForm(0); err != nil {
+			return err
+		}
+		fh = ctx.Request().MultipartForm.File["avatar"][0]
+
+		return NewError(http.StatusForbidden)
+	})
+	rt.Post("/upload", func(ctx Ctx) error {
+		return nil
+	})
+
+	body, contentType := multipartBody(t, nil, map[string][]byte{"avatar": pngHeader})
+	req := httptest.NewRequest(http.MethodPost, "/upload", body)
+	req.Header.Set("Content-Type", contentType)
+
+	rr := httptest.NewRecorder()
+	rt.Mux().ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	require.NotNil(t, fh)
+
+	_, err := fh.Open()
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+// TestCtxSaveFile tests the saving of uploaded files and the removal of temporary files after the request.
+func TestCtxSaveFile(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+
+	dst := filepath.Join(t.TempDir(), "upload.bin")
+	content := bytes.Repeat([]byte("data"), 1024)
+
+	var tempFiles int
+
+	rt := New()
+	rt.SetMultipart(MultipartConfig{MaxMemory: 1})
+	rt.Post("/upload", func(ctx Ctx) error {
+		fh, err := ctx.FormFile("file")
+		if err != nil {
+			return err
+		}
+
+		entries, err := os.ReadDir(tmp)
+		if err != nil {
+			return err
+		}
+		tempFiles = len(entries)
+
+		if err := ctx.SaveFile(fh, dst); err != nil {
+			return err
+		}
+
+		return ctx.SendStatus(http.StatusCreated)
+	})
+
+	body, contentType := multipartBody(t, nil, map[string][]byte{"file": content})
+	req := httptest.NewRequest(http.MethodPost, "/upload", body)
+	req.Header.Set("Content-Type", contentType)
+
+	rr := httptest.NewRecorder()
+	rt.Mux().ServeHTTP(rr, req)
+	require.Equal(t, http.StatusCreated, rr.Code)
+
+	saved, err := os.ReadFile(dst)
+	require.NoError(t, err)
+	assert.Equal(t, content, saved)
+
+	entries, err := os.ReadDir(tmp)
+	require.NoError(t, err)
+	assert.Equal(t, 1, tempFiles, "large file must be stored in a temporary file")
+	assert.Empty(t, entries, "temporary files must be removed after the request")
+}
+
+// TestCtxStreamMultipart tests the reading of multipart forms part by part.
+func TestCtxStreamMultipart(t *testing.T) {
+	image := append(append([]byte{}, pngHeader...), bytes.Repeat([]byte{1}, 600)...)
+
+	cases := []struct {
+		name         string
+		files        map[string][]byte
+		exceptedCode int
+		exceptedBody string
+	}{
+		{
+			name:         "Streamed parts",
+			files:        map[string][]byte{"image": image},
+			exceptedCode: http.StatusOK,
+			exceptedBody: "title=report;image=image.bin image/png 608;",
+		},
+		{
+			name:         "File too large",
+			files:        map[string][]byte{"image": append(image, make([]byte, 1024)...)},
+			exceptedCode: http.StatusRequestEntityTooLarge,
+			exceptedBody: "file too large\n",
+		},
+		{
+			name:         "File type not allowed",
+			files:        map[string][]byte{"image": []byte("plain text")},
+			exceptedCode: http.StatusUnsupportedMediaType,
+			exceptedBody: "file type not allowed\n",
+		},
+		{
+			name:         "Handler error",
+			files:        map[string][]byte{"failed": image},
+			exceptedCode: http.StatusInternalServerError,
+			exceptedBody: "storage failed\n",
+		},
+		{
+			name:         "Body too large",
+			files:        map[string][]byte{"image": image, "second": image, "third": image, "fourth": image},
+			exceptedCode: http.StatusRequestEntityTooLarge,
+			exceptedBody: "body too large\n",
+		},
+	}
+
+	rt := New()
+	rt.SetMultipart(MultipartConfig{
+		MaxFileSize:  1024,
+		MaxTotalSize: 2048,
+		AllowedTypes: []string{"image/png"},
+	})
+	rt.Post("/upload", func(ctx Ctx) error {
+		var result strings.Builder
+
+		err := ctx.StreamMultipart(func(part *UploadPart) error {
+			data, err := io.ReadAll(part)
+			if err != nil {
+				return err
+			}
+
+			if part.FormName == "failed" {
+				return errors.New("storage failed")
+			}
+
+			if part.FileName == "" {
+				result.WriteString(part.FormName + "=" + string(data) + ";")
+				return nil
+			}
+
+			result.WriteString(part.FormName + "=" + part.FileName + " " + part.ContentType + " " + strconv.Itoa(len(data)) + ";")
+
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		return ctx.Write(result.String())
+	})
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			body, contentType := multipartBody(t, map[string]string{"title": "report"}, test.files)
+
+			req := httptest.NewRequest(http.MethodPost, "/upload", body)
+			req.Header.Set("Content-Type", contentType)
+
+			rr := httptest.NewRecorder()
+			rt.Mux().ServeHTTP(rr, req)
+
+			assert.Equal(t, test.exceptedCode, rr.Code)
+			assert.Equal(t, test.exceptedBody, rr.Body.String())
+		})
+	}
+}
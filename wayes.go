@@ -2,7 +2,11 @@ package wayes
 
 import (
 	"fmt"
+	"io/fs"
 	"net/http"
+	"net/netip"
+	"slices"
+	"strings"
 )
 
 // Validater is an interface that defines methods for configuring and performing validation.
@@ -18,30 +22,85 @@ type Handler func(ctx Ctx) error
 // Wayes is an interface that defines methods for working with HTTP routes.
 type Wayes interface {
 	// Head registers a handler function for the HEAD method and the specified path.
-	Head(path string, handler Handler)
+	Head(path string, handler Handler, options ...RouteOption)
 
 	// Get registers a handler function for the GET method and the specified path.
-	Get(path string, handler Handler)
+	Get(path string, handler Handler, options ...RouteOption)
 
 	// Options registers a handler function for the Options method and the specified path.
-	Options(path string, handler Handler)
+	// Paths without an explicit OPTIONS handler respond with 204 No Content and the Allow header.
+	Options(path string, handler Handler, options ...RouteOption)
 
 	// Post registers a handler function for the POST method and the specified path.
-	Post(path string, handler Handler)
+	Post(path string, handler Handler, options ...RouteOption)
 
 	// Patch registers a handler function for the PATCH method and the specified path.
-	Patch(path string, handler Handler)
+	Patch(path string, handler Handler, options ...RouteOption)
 
 	// Put registers a handler function for the PUT method and the specified path.
-	Put(path string, handler Handler)
+	Put(path string, handler Handler, options ...RouteOption)
 
 	// Delete registers a handler function for the DELETE method and the specified path.
-	Delete(path string, handler Handler)
+	Delete(path string, handler Handler, options ...RouteOption)
+
+	// Redirect registers a rule that redirects requests of the path to the target with the status code.
+	// The wildcards of the path can be used in the target, for example "/old/{id}" to "/new/{id}".
+	Redirect(from, to string, code int)
+
+	// Static registers a handler that serves the files of the file system under the prefix.
+	// The files are served through the middlewares of the wayes.
+	Static(prefix string, fsys fs.FS, config ...StaticConfig)
+
+	// WebSocket registers a handler for WebSocket connections on the specified path.
+	// The middlewares and guards run before the upgrade.
+	WebSocket(path string, handler WebSocketHandler, config ...WebSocketConfig)
 
 	// Group creates a new route group.
 	Group(path string) Wayes
 
+	// Guard registers authorization guards for the routes of the wayes.
+	// Guards run after the middlewares, so the principal set by an authentication middleware is available.
+	Guard(guards ...Guard)
+
+	// Routes returns the routes registered in the wayes and its groups.
+	Routes() []Route
+
+	// SetErrorHandler sets the function that handles errors returned by handlers and middlewares.
+	// The error handler is shared with all route groups.
+	SetErrorHandler(handler ErrorHandler)
+
+	// SetAuthorizer sets the authorizer used by the guards to retrieve roles and permissions of the principal.
+	// The authorizer is shared with all route groups.
+	SetAuthorizer(authorizer Authorizer)
+
+	// SetETag enables the generation of ETags by [Ctx.Write] and [Ctx.JSON].
+	// The mode is shared with all route groups.
+	SetETag(mode ETagMode)
+
+	// SetCookieKeys sets the secret keys used to sign and encrypt cookies.
+	// The first key is used for new cookies, all keys are used to read cookies, so keys can be rotated.
+	// The keys are shared with all route groups.
+	SetCookieKeys(keys ...[]byte)
+
+	// SetTrustedProxies sets the CIDR ranges or IP addresses of the proxies whose forwarding headers are trusted
+	// by [Ctx.IP], [Ctx.Scheme] and [Ctx.Hostname]. Without trusted proxies the forwarding headers are ignored.
+	// The proxies are shared with all route groups.
+	SetTrustedProxies(proxies ...string) error
+
+	// SetMultipart sets the memory threshold, the size limits and the allowed file types of multipart uploads.
+	// The configuration is shared with all route groups.
+	SetMultipart(config MultipartConfig)
+
+	// SetRedirectMode enables redirects of requests that match no route to the normalized path of a route.
+	// The mode is shared with all route groups.
+	SetRedirectMode(mode RedirectMode)
+
+	// SetRenderer sets the renderer used by [Ctx.Render].
+	// The renderer is shared with all route groups.
+	SetRenderer(renderer Renderer)
+
 	// Use registers middleware for the wayes.
+	// A middleware must call [Ctx.Next] to pass control to the next handler in the chain.
 	Use(handlers ...Handler)
 
 	// Combine combines multiple routers into a single wayes.
@@ -55,7 +114,65 @@ type Wayes interface {
 type wayes struct {
 	validator   Validater
 	mux         *http.ServeMux
+	fallback    bool
+	combined    []*http.ServeMux
+	prefix      string
 	middlewares []Handler
+	guards      []Guard
+	routes      map[string]*route
+	paths       []string
+	groups      []*wayes
+	settings    *settings
+}
+
+// settings represents a structure for the configuration shared between the router and its groups.
+type settings struct {
+	errorHandler   ErrorHandler
+	authorizer     Authorizer
+	etag           ETagMode
+	cookieKeys     []cookieKey
+	trustedProxies []netip.Prefix
+	multipart      MultipartConfig
+	renderer       Renderer
+	names          map[string]string
+	redirectMode   RedirectMode
+}
+
+// newSettings creates a new instance of [settings] with the default configuration.
+func newSettings() *settings {
+	return &settings{
+		errorHandler: DefaultErrorHandler,
+		authorizer:   defaultAuthorizer{},
+		multipart:    MultipartConfig{}.withDefaults(),
+		names:        make(map[string]string),
+	}
+}
+
+// Route represents a structure that describes a registered route.
+type Route struct {
+	Method string
+	Path   string
+	Name   string
+	Guards []Guard
+}
+
+// Protected reports whether the route is protected by at least one guard.
+func (r Route) Protected() bool {
+	return len(r.Guards) > 0
+}
+
+// route represents a structure that holds the handlers registered for a path.
+type route struct {
+	path      string
+	endpoints []*endpoint
+	options   Handler
+}
+
+// endpoint represents a structure for the options of a handler registered for a method.
+type endpoint struct {
+	method string
+	name   string
+	guards []Guard
 }
 
 // New creates a new instance of [Wayes].
@@ -68,83 +185,294 @@ func New(validator ...Validater) Wayes {
 		validator:   validator[0],
 		mux:         http.NewServeMux(),
 		middlewares: make([]Handler, 0, 10),
+		routes:      make(map[string]*route),
+		settings:    newSettings(),
 	}
 }
 
-// handler executes the handler function and encodes the response.
-func (rt *wayes) handler(handler Handler, w http.ResponseWriter, r *http.Request) {
-	context := NewCtx(rt.validator, w, r)
+// handler executes the middleware chain and the guards of the endpoint followed by the handler function.
+func (rt *wayes) handler(pattern string, ep *endpoint, handler Handler, w http.ResponseWriter, r *http.Request) {
+	context := newCtx(rt.settings, rt.validator, w, r)
+	context.pattern = pattern
+	context.handlers = append(slices.Clip(rt.middlewares), handler)
+
+	if guards := rt.guardsOf(ep); len(guards) > 0 {
+		context.handlers = append(slices.Clip(rt.middlewares), authorize(rt.settings, guards), handler)
+	}
 
-	for _, middleware := range rt.middlewares {
-		if err := middleware(context); err != nil {
-			http.Error(context.Response(), err.Error(), http.StatusInternalServerError)
-			return
+	defer context.removeUploads()
+
+	// The response of a hijacked connection can no longer be written.
+	if err := context.Next(); err != nil && !context.hijacked {
+		rt.settings.errorHandler(context, err)
+	}
+}
+
+// handle registers a handler function for the given method and path.
+// Every registered path automatically responds to the OPTIONS method with the list of allowed methods,
+// unless an OPTIONS handler is registered explicitly.
+func (rt *wayes) handle(method, path string, handler Handler, options ...RouteOption) {
+	key := routeKey(path)
+
+	rte, ok := rt.routes[key]
+	if !ok {
+		rte = &route{path: path}
+		rt.routes[key] = rte
+		rt.paths = append(rt.paths, key)
+
+		pattern := rt.pattern(http.MethodOptions, path)
+		rt.mux.HandleFunc(fmt.Sprintf("%s %s", http.MethodOptions, path), func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Allow", rte.allow())
+
+			if ep := rte.endpoint(http.MethodOptions); ep != nil {
+				rt.handler(pattern, ep, rte.options, w, r)
+				return
+			}
+
+			rt.handler(pattern, nil, func(ctx Ctx) error {
+				return ctx.SendStatus(http.StatusNoContent)
+			}, w, r)
+		})
+	}
+
+	ep := &endpoint{method: method}
+	for _, option := range options {
+		option.apply(ep)
+	}
+	rte.endpoints = append(rte.endpoints, ep)
+
+	if ep.name != "" {
+		if _, ok := rt.settings.names[ep.name]; ok {
+			panic(fmt.Sprintf("wayes: route name %q is already registered", ep.name))
 		}
+		rt.settings.names[ep.name] = rt.prefix + path
 	}
 
-	if err := handler(context); err != nil {
-		http.Error(context.Response(), err.Error(), http.StatusInternalServerError)
+	if method == http.MethodOptions {
+		rte.options = handler
+		return
 	}
+
+	pattern := rt.pattern(method, path)
+	rt.mux.HandleFunc(fmt.Sprintf("%s %s", method, path), func(w http.ResponseWriter, r *http.Request) {
+		rt.handler(pattern, ep, handler, w, r)
+	})
+}
+
+// guardsOf returns the guards of the wayes followed by the guards of the endpoint.
+// The automatic OPTIONS handler has no endpoint and is never guarded.
+func (rt *wayes) guardsOf(ep *endpoint) []Guard {
+	if ep == nil {
+		return nil
+	}
+
+	return append(slices.Clip(rt.guards), ep.guards...)
+}
+
+// pattern returns the full route pattern including the prefixes of the parent groups.
+func (rt *wayes) pattern(method, path string) string {
+	return fmt.Sprintf("%s %s%s", method, rt.prefix, path)
+}
+
+// endpoint returns the endpoint registered for the method, nil if there is none.
+func (r *route) endpoint(method string) *endpoint {
+	for _, ep := range r.endpoints {
+		if ep.method == method {
+			return ep
+		}
+	}
+
+	return nil
+}
+
+// allow returns the value of the Allow header for the route.
+func (r *route) allow() string {
+	methods := make([]string, 0, len(r.endpoints)+2)
+	for _, ep := range r.endpoints {
+		if ep.method == http.MethodOptions {
+			continue
+		}
+
+		methods = append(methods, ep.method)
+		if ep.method == http.MethodGet && r.endpoint(http.MethodHead) == nil {
+			methods = append(methods, http.MethodHead)
+		}
+	}
+	methods = append(methods, http.MethodOptions)
+
+	return strings.Join(methods, ", ")
+}
+
+// routeKey returns the path with wildcard names removed,
+// so that patterns matching the same requests share the same key.
+func routeKey(path string) string {
+	var key strings.Builder
+
+	for {
+		start := strings.IndexByte(path, '{')
+		if start < 0 {
+			break
+		}
+
+		end := strings.IndexByte(path[start:], '}')
+		if end < 0 {
+			break
+		}
+
+		wildcard := path[start+1 : start+end]
+		key.WriteString(path[:start])
+		switch {
+		case wildcard == "$":
+			key.WriteString("{$}")
+		case strings.HasSuffix(wildcard, "..."):
+			key.WriteString("{...}")
+		default:
+			key.WriteString("{}")
+		}
+
+		path = path[start+end+1:]
+	}
+	key.WriteString(path)
+
+	return key.String()
 }
 
 // Head registers a handler function for the HEAD method and the specified path.
-func (rt *wayes) Head(path string, handler Handler) {
-	rt.mux.HandleFunc(fmt.Sprintf("HEAD %s", path), func(w http.ResponseWriter, r *http.Request) {
-		rt.handler(handler, w, r)
-	})
+func (rt *wayes) Head(path string, handler Handler, options ...RouteOption) {
+	rt.handle(http.MethodHead, path, handler, options...)
 }
 
 // Get registers a handler function for the GET method and the specified path.
-func (rt *wayes) Get(path string, handler Handler) {
-	rt.mux.HandleFunc(fmt.Sprintf("GET %s", path), func(w http.ResponseWriter, r *http.Request) {
-		rt.handler(handler, w, r)
-	})
+func (rt *wayes) Get(path string, handler Handler, options ...RouteOption) {
+	rt.handle(http.MethodGet, path, handler, options...)
 }
 
 // Options registers a handler function for the Options method and the specified path.
-func (rt *wayes) Options(path string, handler Handler) {
-	rt.mux.HandleFunc(fmt.Sprintf("OPTIONS %s", path), func(w http.ResponseWriter, r *http.Request) {
-		rt.handler(handler, w, r)
-	})
+func (rt *wayes) Options(path string, handler Handler, options ...RouteOption) {
+	rt.handle(http.MethodOptions, path, handler, options...)
 }
 
 // Post registers a handler function for the POST method and the specified path.
-func (rt *wayes) Post(path string, handler Handler) {
-	rt.mux.HandleFunc(fmt.Sprintf("POST %s", path), func(w http.ResponseWriter, r *http.Request) {
-		rt.handler(handler, w, r)
-	})
+func (rt *wayes) Post(path string, handler Handler, options ...RouteOption) {
+	rt.handle(http.MethodPost, path, handler, options...)
 }
 
 // Patch registers a handler function for the PATCH method and the specified path.
-func (rt *wayes) Patch(path string, handler Handler) {
-	rt.mux.HandleFunc(fmt.Sprintf("PATCH %s", path), func(w http.ResponseWriter, r *http.Request) {
-		rt.handler(handler, w, r)
-	})
+func (rt *wayes) Patch(path string, handler Handler, options ...RouteOption) {
+	rt.handle(http.MethodPatch, path, handler, options...)
 }
 
 // Put registers a handler function for the PUT method and the specified path.
-func (rt *wayes) Put(path string, handler Handler) {
-	rt.mux.HandleFunc(fmt.Sprintf("PUT %s", path), func(w http.ResponseWriter, r *http.Request) {
-		rt.handler(handler, w, r)
-	})
+func (rt *wayes) Put(path string, handler Handler, options ...RouteOption) {
+	rt.handle(http.MethodPut, path, handler, options...)
 }
 
 // Delete registers a handler function for the DELETE method and the specified path.
-func (rt *wayes) Delete(path string, handler Handler) {
-	rt.mux.HandleFunc(fmt.Sprintf("DELETE %s", path), func(w http.ResponseWriter, r *http.Request) {
-		rt.handler(handler, w, r)
-	})
+func (rt *wayes) Delete(path string, handler Handler, options ...RouteOption) {
+	rt.handle(http.MethodDelete, path, handler, options...)
 }
 
 // Group creates a new route group.
 func (rt *wayes) Group(path string) Wayes {
-	group := New(rt.validator)
+	group := New(rt.validator).(*wayes)
+	group.prefix = rt.prefix + path
+	group.settings = rt.settings
 	group.Use(rt.middlewares...)
+	group.Guard(rt.guards...)
+	rt.groups = append(rt.groups, group)
 	rt.mux.Handle(fmt.Sprintf("%s/", path), http.StripPrefix(path, group.Mux()))
 
+	if rt.settings.redirectMode != 0 {
+		group.registerFallback()
+	}
+
 	return group
 }
 
+// Guard registers authorization guards for the routes of the wayes.
+func (rt *wayes) Guard(guards ...Guard) {
+	rt.guards = append(rt.guards, guards...)
+}
+
+// Routes returns the routes registered in the wayes and its groups.
+// Automatic OPTIONS handlers and routers added with Combine are not included.
+func (rt *wayes) Routes() []Route {
+	routes := make([]Route, 0, len(rt.paths))
+
+	for _, key := range rt.paths {
+		rte := rt.routes[key]
+		for _, ep := range rte.endpoints {
+			routes = append(routes, Route{
+				Method: ep.method,
+				Path:   rt.prefix + rte.path,
+				Name:   ep.name,
+				Guards: rt.guardsOf(ep),
+			})
+		}
+	}
+
+	for _, group := range rt.groups {
+		routes = append(routes, group.Routes()...)
+	}
+
+	return routes
+}
+
+// SetErrorHandler sets the function that handles errors returned by handlers and middlewares.
+func (rt *wayes) SetErrorHandler(handler ErrorHandler) {
+	if handler == nil {
+		handler = DefaultErrorHandler
+	}
+
+	rt.settings.errorHandler = handler
+}
+
+// SetAuthorizer sets the authorizer used by the guards to retrieve roles and permissions of the principal.
+func (rt *wayes) SetAuthorizer(authorizer Authorizer) {
+	if authorizer == nil {
+		authorizer = defaultAuthorizer{}
+	}
+
+	rt.settings.authorizer = authorizer
+}
+
+// SetETag enables the generation of ETags by [Ctx.Write] and [Ctx.JSON].
+func (rt *wayes) SetETag(mode ETagMode) {
+	rt.settings.etag = mode
+}
+
+// SetCookieKeys sets the secret keys used to sign and encrypt cookies.
+func (rt *wayes) SetCookieKeys(keys ...[]byte) {
+	cookieKeys := make([]cookieKey, 0, len(keys))
+	for _, key := range keys {
+		cookieKeys = append(cookieKeys, newCookieKey(key))
+	}
+
+	rt.settings.cookieKeys = cookieKeys
+}
+
+// SetTrustedProxies sets the CIDR ranges or IP addresses of the proxies whose forwarding headers are trusted.
+func (rt *wayes) SetTrustedProxies(proxies ...string) error {
+	prefixes, err := ParsePrefixes(proxies)
+	if err != nil {
+		return err
+	}
+
+	rt.settings.trustedProxies = prefixes
+
+	return nil
+}
+
+// SetMultipart sets the memory threshold, the size limits and the allowed file types of multipart uploads.
+func (rt *wayes) SetMultipart(config MultipartConfig) {
+	rt.settings.multipart = config.withDefaults()
+}
+
+// SetRenderer sets the renderer used by [Ctx.Render].
+func (rt *wayes) SetRenderer(renderer Renderer) {
+	rt.settings.renderer = renderer
+}
+
 // Use registers middleware for the wayes.
 func (rt *wayes) Use(handlers ...Handler) {
 	for _, handler := range handlers {
@@ -153,10 +481,10 @@ func (rt *wayes) Use(handlers ...Handler) {
 }
 
 // Combine combines multiple routers into a single wayes.
+// Requests matching no route of the wayes are passed to the first router with a matching route.
 func (rt *wayes) Combine(routers ...*http.ServeMux) *http.ServeMux {
-	for _, router := range routers {
-		rt.Mux().Handle("/", router)
-	}
+	rt.combined = append(rt.combined, routers...)
+	rt.handleFallback()
 
 	return rt.Mux()
 }
@@ -715,3 +715,96 @@ func TestWayesCombine(t *testing.T) {
 		})
 	}
 }
+
+// TestWayesOptions_automatic tests the automatic handling of the OPTIONS method.
+func TestWayesOptions_automatic(t *testing.T) {
+	rt := New()
+	rt.Get("/users/{id}", func(ctx Ctx) error {
+		return ctx.Write("Test get response")
+	})
+	rt.Delete("/users/{name}", func(ctx Ctx) error {
+		return ctx.Write("Test delete response")
+	})
+	rt.Post("/posts", func(ctx Ctx) error {
+		return ctx.Write("Test post response")
+	})
+	rt.Options("/posts", func(ctx Ctx) error {
+		return ctx.Write("Test options response")
+	})
+
+	req, err := http.NewRequest(http.MethodOptions, "/users/1", nil)
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	rt.Mux().ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	assert.Equal(t, "GET, HEAD, DELETE, OPTIONS", rr.Header().Get("Allow"))
+	assert.Equal(t, "", rr.Body.String())
+
+	req, err = http.NewRequest(http.MethodOptions, "/posts", nil)
+	require.NoError(t, err)
+
+	rr = httptest.NewRecorder()
+	rt.Mux().ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "POST, OPTIONS", rr.Header().Get("Allow"))
+	assert.Equal(t, "Test options response", rr.Body.String())
+}
+
+// TestWayesUse_chain tests the order of middlewares and stopping the chain.
+func TestWayesUse_chain(t *testing.T) {
+	rt := New()
+	rt.Use(func(ctx Ctx) error {
+		ctx.Set("X-Before", "true")
+
+		return ctx.Next()
+	})
+	rt.Use(func(ctx Ctx) error {
+		if ctx.Request().URL.Query().Has("stop") {
+			return ctx.Status(http.StatusForbidden).Write("stopped")
+		}
+
+		return ctx.Next()
+	})
+	rt.Get("/test", func(ctx Ctx) error {
+		return ctx.Write("Test get response")
+	})
+
+	req, err := http.NewRequest(http.MethodGet, "/test", nil)
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	rt.Mux().ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "true", rr.Header().Get("X-Before"))
+	assert.Equal(t, "Test get response", rr.Body.String())
+
+	req, err = http.NewRequest(http.MethodGet, "/test?stop", nil)
+	require.NoError(t, err)
+
+	rr = httptest.NewRecorder()
+	rt.Mux().ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	assert.Equal(t, "stopped", rr.Body.String())
+}
+
+// TestWayesPattern tests the route pattern available in the context.
+func TestWayesPattern(t *testing.T) {
+	rt := New()
+	users := rt.Group("/api").Group("/users")
+	users.Get("/{id}", func(ctx Ctx) error {
+		return ctx.Write(ctx.Pattern())
+	})
+
+	req, err := http.NewRequest(http.MethodGet, "/api/users/1", nil)
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	rt.Mux().ServeHTTP(rr, req)
+
+	assert.Equal(t, "GET /api/users/{id}", rr.Body.String())
+}
@@ -0,0 +1,692 @@
+package wayes
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// websocketGUID is the globally unique identifier used to compute the Sec-WebSocket-Accept header.
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// MessageType represents the type of WebSocket data message.
+type MessageType int
+
+// Types of WebSocket data messages.
+const (
+	TextMessage   MessageType = 1
+	BinaryMessage MessageType = 2
+)
+
+// Frame opcodes defined by RFC 6455.
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xa
+)
+
+// Close codes defined by RFC 6455.
+const (
+	CloseNormal             = 1000
+	CloseGoingAway          = 1001
+	CloseProtocolError      = 1002
+	CloseUnsupportedData    = 1003
+	CloseNoStatus           = 1005
+	CloseAbnormal           = 1006
+	CloseInvalidPayload     = 1007
+	ClosePolicyViolation    = 1008
+	CloseMessageTooBig      = 1009
+	CloseMandatoryExtension = 1010
+	CloseInternalError      = 1011
+)
+
+// maxControlPayload is the maximum payload length of control frames.
+const maxControlPayload = 125
+
+// compressionThreshold is the minimum size of messages compressed with permessage-deflate.
+const compressionThreshold = 128
+
+// deflateTail completes a message compressed with permessage-deflate, so the decompressor reaches the end of stream.
+var deflateTail = []byte{0x00, 0x00, 0xff, 0xff, 0x01, 0x00, 0x00, 0xff, 0xff}
+
+// ErrWebSocketClosed is returned when a message is written to a closed WebSocket connection.
+var ErrWebSocketClosed = errors.New("websocket: connection closed")
+
+// deflaters is a pool of compressors of WebSocket messages.
+var deflaters = sync.Pool{
+	New: func() any {
+		w, _ := flate.NewWriter(nil, flate.BestSpeed)
+		return w
+	},
+}
+
+// CloseError represents an error that describes the closing of a WebSocket connection.
+// It is returned by [Conn.ReadMessage] when the peer closes the connection or violates the protocol.
+type CloseError struct {
+	Code   int
+	Reason string
+}
+
+// Error returns the string representation of the close error.
+func (e *CloseError) Error() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("websocket: close %d", e.Code)
+	}
+
+	return fmt.Sprintf("websocket: close %d: %s", e.Code, e.Reason)
+}
+
+// WebSocketHandler defines a function signature for handling WebSocket connections.
+type WebSocketHandler func(ctx Ctx, conn *Conn) error
+
+// WebSocketConfig represents a structure for the [Wayes.WebSocket] configuration.
+type WebSocketConfig struct {
+	// Origins is the list of origins allowed to open connections, "*" allows any origin.
+	// Defaults to the same origin, requests without the Origin header are always allowed.
+	Origins []string
+
+	// CheckOrigin reports whether the origin of the handshake request is allowed. It replaces the Origins check.
+	CheckOrigin func(ctx Ctx) bool
+
+	// Subprotocols is the list of supported subprotocols in the order of preference.
+	Subprotocols []string
+
+	// ReadLimit is the maximum size of a received message in bytes. Defaults to 16MB.
+	ReadLimit int64
+
+	// Compression enables the permessage-deflate extension when the client offers it.
+	Compression bool
+}
+
+// Conn represents a structure for a WebSocket connection.
+// Reading must be done by one goroutine, writing is safe for concurrent use.
+type Conn struct {
+	conn        net.Conn
+	br          *bufio.Reader
+	mu          sync.Mutex
+	bw          *bufio.Writer
+	closed      bool
+	readLimit   int64
+	compress    bool
+	subprotocol string
+	pongHandler func(data []byte)
+}
+
+// frame represents a structure for a received WebSocket frame.
+type frame struct {
+	fin     bool
+	rsv1    bool
+	opcode  byte
+	payload []byte
+}
+
+// WebSocket registers a handler for WebSocket connections on the GET method and the specified path.
+// The middlewares and guards run before the upgrade, so the handshake can be authenticated and logged.
+// The connection is closed when the handler returns, with 1011 Internal Error if the handler returns an error.
+func (rt *wayes) WebSocket(path string, handler WebSocketHandler, config ...WebSocketConfig) {
+	cfg := WebSocketConfig{}
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+
+	if cfg.ReadLimit <= 0 {
+		cfg.ReadLimit = 16 << 20
+	}
+
+	rt.Get(path, func(ctx Ctx) error {
+		conn, err := cfg.upgrade(ctx)
+		if err != nil {
+			return err
+		}
+
+		if err = handler(ctx, conn); err != nil {
+			var closeErr *CloseError
+			if errors.As(err, &closeErr) {
+				_ = conn.Close(CloseNormal, "")
+				return nil
+			}
+
+			_ = conn.Close(CloseInternalError, "")
+			return err
+		}
+
+		return conn.Close(CloseNormal, "")
+	})
+}
+
+// upgrade validates the handshake request, hijacks the connection and sends the handshake response.
+func (cfg WebSocketConfig) upgrade(ctx Ctx) (*Conn, error) {
+	r := ctx.Request()
+
+	if !headerContains(r.Header, "Connection", "upgrade") || !headerContains(r.Header, "Upgrade", "websocket") {
+		ctx.Set("Upgrade", "websocket")
+		return nil, NewError(http.StatusUpgradeRequired)
+	}
+
+	if r.Method != http.MethodGet {
+		return nil, NewError(http.StatusMethodNotAllowed)
+	}
+
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		ctx.Set("Sec-WebSocket-Version", "13")
+		return nil, NewError(http.StatusUpgradeRequired, "unsupported websocket version")
+	}
+
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		return nil, NewError(http.StatusBadRequest, "invalid websocket key")
+	}
+
+	if !cfg.allowed(ctx) {
+		return nil, NewError(http.StatusForbidden, "untrusted request origin")
+	}
+
+	subprotocol := cfg.subprotocol(r.Header)
+	compress := cfg.Compression && acceptsDeflate(r.Header)
+
+	netConn, rw, err := http.NewResponseController(ctx.Response()).Hijack()
+	if err != nil {
+		return nil, err
+	}
+
+	hijack(ctx)
+
+	// The server may have set deadlines for the request, the connection lives longer.
+	if err = netConn.SetDeadline(time.Time{}); err != nil {
+		netConn.Close()
+		return nil, err
+	}
+
+	header := ctx.Response().Header().Clone()
+	for _, name := range []string{"Content-Length", "Content-Type", "Content-Encoding", "Transfer-Encoding"} {
+		header.Del(name)
+	}
+	header.Set("Upgrade", "websocket")
+	header.Set("Connection", "Upgrade")
+	header.Set("Sec-WebSocket-Accept", acceptKey(key))
+	if subprotocol != "" {
+		header.Set("Sec-WebSocket-Protocol", subprotocol)
+	}
+	if compress {
+		header.Set("Sec-WebSocket-Extensions", "permessage-deflate; server_no_context_takeover; client_no_context_takeover")
+	}
+
+	_, _ = rw.WriteString("HTTP/1.1 101 Switching Protocols\r\n")
+	_ = header.Write(rw)
+	_, _ = rw.WriteString("\r\n")
+	if err = rw.Flush(); err != nil {
+		netConn.Close()
+		return nil, err
+	}
+
+	return &Conn{
+		conn:        netConn,
+		br:          rw.Reader,
+		bw:          rw.Writer,
+		readLimit:   cfg.ReadLimit,
+		compress:    compress,
+		subprotocol: subprotocol,
+	}, nil
+}
+
+// hijack marks the context as hijacked, so the error handler no longer writes to the response.
+func hijack(c Ctx) {
+	if hc, ok := c.(*ctx); ok {
+		hc.hijacked = true
+		hc.committed.Store(true)
+	}
+}
+
+// allowed reports whether the origin of the handshake request is allowed.
+func (cfg WebSocketConfig) allowed(ctx Ctx) bool {
+	if cfg.CheckOrigin != nil {
+		return cfg.CheckOrigin(ctx)
+	}
+
+	origin := ctx.Request().Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	for _, allowed := range cfg.Origins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+
+	u, err := url.Parse(origin)
+
+	return err == nil && strings.EqualFold(u.Hostname(), ctx.Hostname())
+}
+
+// subprotocol returns the first supported subprotocol requested by the client.
+func (cfg WebSocketConfig) subprotocol(header http.Header) string {
+	var requested []string
+	for _, value := range header.Values("Sec-WebSocket-Protocol") {
+		for _, protocol := range strings.Split(value, ",") {
+			requested = append(requested, strings.TrimSpace(protocol))
+		}
+	}
+
+	for _, protocol := range cfg.Subprotocols {
+		if slices.Contains(requested, protocol) {
+			return protocol
+		}
+	}
+
+	return ""
+}
+
+// acceptsDeflate reports whether the client offers the permessage-deflate extension with parameters
+// the server supports. The server always disables the context takeover in both directions.
+func acceptsDeflate(header http.Header) bool {
+	for _, value := range header.Values("Sec-WebSocket-Extensions") {
+		for _, offer := range splitQuoted(value, ',') {
+			params := splitQuoted(offer, ';')
+			if strings.TrimSpace(params[0]) != "permessage-deflate" {
+				continue
+			}
+
+			supported := true
+			for _, param := range params[1:] {
+				name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+				value = strings.Trim(strings.TrimSpace(value), `"`)
+
+				switch strings.TrimSpace(name) {
+				case "server_no_context_takeover", "client_no_context_takeover", "client_max_window_bits":
+				case "server_max_window_bits":
+					supported = supported && value == "15"
+				default:
+					supported = false
+				}
+			}
+
+			if supported {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// headerContains reports whether the comma-separated values of the header contain the token.
+func headerContains(header http.Header, name, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, part := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// acceptKey computes the value of the Sec-WebSocket-Accept header for the key.
+func acceptKey(key string) string {
+	hash := sha1.Sum([]byte(key + websocketGUID))
+
+	return base64.StdEncoding.EncodeToString(hash[:])
+}
+
+// Subprotocol returns the subprotocol negotiated during the handshake.
+func (c *Conn) Subprotocol() string {
+	return c.subprotocol
+}
+
+// SetReadDeadline sets the deadline for reading messages.
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+// SetWriteDeadline sets the deadline for writing messages.
+func (c *Conn) SetWriteDeadline(t time.Time) error {
+	return c.conn.SetWriteDeadline(t)
+}
+
+// SetPongHandler sets the function called with the payload of received pong frames.
+func (c *Conn) SetPongHandler(handler func(data []byte)) {
+	c.pongHandler = handler
+}
+
+// ReadMessage reads the next data message, reassembling fragmented messages.
+// Ping frames are answered automatically. When the peer closes the connection or violates the protocol,
+// the connection is closed and a [CloseError] is returned.
+func (c *Conn) ReadMessage() (MessageType, []byte, error) {
+	var (
+		messageType MessageType
+		payload     []byte
+		compressed  bool
+	)
+
+	for {
+		f, err := c.readFrame()
+		if err != nil {
+			return 0, nil, c.fail(err)
+		}
+
+		switch f.opcode {
+		case opClose:
+			return 0, nil, c.handleClose(f.payload)
+		case opPing:
+			if err = c.writeFrame(opPong, f.payload, false); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case opPong:
+			if c.pongHandler != nil {
+				c.pongHandler(f.payload)
+			}
+			continue
+		case opText, opBinary:
+			if messageType != 0 {
+				return 0, nil, c.fail(&CloseError{Code: CloseProtocolError, Reason: "unexpected data frame"})
+			}
+			messageType, compressed = MessageType(f.opcode), f.rsv1
+		case opContinuation:
+			if messageType == 0 || f.rsv1 {
+				return 0, nil, c.fail(&CloseError{Code: CloseProtocolError, Reason: "unexpected continuation frame"})
+			}
+		}
+
+		if int64(len(payload)+len(f.payload)) > c.readLimit {
+			return 0, nil, c.fail(&CloseError{Code: CloseMessageTooBig})
+		}
+		payload = append(payload, f.payload...)
+
+		if !f.fin {
+			continue
+		}
+
+		if compressed {
+			if payload, err = inflate(payload, c.readLimit); err != nil {
+				return 0, nil, c.fail(err)
+			}
+		}
+
+		if messageType == TextMessage && !utf8.Valid(payload) {
+			return 0, nil, c.fail(&CloseError{Code: CloseInvalidPayload, Reason: "invalid UTF-8"})
+		}
+
+		return messageType, payload, nil
+	}
+}
+
+// readFrame reads and unmasks the next frame, validating its header.
+func (c *Conn) readFrame() (frame, error) {
+	var head [2]byte
+	if _, err := io.ReadFull(c.br, head[:]); err != nil {
+		return frame{}, err
+	}
+
+	f := frame{
+		fin:    head[0]&0x80 != 0,
+		rsv1:   head[0]&0x40 != 0,
+		opcode: head[0] & 0x0f,
+	}
+
+	if head[0]&0x30 != 0 || (f.rsv1 && (!c.compress || f.opcode >= opClose)) {
+		return frame{}, &CloseError{Code: CloseProtocolError, Reason: "unexpected reserved bits"}
+	}
+
+	switch f.opcode {
+	case opContinuation, opText, opBinary, opClose, opPing, opPong:
+	default:
+		return frame{}, &CloseError{Code: CloseProtocolError, Reason: "unknown opcode"}
+	}
+
+	if head[1]&0x80 == 0 {
+		return frame{}, &CloseError{Code: CloseProtocolError, Reason: "unmasked frame"}
+	}
+
+	length := uint64(head[1] & 0x7f)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return frame{}, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return frame{}, err
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+
+	if f.opcode >= opClose && (!f.fin || length > maxControlPayload) {
+		return frame{}, &CloseError{Code: CloseProtocolError, Reason: "invalid control frame"}
+	}
+
+	if length > uint64(c.readLimit) {
+		return frame{}, &CloseError{Code: CloseMessageTooBig}
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(c.br, mask[:]); err != nil {
+		return frame{}, err
+	}
+
+	f.payload = make([]byte, length)
+	if _, err := io.ReadFull(c.br, f.payload); err != nil {
+		return frame{}, err
+	}
+
+	for i := range f.payload {
+		f.payload[i] ^= mask[i%4]
+	}
+
+	return f, nil
+}
+
+// handleClose answers the close frame of the peer and closes the connection.
+func (c *Conn) handleClose(payload []byte) error {
+	closeErr := &CloseError{Code: CloseNoStatus}
+
+	if len(payload) > 0 {
+		if len(payload) < 2 {
+			return c.fail(&CloseError{Code: CloseProtocolError, Reason: "invalid close frame"})
+		}
+
+		closeErr.Code = int(binary.BigEndian.Uint16(payload))
+		closeErr.Reason = string(payload[2:])
+
+		if !validCloseCode(closeErr.Code) {
+			return c.fail(&CloseError{Code: CloseProtocolError, Reason: "invalid close code"})
+		}
+
+		if !utf8.Valid(payload[2:]) {
+			return c.fail(&CloseError{Code: CloseInvalidPayload, Reason: "invalid UTF-8"})
+		}
+	}
+
+	if closeErr.Code == CloseNoStatus {
+		_ = c.writeClose(nil)
+	} else {
+		_ = c.writeClose(payload[:2])
+	}
+	c.conn.Close()
+
+	return closeErr
+}
+
+// fail closes the connection, sending the close frame when the error is a protocol violation.
+func (c *Conn) fail(err error) error {
+	var closeErr *CloseError
+	if errors.As(err, &closeErr) {
+		_ = c.Close(closeErr.Code, closeErr.Reason)
+		return err
+	}
+
+	c.mu.Lock()
+	c.closed = true
+	c.mu.Unlock()
+	c.conn.Close()
+
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return &CloseError{Code: CloseAbnormal}
+	}
+
+	return err
+}
+
+// validCloseCode reports whether the close code can be sent in a close frame.
+func validCloseCode(code int) bool {
+	switch {
+	case code >= 1000 && code <= 1003, code >= 1007 && code <= 1014:
+		return true
+	default:
+		return code >= 3000 && code <= 4999
+	}
+}
+
+// WriteMessage writes a data message in a single frame.
+// Messages are compressed when the permessage-deflate extension is negotiated.
+func (c *Conn) WriteMessage(messageType MessageType, data []byte) error {
+	if messageType != TextMessage && messageType != BinaryMessage {
+		return fmt.Errorf("websocket: invalid message type %d", messageType)
+	}
+
+	if c.compress && len(data) >= compressionThreshold {
+		return c.writeFrame(byte(messageType), deflate(data), true)
+	}
+
+	return c.writeFrame(byte(messageType), data, false)
+}
+
+// WriteText writes a text message.
+func (c *Conn) WriteText(text string) error {
+	return c.WriteMessage(TextMessage, []byte(text))
+}
+
+// Ping sends a ping frame, the peer answers with a pong frame with the same payload.
+func (c *Conn) Ping(data []byte) error {
+	if len(data) > maxControlPayload {
+		return errors.New("websocket: control frame payload too large")
+	}
+
+	return c.writeFrame(opPing, data, false)
+}
+
+// Close sends a close frame with the code and the reason and closes the connection.
+// Closing an already closed connection does nothing.
+func (c *Conn) Close(code int, reason string) error {
+	var payload []byte
+	if code != CloseNoStatus {
+		if len(reason) > maxControlPayload-2 {
+			reason = reason[:maxControlPayload-2]
+		}
+
+		payload = binary.BigEndian.AppendUint16(nil, uint16(code))
+		payload = append(payload, reason...)
+	}
+
+	err := c.writeClose(payload)
+	if errors.Is(err, ErrWebSocketClosed) {
+		return nil
+	}
+
+	if closeErr := c.conn.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+// writeClose sends the close frame, after which no more frames are written.
+func (c *Conn) writeClose(payload []byte) error {
+	err := c.writeFrame(opClose, payload, false)
+
+	c.mu.Lock()
+	c.closed = true
+	c.mu.Unlock()
+
+	return err
+}
+
+// writeFrame writes an unmasked frame with the payload.
+func (c *Conn) writeFrame(opcode byte, payload []byte, compressed bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return ErrWebSocketClosed
+	}
+
+	head := make([]byte, 2, 10)
+	head[0] = 0x80 | opcode
+	if compressed {
+		head[0] |= 0x40
+	}
+
+	switch length := len(payload); {
+	case length <= 125:
+		head[1] = byte(length)
+	case length <= 0xffff:
+		head[1] = 126
+		head = binary.BigEndian.AppendUint16(head, uint16(length))
+	default:
+		head[1] = 127
+		head = binary.BigEndian.AppendUint64(head, uint64(length))
+	}
+
+	if _, err := c.bw.Write(head); err != nil {
+		return err
+	}
+
+	if _, err := c.bw.Write(payload); err != nil {
+		return err
+	}
+
+	return c.bw.Flush()
+}
+
+// deflate compresses the message with permessage-deflate without the context takeover.
+func deflate(data []byte) []byte {
+	var buf bytes.Buffer
+
+	w := deflaters.Get().(*flate.Writer)
+	defer deflaters.Put(w)
+	w.Reset(&buf)
+
+	_, _ = w.Write(data)
+	_ = w.Flush()
+
+	return bytes.TrimSuffix(buf.Bytes(), deflateTail[:4])
+}
+
+// inflate decompresses the message compressed with permessage-deflate, limiting its size.
+func inflate(data []byte, limit int64) ([]byte, error) {
+	r := flate.NewReader(io.MultiReader(bytes.NewReader(data), bytes.NewReader(deflateTail)))
+	defer r.Close()
+
+	payload, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, &CloseError{Code: CloseInvalidPayload, Reason: "invalid compressed data"}
+	}
+
+	if int64(len(payload)) > limit {
+		return nil, &CloseError{Code: CloseMessageTooBig}
+	}
+
+	return payload, nil
+}
@@ -0,0 +1,424 @@
+package wayes
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// wsClient represents a minimal WebSocket client that writes masked frames.
+type wsClient struct {
+	conn net.Conn
+	br   *bufio.Reader
+}
+
+// dialWebSocket sends the handshake request to the server and returns the client and the handshake response.
+func dialWebSocket(t *testing.T, server *httptest.Server, path string, header http.Header) (*wsClient, *http.Response) {
+	t.Helper()
+
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	require.NoError(t, conn.SetDeadline(time.Now().Add(5*time.Second)))
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+path, nil)
+	require.NoError(t, err)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	require.NoError(t, req.Write(conn))
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	require.NoError(t, err)
+
+	return &wsClient{conn: conn, br: br}, resp
+}
+
+// writeFrame writes a frame masked unless the mask is disabled.
+func (c *wsClient) writeFrame(t *testing.T, fin bool, rsv1 bool, opcode byte, payload []byte, unmasked ...bool) {
+	t.Helper()
+
+	head := []byte{opcode, 0x80}
+	if fin {
+		head[0] |= 0x80
+	}
+	if rsv1 {
+		head[0] |= 0x40
+	}
+	if len(unmasked) > 0 && unmasked[0] {
+		head[1] = 0
+	}
+
+	switch {
+	case len(payload) <= 125:
+		head[1] |= byte(len(payload))
+	default:
+		head[1] |= 126
+		head = binary.BigEndian.AppendUint16(head, uint16(len(payload)))
+	}
+
+	data := append([]byte(nil), payload...)
+	if head[1]&0x80 != 0 {
+		mask := []byte{0x12, 0x34, 0x56, 0x78}
+		head = append(head, mask...)
+		for i := range data {
+			data[i] ^= mask[i%4]
+		}
+	}
+
+	_, err := c.conn.Write(append(head, data...))
+	require.NoError(t, err)
+}
+
+// readFrame reads an unmasked frame written by the server.
+func (c *wsClient) readFrame(t *testing.T) (opcode byte, rsv1 bool, payload []byte) {
+	t.Helper()
+
+	head := make([]byte, 2)
+	_, err := io.ReadFull(c.br, head)
+	require.NoError(t, err)
+	require.Zero(t, head[1]&0x80, "server frames must not be masked")
+
+	length := int(head[1] & 0x7f)
+	if length == 126 {
+		ext := make([]byte, 2)
+		_, err = io.ReadFull(c.br, ext)
+		require.NoError(t, err)
+		length = int(binary.BigEndian.Uint16(ext))
+	}
+
+	payload = make([]byte, length)
+	_, err = io.ReadFull(c.br, payload)
+	require.NoError(t, err)
+
+	return head[0] & 0x0f, head[0]&0x40 != 0, payload
+}
+
+// readClose reads a close frame and returns its code.
+func (c *wsClient) readClose(t *testing.T) int {
+	t.Helper()
+
+	opcode, _, payload := c.readFrame(t)
+	require.Equal(t, byte(opClose), opcode)
+	require.GreaterOrEqual(t, len(payload), 2)
+
+	return int(binary.BigEndian.Uint16(payload))
+}
+
+// newWebSocketServer creates a server with an echo WebSocket handler.
+func newWebSocketServer(t *testing.T, config WebSocketConfig) *httptest.Server {
+	rt := New()
+	rt.Use(func(ctx Ctx) error {
+		if ctx.Request().URL.Query().Get("token") == "denied" {
+			return NewError(http.StatusUnauthorized)
+		}
+
+		ctx.Set("X-Handshake", "middleware")
+		return ctx.Next()
+	})
+	rt.WebSocket("/echo", func(ctx Ctx, conn *Conn) error {
+		for {
+			messageType, data, err := conn.ReadMessage()
+			if err != nil {
+				return err
+			}
+
+			if err = conn.WriteMessage(messageType, data); err != nil {
+				return err
+			}
+		}
+	}, config)
+	rt.WebSocket("/fail", func(ctx Ctx, conn *Conn) error {
+		return errors.New("handler failed")
+	})
+
+	server := httptest.NewServer(rt.Mux())
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// TestWebSocket_handshake tests the validation of the handshake request and the negotiation of the connection.
+func TestWebSocket_handshake(t *testing.T) {
+	cases := []struct {
+		name                string
+		path                string
+		headers             map[string]string
+		exceptedCode        int
+		exceptedProtocol    string
+		exceptedExtensions  string
+		exceptedMiddlewares bool
+	}{
+		{
+			name:                "Upgraded",
+			path:                "/echo",
+			exceptedCode:        http.StatusSwitchingProtocols,
+			exceptedMiddlewares: true,
+		},
+		{
+			name:         "Not an upgrade",
+			path:         "/echo",
+			headers:      map[string]string{"Upgrade": "h2c"},
+			exceptedCode: http.StatusUpgradeRequired,
+		},
+		{
+			name:         "Unsupported version",
+			path:         "/echo",
+			headers:      map[string]string{"Sec-WebSocket-Version": "8"},
+			exceptedCode: http.StatusUpgradeRequired,
+		},
+		{
+			name:         "Invalid key",
+			path:         "/echo",
+			headers:      map[string]string{"Sec-WebSocket-Key": "short"},
+			exceptedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "Rejected by middleware",
+			path:         "/echo?token=denied",
+			exceptedCode: http.StatusUnauthorized,
+		},
+		{
+			name:                "Same origin",
+			path:                "/echo",
+			headers:             map[string]string{"Origin": "http://127.0.0.1"},
+			exceptedCode:        http.StatusSwitchingProtocols,
+			exceptedMiddlewares: true,
+		},
+		{
+			name:                "Allowed origin",
+			path:                "/echo",
+			headers:             map[string]string{"Origin": "https://app.example.com"},
+			exceptedCode:        http.StatusSwitchingProtocols,
+			exceptedMiddlewares: true,
+		},
+		{
+			name:         "Cross origin",
+			path:         "/echo",
+			headers:      map[string]string{"Origin": "https://evil.example.com"},
+			exceptedCode: http.StatusForbidden,
+		},
+		{
+			name:                "Subprotocol",
+			path:                "/echo",
+			headers:             map[string]string{"Sec-WebSocket-Protocol": "unknown, chat"},
+			exceptedCode:        http.StatusSwitchingProtocols,
+			exceptedProtocol:    "chat",
+			exceptedMiddlewares: true,
+		},
+		{
+			name:                "Compression",
+			path:                "/echo",
+			headers:             map[string]string{"Sec-WebSocket-Extensions": "permessage-deflate; client_max_window_bits"},
+			exceptedCode:        http.StatusSwitchingProtocols,
+			exceptedExtensions:  "permessage-deflate; server_no_context_takeover; client_no_context_takeover",
+			exceptedMiddlewares: true,
+		},
+		{
+			name:                "Unsupported compression parameters",
+			path:                "/echo",
+			headers:             map[string]string{"Sec-WebSocket-Extensions": "permessage-deflate; server_max_window_bits=10"},
+			exceptedCode:        http.StatusSwitchingProtocols,
+			exceptedMiddlewares: true,
+		},
+	}
+
+	server := newWebSocketServer(t, WebSocketConfig{
+		Origins:      []string{"https://app.example.com"},
+		Subprotocols: []string{"chat"},
+		Compression:  true,
+	})
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			header := http.Header{}
+			for key, value := range test.headers {
+				header.Set(key, value)
+			}
+
+			_, resp := dialWebSocket(t, server, test.path, header)
+
+			assert.Equal(t, test.exceptedCode, resp.StatusCode)
+			assert.Equal(t, test.exceptedProtocol, resp.Header.Get("Sec-WebSocket-Protocol"))
+			assert.Equal(t, test.exceptedExtensions, resp.Header.Get("Sec-WebSocket-Extensions"))
+
+			if test.exceptedMiddlewares {
+				assert.Equal(t, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", resp.Header.Get("Sec-WebSocket-Accept"))
+				assert.Equal(t, "middleware", resp.Header.Get("X-Handshake"))
+			}
+		})
+	}
+}
+
+// TestWebSocket_messages tests the exchange of messages, control frames and the closing handshake.
+func TestWebSocket_messages(t *testing.T) {
+	server := newWebSocketServer(t, WebSocketConfig{})
+	client, resp := dialWebSocket(t, server, "/echo", nil)
+	require.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+
+	client.writeFrame(t, true, false, opText, []byte("hello"))
+	opcode, _, payload := client.readFrame(t)
+	assert.Equal(t, byte(opText), opcode)
+	assert.Equal(t, "hello", string(payload))
+
+	long := bytes.Repeat([]byte{0x01, 0x02}, 200)
+	client.writeFrame(t, true, false, opBinary, long)
+	opcode, _, payload = client.readFrame(t)
+	assert.Equal(t, byte(opBinary), opcode)
+	assert.Equal(t, long, payload)
+
+	client.writeFrame(t, false, false, opText, []byte("frag"))
+	client.writeFrame(t, true, false, opPing, []byte("ping"))
+	opcode, _, payload = client.readFrame(t)
+	assert.Equal(t, byte(opPong), opcode)
+	assert.Equal(t, "ping", string(payload))
+
+	client.writeFrame(t, false, false, opContinuation, []byte("mented "))
+	client.writeFrame(t, true, false, opContinuation, []byte("message"))
+	opcode, _, payload = client.readFrame(t)
+	assert.Equal(t, byte(opText), opcode)
+	assert.Equal(t, "fragmented message", string(payload))
+
+	client.writeFrame(t, true, false, opClose, binary.BigEndian.AppendUint16(nil, CloseGoingAway))
+	assert.Equal(t, CloseGoingAway, client.readClose(t))
+
+	_, err := client.br.ReadByte()
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+// TestWebSocket_compression tests the exchange of messages compressed with permessage-deflate.
+func TestWebSocket_compression(t *testing.T) {
+	server := newWebSocketServer(t, WebSocketConfig{Compression: true})
+	client, resp := dialWebSocket(t, server, "/echo", http.Header{"Sec-WebSocket-Extensions": {"permessage-deflate"}})
+	require.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+
+	message := strings.Repeat("compressed message ", 20)
+
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, flate.BestCompression)
+	require.NoError(t, err)
+	_, err = w.Write([]byte(message))
+	require.NoError(t, err)
+	require.NoError(t, w.Flush())
+
+	client.writeFrame(t, true, true, opText, bytes.TrimSuffix(buf.Bytes(), []byte{0x00, 0x00, 0xff, 0xff}))
+
+	opcode, rsv1, payload := client.readFrame(t)
+	assert.Equal(t, byte(opText), opcode)
+	require.True(t, rsv1)
+	assert.Less(t, len(payload), len(message))
+
+	inflated, err := inflate(payload, 1024)
+	require.NoError(t, err)
+	assert.Equal(t, message, string(inflated))
+
+	client.writeFrame(t, true, false, opText, []byte("small"))
+	opcode, rsv1, payload = client.readFrame(t)
+	assert.Equal(t, byte(opText), opcode)
+	assert.False(t, rsv1)
+	assert.Equal(t, "small", string(payload))
+}
+
+// TestWebSocket_errors tests the closing of the connection on protocol violations and handler errors.
+func TestWebSocket_errors(t *testing.T) {
+	cases := []struct {
+		name          string
+		path          string
+		send          func(t *testing.T, client *wsClient)
+		exceptedClose int
+	}{
+		{
+			name: "Unmasked frame",
+			path: "/echo",
+			send: func(t *testing.T, client *wsClient) {
+				client.writeFrame(t, true, false, opText, []byte("hello"), true)
+			},
+			exceptedClose: CloseProtocolError,
+		},
+		{
+			name: "Reserved bits",
+			path: "/echo",
+			send: func(t *testing.T, client *wsClient) {
+				client.writeFrame(t, true, true, opText, []byte("hello"))
+			},
+			exceptedClose: CloseProtocolError,
+		},
+		{
+			name: "Unexpected continuation",
+			path: "/echo",
+			send: func(t *testing.T, client *wsClient) {
+				client.writeFrame(t, true, false, opContinuation, []byte("hello"))
+			},
+			exceptedClose: CloseProtocolError,
+		},
+		{
+			name: "Fragmented control frame",
+			path: "/echo",
+			send: func(t *testing.T, client *wsClient) {
+				client.writeFrame(t, false, false, opPing, []byte("ping"))
+			},
+			exceptedClose: CloseProtocolError,
+		},
+		{
+			name: "Invalid close code",
+			path: "/echo",
+			send: func(t *testing.T, client *wsClient) {
+				client.writeFrame(t, true, false, opClose, binary.BigEndian.AppendUint16(nil, 1004))
+			},
+			exceptedClose: CloseProtocolError,
+		},
+		{
+			name: "Invalid UTF-8",
+			path: "/echo",
+			send: func(t *testing.T, client *wsClient) {
+				client.writeFrame(t, true, false, opText, []byte{0xff, 0xfe})
+			},
+			exceptedClose: CloseInvalidPayload,
+		},
+		{
+			name: "Message too big",
+			path: "/echo",
+			send: func(t *testing.T, client *wsClient) {
+				client.writeFrame(t, false, false, opBinary, make([]byte, 60))
+				client.writeFrame(t, true, false, opContinuation, make([]byte, 60))
+			},
+			exceptedClose: CloseMessageTooBig,
+		},
+		{
+			name:          "Handler error",
+			path:          "/fail",
+			send:          func(t *testing.T, client *wsClient) {},
+			exceptedClose: CloseInternalError,
+		},
+	}
+
+	server := newWebSocketServer(t, WebSocketConfig{ReadLimit: 100})
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			client, resp := dialWebSocket(t, server, test.path, nil)
+			require.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+
+			test.send(t, client)
+
+			assert.Equal(t, test.exceptedClose, client.readClose(t))
+		})
+	}
+}